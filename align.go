@@ -0,0 +1,74 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// WithAlignment guarantees that the slice returned by Bytes starts at a
+// multiple of align bytes, which must be a power of two.
+//
+// Mappings always start at a page boundary, so any alignment up to the
+// page size of the platform is honored: opening the file fails for larger
+// alignments, and for files that can not be mapped as a whole.
+func WithAlignment(align int) Option {
+	return func(o *options) {
+		o.align = align
+	}
+}
+
+// Bytes returns the mapped contents of the file.
+// The slice starts at a page boundary, and is only valid until the file is
+// closed or remapped.
+// Writing to the slice of a file opened read-only crashes the program.
+//
+// Bytes returns nil for files mapped through a sliding window.
+func (f *File) Bytes() []byte {
+	if f == nil || f.refresh() != nil || f.w != nil {
+		return nil
+	}
+	return f.data
+}
+
+// AlignedSection returns the [off, off+n) range of the mapping, checking
+// that it starts at a multiple of align bytes in memory, so that it can be
+// accessed in place as a buffer of a zero-copy serialization format.
+// align must be a power of two.
+func (f *File) AlignedSection(off, n int64, align int) ([]byte, error) {
+	if align <= 0 || align&(align-1) != 0 {
+		return nil, fmt.Errorf("mmap: invalid alignment %d", align)
+	}
+	if _, err := f.region(off, n); err != nil {
+		return nil, err
+	}
+	if f.w != nil {
+		return nil, errUnsupported
+	}
+	b := f.data[off : off+n : off+n]
+	if n > 0 && uintptr(unsafe.Pointer(&b[0]))&uintptr(align-1) != 0 {
+		return nil, fmt.Errorf("mmap: section at offset %d is not aligned to %d bytes", off, align)
+	}
+	return b, nil
+}
+
+// checkAlign checks that the mapping honors the alignment requested with
+// WithAlignment.
+func (f *File) checkAlign() error {
+	align := f.cfg.align
+	switch {
+	case align == 0:
+		return nil
+	case align < 0 || align&(align-1) != 0:
+		return fmt.Errorf("mmap: invalid alignment %d", align)
+	case align > os.Getpagesize():
+		return fmt.Errorf("mmap: alignment %d exceeds the page size", align)
+	case f.w != nil:
+		return fmt.Errorf("mmap: file %q is too large to be aligned", f.fd.Name())
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package alloc provides an allocator of variable-size blocks within a
+// memory-mapped file, as malloc and free do in memory, for custom file
+// formats that store records of various sizes.
+//
+// Blocks are identified by their offset in the file, which stays valid
+// when the file grows and across runs, rather than by pointers.
+// The file starts with a header holding a free list for each size class,
+// followed by the blocks: freed blocks are kept on the free list of their
+// class, and blocks that do not fit the free ones are cut from the end of
+// the allocated region, which grows the file as needed.
+// The file must stay mapped whole: growing it past the size files are
+// mapped through a sliding window from, as set by mmap.SetMaxMappedBytes,
+// fails.
+//
+// Blocks are rounded up to powers of two, and are neither split nor
+// coalesced: the allocator suits formats whose records have similar sizes
+// over time, at the cost of up to half of the space of each block.
+// Updates are made in place: an allocation or a free interrupted by a
+// crash may leave the file inconsistent.
+package alloc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+
+	"github.com/go-mmap/mmap"
+)
+
+const (
+	// hdrSize is the size of the header of an allocator file: a magic
+	// string, the end of the allocated region, the number of allocated
+	// bytes, and the heads of the free lists, one per size class.
+	hdrSize = 512
+
+	// blockHdrSize is the size of the header of a block: its class and
+	// flags, and the size it was allocated for.
+	blockHdrSize = 16
+
+	// minClass and maxClass are the size classes of the blocks, as the
+	// logarithm of their size, header included.
+	minClass = 5
+	maxClass = 62
+
+	// initSize is the size of a new allocator file.
+	initSize = 64 << 10
+)
+
+const (
+	offTop   = 8
+	offUsed  = 16
+	offFree  = 24
+	allocBit = 1 << 8 // set in the header of allocated blocks.
+)
+
+var magic = [8]byte{'m', 'm', 'a', 'p', 'a', 'l', 'c', '1'}
+
+// ErrClosed is returned when using a closed allocator.
+var ErrClosed = errors.New("alloc: closed")
+
+// Allocator allocates blocks within a memory-mapped file.
+// An Allocator must not be used from several goroutines at once.
+type Allocator struct {
+	f    *mmap.File
+	name string
+}
+
+// Create creates the named file holding an allocator with no blocks, and
+// opens it.
+// An existing file is replaced.
+func Create(filename string) (*Allocator, error) {
+	a, err := mmap.CreateAtomic(filename, initSize)
+	if err != nil {
+		return nil, err
+	}
+	b := a.Bytes()
+	copy(b, magic[:])
+	binary.LittleEndian.PutUint64(b[offTop:], hdrSize)
+	err = a.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filename)
+}
+
+// Open opens the allocator held by the named file, created with Create.
+func Open(filename string) (*Allocator, error) {
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write, mmap.WithAutoExtend())
+	if err != nil {
+		return nil, err
+	}
+	b := f.Bytes()
+	if b == nil && f.Len() > 0 {
+		_ = f.Close()
+		return nil, fmt.Errorf("alloc: %q is mapped through a window, not whole", filename)
+	}
+	if len(b) < hdrSize || !bytes.Equal(b[:8], magic[:]) {
+		_ = f.Close()
+		return nil, fmt.Errorf("alloc: %q is not an allocator", filename)
+	}
+	if top := binary.LittleEndian.Uint64(b[offTop:]); top < hdrSize || top > uint64(len(b)) {
+		_ = f.Close()
+		return nil, fmt.Errorf("alloc: invalid header of %q", filename)
+	}
+	return &Allocator{f: f, name: filename}, nil
+}
+
+func (a *Allocator) header(off int) uint64 {
+	return binary.LittleEndian.Uint64(a.f.Bytes()[off:])
+}
+
+func (a *Allocator) setHeader(off int, v uint64) {
+	binary.LittleEndian.PutUint64(a.f.Bytes()[off:], v)
+}
+
+// class returns the size class of blocks holding n bytes.
+func class(n int64) int {
+	c := bits.Len64(uint64(n+blockHdrSize) - 1)
+	if c < minClass {
+		c = minClass
+	}
+	return c
+}
+
+// Alloc allocates a block of n bytes, and returns its offset in the file.
+// The contents of the block are zeroed.
+// Alloc may grow the file: slices previously returned by Bytes must not be
+// used afterwards.
+func (a *Allocator) Alloc(n int64) (int64, error) {
+	if a.f == nil {
+		return 0, ErrClosed
+	}
+	if n < 0 || n > 1<<maxClass-blockHdrSize {
+		return 0, fmt.Errorf("alloc: invalid block size %d", n)
+	}
+	c := class(n)
+	size := uint64(1) << c
+
+	head := offFree + 8*(c-minClass)
+	blk := a.header(head)
+	if blk != 0 {
+		// Pop the first free block of the class.
+		next := binary.LittleEndian.Uint64(a.f.Bytes()[blk+blockHdrSize:])
+		a.setHeader(head, next)
+	} else {
+		blk = a.header(offTop)
+		end := blk + size
+		if end > uint64(a.f.Len()) {
+			grown := 2 * uint64(a.f.Len())
+			if grown < end {
+				grown = end
+			}
+			size := int64(a.f.Len())
+			_, err := a.f.WriteAt([]byte{0}, int64(grown)-1)
+			if a.f.Bytes() == nil {
+				if err == nil {
+					err = fmt.Errorf("file of %d bytes mapped through a window, not whole", grown)
+				}
+				err = a.shrink(size, err)
+			}
+			if err != nil {
+				return 0, fmt.Errorf("alloc: could not grow %q: %w", a.name, err)
+			}
+		}
+		a.setHeader(offTop, end)
+	}
+
+	b := a.f.Bytes()[blk : blk+size]
+	binary.LittleEndian.PutUint64(b, uint64(c)|allocBit)
+	binary.LittleEndian.PutUint64(b[8:], uint64(n))
+	for i := range b[blockHdrSize:] {
+		b[blockHdrSize+i] = 0
+	}
+	a.setHeader(offUsed, a.header(offUsed)+uint64(n))
+	return int64(blk) + blockHdrSize, nil
+}
+
+// shrink truncates the file back to size bytes and maps it again, once
+// growing it unmapped it, or mapped it through a sliding window whose bytes
+// can not be handed out. It returns cause, or the error mapping the file.
+func (a *Allocator) shrink(size int64, cause error) error {
+	_ = a.f.Close()
+	a.f = nil
+	err := os.Truncate(a.name, size)
+	if err != nil {
+		return err
+	}
+	f, err := mmap.OpenFile(a.name, mmap.Read|mmap.Write, mmap.WithAutoExtend())
+	if err != nil {
+		return err
+	}
+	if f.Bytes() == nil {
+		_ = f.Close()
+		return cause
+	}
+	a.f = f
+	return cause
+}
+
+// block returns the offset of the header of the allocated block at off,
+// and its class.
+func (a *Allocator) block(off int64) (uint64, int, error) {
+	if a.f == nil {
+		return 0, 0, ErrClosed
+	}
+	top := a.header(offTop)
+	if off < hdrSize+blockHdrSize || uint64(off) >= top || off%blockHdrSize != 0 {
+		return 0, 0, fmt.Errorf("alloc: invalid block offset %d", off)
+	}
+	blk := uint64(off) - blockHdrSize
+	v := a.header(int(blk))
+	c := int(v &^ allocBit)
+	if v&allocBit == 0 || c < minClass || c > maxClass || blk+1<<c > top {
+		return 0, 0, fmt.Errorf("alloc: no block allocated at offset %d", off)
+	}
+	return blk, c, nil
+}
+
+// Free frees the block at off, returned by Alloc, so that its space is
+// reused by later allocations.
+func (a *Allocator) Free(off int64) error {
+	blk, c, err := a.block(off)
+	if err != nil {
+		return err
+	}
+	n := a.header(int(blk) + 8)
+	head := offFree + 8*(c-minClass)
+	a.setHeader(int(blk), uint64(c))
+	a.setHeader(int(blk)+blockHdrSize, a.header(head))
+	a.setHeader(head, blk)
+	a.setHeader(offUsed, a.header(offUsed)-n)
+	return nil
+}
+
+// Bytes returns the contents of the block at off, as many bytes as it was
+// allocated for. The slice aliases the mapping.
+func (a *Allocator) Bytes(off int64) ([]byte, error) {
+	blk, _, err := a.block(off)
+	if err != nil {
+		return nil, err
+	}
+	n := a.header(int(blk) + 8)
+	return a.f.Bytes()[off : uint64(off)+n : uint64(off)+n], nil
+}
+
+// Size returns the number of bytes the block at off was allocated for.
+func (a *Allocator) Size(off int64) (int64, error) {
+	blk, _, err := a.block(off)
+	if err != nil {
+		return 0, err
+	}
+	return int64(a.header(int(blk) + 8)), nil
+}
+
+// Used returns the number of bytes allocated, as requested from Alloc.
+func (a *Allocator) Used() int64 {
+	if a.f == nil {
+		return 0
+	}
+	return int64(a.header(offUsed))
+}
+
+// Sync commits the allocator and the contents of its blocks to stable
+// storage.
+func (a *Allocator) Sync() error {
+	if a.f == nil {
+		return ErrClosed
+	}
+	return a.f.Sync()
+}
+
+// Close closes the allocator.
+// It does not sync it.
+func (a *Allocator) Close() error {
+	if a.f == nil {
+		return nil
+	}
+	f := a.f
+	a.f = nil
+	return f.Close()
+}
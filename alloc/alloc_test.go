@@ -0,0 +1,163 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package alloc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestAllocator(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "heap")
+	a, err := Create(fname)
+	if err != nil {
+		t.Fatalf("could not create allocator: %+v", err)
+	}
+
+	// Allocate blocks of various sizes, past the initial size of the file.
+	offs := make(map[int64][]byte)
+	for i := 0; i < 200; i++ {
+		n := int64(i*37) % 3000
+		off, err := a.Alloc(n)
+		if err != nil {
+			t.Fatalf("could not allocate %d bytes: %+v", n, err)
+		}
+		b, err := a.Bytes(off)
+		if err != nil {
+			t.Fatalf("could not get block %d: %+v", off, err)
+		}
+		if int64(len(b)) != n || !bytes.Equal(b, make([]byte, n)) {
+			t.Fatalf("invalid new block of %d bytes: %d bytes", n, len(b))
+		}
+		for j := range b {
+			b[j] = byte(i)
+		}
+		offs[off] = bytes.Repeat([]byte{byte(i)}, int(n))
+	}
+	used := a.Used()
+
+	// Free half of the blocks, and check that their space is reused.
+	freed := make(map[int64]int)
+	for off, b := range offs {
+		if len(freed) == len(offs)/2 {
+			break
+		}
+		freed[off] = len(b)
+	}
+	for off := range freed {
+		err := a.Free(off)
+		if err != nil {
+			t.Fatalf("could not free block %d: %+v", off, err)
+		}
+		delete(offs, off)
+		if err := a.Free(off); err == nil {
+			t.Fatalf("expected an error freeing a block twice")
+		}
+	}
+	if err := a.Free(3); err == nil {
+		t.Fatalf("expected an error freeing an invalid offset")
+	}
+	if a.Used() >= used {
+		t.Fatalf("freeing did not lower the used bytes")
+	}
+
+	// Blocks of the same sizes reuse the freed ones.
+	top := a.header(offTop)
+	for _, n := range freed {
+		off, err := a.Alloc(int64(n))
+		if err != nil {
+			t.Fatalf("could not allocate: %+v", err)
+		}
+		if _, ok := freed[off]; !ok {
+			t.Fatalf("freed block not reused: new block at %d", off)
+		}
+		if got, err := a.Size(off); err != nil || got != int64(n) {
+			t.Fatalf("invalid size of block %d: %d, %+v", off, got, err)
+		}
+		offs[off] = make([]byte, n)
+	}
+	if a.header(offTop) != top {
+		t.Fatalf("allocated region grew despite free blocks")
+	}
+
+	err = a.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	err = a.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+
+	// The blocks outlive the allocator.
+	a, err = Open(fname)
+	if err != nil {
+		t.Fatalf("could not open allocator: %+v", err)
+	}
+	defer a.Close()
+	for off, want := range offs {
+		got, err := a.Bytes(off)
+		if err != nil {
+			t.Fatalf("could not get block %d: %+v", off, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("invalid contents of block %d", off)
+		}
+	}
+}
+
+func TestAllocatorWindowed(t *testing.T) {
+	defer mmap.SetMaxMappedBytes(mmap.SetMaxMappedBytes(1 << 20))
+
+	fname := filepath.Join(t.TempDir(), "heap")
+	a, err := Create(fname)
+	if err != nil {
+		t.Fatalf("could not create allocator: %+v", err)
+	}
+	defer a.Close()
+
+	// Allocate blocks until the file would grow past the bound, and stop
+	// being mapped whole.
+	var offs []int64
+	for {
+		off, err := a.Alloc(100 << 10)
+		if err != nil {
+			break
+		}
+		if len(offs) > 100 {
+			t.Fatalf("expected an error growing the file past the bound")
+		}
+		offs = append(offs, off)
+	}
+	if len(offs) == 0 {
+		t.Fatalf("could not allocate any block")
+	}
+
+	// The allocator is left as it was before the failed allocation.
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatalf("could not stat file: %+v", err)
+	}
+	if fi.Size() > 1<<20 {
+		t.Fatalf("file not shrunk back: %d bytes", fi.Size())
+	}
+	if got, want := a.Used(), int64(len(offs))*100<<10; got != want {
+		t.Fatalf("invalid used bytes: got=%d, want=%d", got, want)
+	}
+	for _, off := range offs {
+		_, err := a.Bytes(off)
+		if err != nil {
+			t.Fatalf("could not get block %d: %+v", off, err)
+		}
+	}
+	_, err = a.Alloc(10)
+	if err != nil {
+		t.Fatalf("could not allocate small block: %+v", err)
+	}
+}
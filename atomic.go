@@ -0,0 +1,186 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// AtomicFile is a memory-mapped temporary file that atomically replaces
+// its destination when committed.
+//
+// An AtomicFile only has the methods of a File reading and writing its
+// contents: the mapping is closed by Commit and Close.
+type AtomicFile struct {
+	f    *File
+	path string // path of the destination file
+	done bool   // done is set once the file was committed or discarded.
+}
+
+// CreateAtomic creates a temporary file of the given size next to path,
+// and memory-maps it for reading and writing.
+//
+// The destination file at path is only replaced when Commit is called, so
+// readers and crashes observe either the previous contents of the file or
+// the new ones, never a mix of both.
+//
+// Once committed, the file has the permissions of the destination file it
+// replaced, if any, or else the ones set with WithCreate (0666 by default),
+// before umask.
+// WithExclusive and WithTruncate, which apply to the opening of existing
+// files, are rejected with an error wrapping os.ErrInvalid.
+func CreateAtomic(path string, size int64, opts ...Option) (*AtomicFile, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("mmap: invalid size %d", size)
+	}
+	cfg := newOptions(opts)
+	if cfg.excl || cfg.trunc {
+		return nil, fmt.Errorf("mmap: could not create %q with exclusive or truncating options: %w", path, os.ErrInvalid)
+	}
+
+	tmp, err := createTemp(path, cfg.perm)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not create temporary file for %q: %w", path, err)
+	}
+	name := tmp.Name()
+	err = tmp.Truncate(size)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, fmt.Errorf("mmap: could not resize temporary file for %q: %w", path, err)
+	}
+
+	f, err := OpenFile(name, Read|Write, opts...)
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, err
+	}
+	return &AtomicFile{f: f, path: path}, nil
+}
+
+// Len returns the length of the file.
+func (a *AtomicFile) Len() int {
+	return a.f.Len()
+}
+
+// Bytes returns the mapped contents of the file, as File.Bytes does.
+func (a *AtomicFile) Bytes() []byte {
+	return a.f.Bytes()
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (a *AtomicFile) ReadAt(p []byte, off int64) (int, error) {
+	return a.f.ReadAt(p, off)
+}
+
+// WriteAt implements the io.WriterAt interface.
+func (a *AtomicFile) WriteAt(p []byte, off int64) (int, error) {
+	return a.f.WriteAt(p, off)
+}
+
+// Sync commits the current contents of the file to stable storage.
+// The destination file is only replaced by Commit.
+func (a *AtomicFile) Sync() error {
+	return a.f.Sync()
+}
+
+// Commit commits the contents of the file to stable storage, closes it and
+// renames it over the destination file.
+// On unix, the directory holding the file is synced as well, so that the
+// rename itself is durable.
+//
+// The file is discarded if Commit fails.
+func (a *AtomicFile) Commit() error {
+	if a.done {
+		return errClosed
+	}
+	a.done = true
+
+	name := a.f.fd.Name()
+	var err error
+	if a.f.Size() > 0 {
+		err = a.f.Sync()
+	}
+	if err == nil {
+		err = a.f.fd.Sync()
+	}
+	if e := a.f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = keepMode(name, a.path)
+	}
+	if err == nil {
+		err = os.Rename(name, a.path)
+	}
+	if err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("mmap: could not commit %q: %w", a.path, err)
+	}
+
+	err = syncDir(filepath.Dir(a.path))
+	if err != nil {
+		return fmt.Errorf("mmap: could not commit %q: %w", a.path, err)
+	}
+	return nil
+}
+
+// Close discards the file, unless it was committed.
+// The destination file is left untouched.
+func (a *AtomicFile) Close() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+
+	name := a.f.fd.Name()
+	err := a.f.Close()
+	if e := os.Remove(name); err == nil {
+		err = e
+	}
+	return err
+}
+
+// tempSeq is the number of temporary files named by createTemp.
+var tempSeq atomic.Uint64
+
+// createTemp creates a temporary file next to path, with the permissions
+// perm, before umask. Unlike os.CreateTemp, which creates files readable
+// by their owner only, it lets the file be renamed over path with the
+// permissions of a file created there.
+func createTemp(path string, perm fs.FileMode) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	for i := 0; ; i++ {
+		seed := uint64(time.Now().UnixNano()) + tempSeq.Add(1)
+		name := prefix + strconv.FormatUint(seed, 36)
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) && i < 10000 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// keepMode gives the file name the permissions of the file at path, if it
+// exists.
+func keepMode(name, path string) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chmod(name, fi.Mode().Perm())
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"os"
+)
+
+// BatchWriter accumulates writes to a memory-mapped file, and carries them
+// to the file at once when committed, in the order they were made.
+//
+// Writes are checked against the bounds of the file as they are made, so
+// that a batch failing validation is dropped before touching the file.
+// This suits page-structured formats updating several pages at once, which
+// are then written in a single pass and synced with a single ranged sync.
+// Committed writes are not atomic, though: readers of the mapping, and the
+// file after a crash, may observe some of them without the others, which
+// transactions started with Begin protect against.
+// Unlike with an Overlay, reads of the file do not observe the pending
+// writes.
+//
+// A BatchWriter must not be used from several goroutines at once.
+type BatchWriter struct {
+	f      *File
+	buf    []byte       // data of the writes, back to back.
+	writes []batchWrite // in order.
+	beg    int64        // range spanned by the writes.
+	end    int64
+}
+
+// batchWrite is a pending write of buf[beg:end] at off.
+type batchWrite struct {
+	off      int64
+	beg, end int
+}
+
+// NewBatchWriter returns a batch writer to f, which must be opened for
+// writing.
+func NewBatchWriter(f *File) *BatchWriter {
+	return &BatchWriter{f: f}
+}
+
+// WriteAt implements the io.WriterAt interface, recording the write of p
+// at off until the batch is committed.
+// Writes past the end of a file not opened with WithAutoExtend are
+// rejected with ErrNoSpace, without recording any of p.
+func (b *BatchWriter) WriteAt(p []byte, off int64) (int, error) {
+	if b.f == nil {
+		return 0, os.ErrInvalid
+	}
+	if !b.f.wflag() {
+		return 0, errBadFD
+	}
+	if b.f.closed() {
+		return 0, errClosed
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("mmap: invalid WriteAt offset %d", off)
+	}
+	end := off + int64(len(p))
+	if !b.f.cfg.extend && end > b.f.size() {
+		return 0, ErrNoSpace
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if len(b.writes) == 0 || off < b.beg {
+		b.beg = off
+	}
+	if end > b.end {
+		b.end = end
+	}
+	beg := len(b.buf)
+	b.buf = append(b.buf, p...)
+	b.writes = append(b.writes, batchWrite{off: off, beg: beg, end: len(b.buf)})
+	return len(p), nil
+}
+
+// Len returns the number of pending writes.
+func (b *BatchWriter) Len() int {
+	return len(b.writes)
+}
+
+// Buffered returns the number of bytes of the pending writes.
+func (b *BatchWriter) Buffered() int {
+	return len(b.buf)
+}
+
+// Commit writes the pending writes to the file, in order, so that later
+// writes override earlier ones where they overlap, and empties the batch.
+// If sync is set, the range spanned by the writes is then committed to
+// stable storage, with a single call to SyncRange.
+//
+// The writes are checked again against the bounds of the file, which may
+// have been remapped since they were made: Commit fails without writing
+// anything if they do not fit anymore. Files opened with WithAutoExtend
+// grow once to hold all the writes instead.
+func (b *BatchWriter) Commit(sync bool) error {
+	f := b.f
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if len(b.writes) == 0 {
+		return nil
+	}
+	if f.closed() {
+		return errClosed
+	}
+	if err := f.refresh(); err != nil {
+		return err
+	}
+	if !f.wflag() {
+		return errBadFD
+	}
+	n, err := f.writeSpans(b.end, len(b.writes), func(i int) (int64, []byte) {
+		w := b.writes[i]
+		return w.off, b.buf[w.beg:w.end]
+	})
+	if err != nil {
+		return err
+	}
+	beg, end := b.beg, b.end
+	b.Reset()
+	err = f.wrote(n)
+	if err != nil || !sync {
+		return err
+	}
+	return f.SyncRange(beg, end-beg)
+}
+
+// writeSpans writes n spans of data to the file, in order, span returning
+// the offset and data of each, once they were checked to fit in the file,
+// of which end is the end of the last span.
+// Files opened with WithAutoExtend grow once to hold all the spans: others
+// are left untouched if end is past their end.
+// writeSpans returns the number of bytes written, whose sync policy the
+// caller applies.
+func (f *File) writeSpans(end int64, n int, span func(i int) (int64, []byte)) (int64, error) {
+	if err := f.grow(0, end); err != nil {
+		return 0, err
+	}
+	if f.size() < end {
+		return 0, fmt.Errorf("mmap: write ends at offset %d, past the end of the file", end)
+	}
+
+	var written int64
+	for i := 0; i < n; i++ {
+		off, p := span(i)
+		c, err := f.writeAt(p, off)
+		written += int64(c)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Reset drops the pending writes.
+func (b *BatchWriter) Reset() {
+	b.buf = b.buf[:0]
+	b.writes = b.writes[:0]
+	b.beg, b.end = 0, 0
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// budget bounds the number of bytes mapped by the process, as set with
+// SetMaxMappedBytes.
+var budget struct {
+	max     atomic.Int64
+	waiters atomic.Int32
+
+	mu      sync.Mutex
+	freed   *sync.Cond
+	pending int64             // bytes reserved by mappings in progress.
+	views   map[*window]*File // windows holding a view, that may be evicted.
+}
+
+func init() {
+	budget.freed = sync.NewCond(&budget.mu)
+	budget.views = make(map[*window]*File)
+}
+
+// SetMaxMappedBytes bounds the total size of the mappings of the process
+// to n bytes, and returns the previous bound. If n is zero or negative, the
+// mappings are not bounded, which is the default.
+//
+// Mapping a file, or a view of a file mapped through a sliding window, that
+// would take the mappings over the bound first unmaps the views of the
+// other windowed files that are not in use, then fails with a
+// *BudgetError, or waits for other files to be unmapped if the file was
+// opened with WithBudgetWait.
+// Files larger than the bound are mapped through a sliding window, unless
+// they are mapped with MapAt, WithDirtyTracking or as snapshots.
+//
+// This protects 32-bit processes, or processes whose address space is
+// constrained, from running out of address space.
+func SetMaxMappedBytes(n int64) int64 {
+	if n < 0 {
+		n = 0
+	}
+	prev := budget.max.Swap(n)
+	if budget.waiters.Load() > 0 {
+		budget.mu.Lock()
+		budget.freed.Broadcast()
+		budget.mu.Unlock()
+	}
+	return prev
+}
+
+// MaxMappedBytes returns the bound set with SetMaxMappedBytes, or zero if
+// the mappings are not bounded.
+func MaxMappedBytes() int64 {
+	return budget.max.Load()
+}
+
+// WithBudgetWait makes mapping the file wait for other files to be unmapped
+// when it would take the mappings over the bound set with
+// SetMaxMappedBytes, instead of failing.
+func WithBudgetWait() Option {
+	return func(o *options) {
+		o.budgetWait = true
+	}
+}
+
+// BudgetError is returned when mapping a file would take the mappings of
+// the process over the bound set with SetMaxMappedBytes.
+// It matches ErrMappedLimit with errors.Is.
+type BudgetError struct {
+	Name   string // Name of the file.
+	Size   int64  // Number of bytes to map.
+	Mapped int64  // Number of bytes already mapped.
+	Max    int64  // Bound on the number of mapped bytes.
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("mmap: could not map %d bytes of %q: %d of %d bytes already mapped", e.Size, e.Name, e.Mapped, e.Max)
+}
+
+// Is reports whether target is ErrMappedLimit.
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrMappedLimit
+}
+
+// overBudget reports whether size bytes can never be mapped at once, as
+// they exceed the bound on mapped bytes.
+func overBudget(size int64) bool {
+	max := budget.max.Load()
+	return max > 0 && size > max
+}
+
+// budgetSpan returns the size of the views of the files mapped through a
+// sliding window as they exceed the bound on mapped bytes: at most half of
+// the bound, and a multiple of the allocation granularity.
+func budgetSpan() int64 {
+	span := int64(windowSpan)
+	for span > budget.max.Load()/2 && span > 64<<10 {
+		span >>= 1
+	}
+	return span
+}
+
+// reserve reserves n bytes of the budget to map them for f, from the view
+// of w if w is non-nil, evicting the views of other windowed files or
+// waiting for mappings to be released if needed.
+// It returns the number of bytes reserved, zero if the mappings are not
+// bounded, which is released by calling unreserve once mapped.
+func (f *File) reserve(w *window, n int64) (int64, error) {
+	if budget.max.Load() <= 0 {
+		return 0, nil
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	for evicted := false; ; {
+		max := budget.max.Load()
+		mapped := stats.bytes.Load() + budget.pending
+		if max <= 0 || mapped+n <= max {
+			budget.pending += n
+			return n, nil
+		}
+		if !evicted {
+			evicted = true
+			budget.mu.Unlock()
+			evictViews(w)
+			budget.mu.Lock()
+			continue
+		}
+		if !f.cfg.budgetWait || n > max {
+			return 0, &BudgetError{Name: f.fd.Name(), Size: n, Mapped: mapped, Max: max}
+		}
+		budget.waiters.Add(1)
+		budget.freed.Wait()
+		budget.waiters.Add(-1)
+		evicted = false
+	}
+}
+
+// unreserve releases the n bytes reserved by reserve.
+func unreserve(n int64) {
+	if n == 0 {
+		return
+	}
+	budget.mu.Lock()
+	budget.pending -= n
+	budget.mu.Unlock()
+}
+
+// released wakes up the mappings waiting for mapped bytes to be released.
+func released() {
+	if budget.waiters.Load() > 0 {
+		budget.mu.Lock()
+		budget.freed.Broadcast()
+		budget.mu.Unlock()
+	}
+}
+
+// trackView records that w holds a view of f, that may be evicted.
+// Views are tracked even while the mappings are not bounded, so that the
+// ones mapped before SetMaxMappedBytes may be evicted.
+func trackView(w *window, f *File) {
+	budget.mu.Lock()
+	budget.views[w] = f
+	budget.mu.Unlock()
+}
+
+// forgetView records that w does not hold a view anymore.
+func forgetView(w *window) {
+	budget.mu.Lock()
+	delete(budget.views, w)
+	budget.mu.Unlock()
+}
+
+// evictViews unmaps the views of the windowed files not in use, but the
+// one of w.
+func evictViews(w *window) {
+	budget.mu.Lock()
+	views := make(map[*window]*File, len(budget.views))
+	for v, f := range budget.views {
+		if v != w {
+			views[v] = f
+		}
+	}
+	budget.mu.Unlock()
+
+	for v, f := range views {
+		if !v.mu.TryLock() {
+			continue
+		}
+		_ = v.unmap(f)
+		v.mu.Unlock()
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// ByteOrderView reads and writes fixed-size integers and floats at offsets
+// of a file, in a given byte order.
+type ByteOrderView struct {
+	f     *File
+	order binary.ByteOrder
+}
+
+// NewByteOrderView returns a view of f decoding and encoding values in the
+// given byte order, such as binary.BigEndian for network-order formats.
+func NewByteOrderView(f *File, order binary.ByteOrder) *ByteOrderView {
+	return &ByteOrderView{f: f, order: order}
+}
+
+// load reads len(b) bytes at off, failing with io.ErrUnexpectedEOF if the
+// file ends before.
+func (v *ByteOrderView) load(b []byte, off int64) error {
+	n, err := v.f.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (v *ByteOrderView) store(b []byte, off int64) error {
+	_, err := v.f.WriteAt(b, off)
+	return err
+}
+
+// Uint16 returns the uint16 at off.
+func (v *ByteOrderView) Uint16(off int64) (uint16, error) {
+	var b [2]byte
+	err := v.load(b[:], off)
+	if err != nil {
+		return 0, err
+	}
+	return v.order.Uint16(b[:]), nil
+}
+
+// Uint32 returns the uint32 at off.
+func (v *ByteOrderView) Uint32(off int64) (uint32, error) {
+	var b [4]byte
+	err := v.load(b[:], off)
+	if err != nil {
+		return 0, err
+	}
+	return v.order.Uint32(b[:]), nil
+}
+
+// Uint64 returns the uint64 at off.
+func (v *ByteOrderView) Uint64(off int64) (uint64, error) {
+	var b [8]byte
+	err := v.load(b[:], off)
+	if err != nil {
+		return 0, err
+	}
+	return v.order.Uint64(b[:]), nil
+}
+
+// Float32 returns the IEEE 754 float32 at off.
+func (v *ByteOrderView) Float32(off int64) (float32, error) {
+	u, err := v.Uint32(off)
+	return math.Float32frombits(u), err
+}
+
+// Float64 returns the IEEE 754 float64 at off.
+func (v *ByteOrderView) Float64(off int64) (float64, error) {
+	u, err := v.Uint64(off)
+	return math.Float64frombits(u), err
+}
+
+// PutUint16 writes u at off.
+func (v *ByteOrderView) PutUint16(off int64, u uint16) error {
+	var b [2]byte
+	v.order.PutUint16(b[:], u)
+	return v.store(b[:], off)
+}
+
+// PutUint32 writes u at off.
+func (v *ByteOrderView) PutUint32(off int64, u uint32) error {
+	var b [4]byte
+	v.order.PutUint32(b[:], u)
+	return v.store(b[:], off)
+}
+
+// PutUint64 writes u at off.
+func (v *ByteOrderView) PutUint64(off int64, u uint64) error {
+	var b [8]byte
+	v.order.PutUint64(b[:], u)
+	return v.store(b[:], off)
+}
+
+// PutFloat32 writes x at off, as an IEEE 754 float32.
+func (v *ByteOrderView) PutFloat32(off int64, x float32) error {
+	return v.PutUint32(off, math.Float32bits(x))
+}
+
+// PutFloat64 writes x at off, as an IEEE 754 float64.
+func (v *ByteOrderView) PutFloat64(off int64, x float64) error {
+	return v.PutUint64(off, math.Float64bits(x))
+}
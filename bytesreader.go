@@ -0,0 +1,165 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// BytesReader reads a memory-mapped file with the methods of a
+// bytes.Reader, so that code written against bytes.Reader can read files
+// without changes to their semantics: Len reports the number of unread
+// bytes, and Seek from io.SeekEnd adds its offset to the size of the file.
+//
+// A BytesReader has its own offset, and does not move the cursor of the
+// file. Code requiring a *bytes.Reader itself may wrap the mapped bytes of
+// files not mapped through a sliding window, with bytes.NewReader and
+// File.Bytes.
+type BytesReader struct {
+	f        *File
+	i        int64 // current reading index.
+	prevRune int64 // index of the previous rune, or -1.
+}
+
+// NewBytesReader returns a BytesReader reading f from its start.
+func NewBytesReader(f *File) *BytesReader {
+	return &BytesReader{f: f, prevRune: -1}
+}
+
+// Len returns the number of bytes of the unread portion of the file.
+func (r *BytesReader) Len() int {
+	size := r.f.Size()
+	if r.i >= size {
+		return 0
+	}
+	return int(size - r.i)
+}
+
+// Size returns the length of the file.
+func (r *BytesReader) Size() int64 {
+	return r.f.Size()
+}
+
+// Read implements the io.Reader interface.
+func (r *BytesReader) Read(p []byte) (int, error) {
+	r.prevRune = -1
+	n, err := r.f.ReadAt(p, r.i)
+	r.i += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *BytesReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.f.ReadAt(p, off)
+}
+
+// ReadByte implements the io.ByteReader interface.
+func (r *BytesReader) ReadByte() (byte, error) {
+	r.prevRune = -1
+	var b [1]byte
+	n, err := r.f.ReadAt(b[:], r.i)
+	if n == 0 {
+		return 0, err
+	}
+	r.i++
+	return b[0], nil
+}
+
+// UnreadByte complements ReadByte in implementing the io.ByteScanner
+// interface.
+func (r *BytesReader) UnreadByte() error {
+	if r.i <= 0 {
+		return errors.New("mmap: UnreadByte at beginning of file")
+	}
+	r.prevRune = -1
+	r.i--
+	return nil
+}
+
+// ReadRune implements the io.RuneReader interface.
+func (r *BytesReader) ReadRune() (ch rune, size int, err error) {
+	var b [utf8.UTFMax]byte
+	n, err := r.f.ReadAt(b[:], r.i)
+	if n == 0 || err != nil && err != io.EOF {
+		r.prevRune = -1
+		return 0, 0, err
+	}
+	r.prevRune = r.i
+	ch, size = utf8.DecodeRune(b[:n])
+	r.i += int64(size)
+	return ch, size, nil
+}
+
+// UnreadRune complements ReadRune in implementing the io.RuneScanner
+// interface.
+func (r *BytesReader) UnreadRune() error {
+	if r.i <= 0 {
+		return errors.New("mmap: UnreadRune at beginning of file")
+	}
+	if r.prevRune < 0 {
+		return errors.New("mmap: UnreadRune not preceded by ReadRune")
+	}
+	r.i = r.prevRune
+	r.prevRune = -1
+	return nil
+}
+
+// Seek implements the io.Seeker interface.
+func (r *BytesReader) Seek(offset int64, whence int) (int64, error) {
+	r.prevRune = -1
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.i + offset
+	case io.SeekEnd:
+		abs = r.f.Size() + offset
+	default:
+		return 0, errors.New("mmap: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("mmap: negative position")
+	}
+	r.i = abs
+	return abs, nil
+}
+
+// WriteTo implements the io.WriterTo interface, like File.WriteTo.
+func (r *BytesReader) WriteTo(w io.Writer) (int64, error) {
+	r.prevRune = -1
+	f := r.f
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
+	if !f.rflag() {
+		return 0, errBadFD
+	}
+	size := f.size()
+	if r.i >= size {
+		return 0, nil
+	}
+	n, err := f.writeRange(w, r.i, size)
+	r.i += n
+	return n, err
+}
+
+// Reset resets the reader to read f from its start.
+func (r *BytesReader) Reset(f *File) {
+	*r = BytesReader{f: f, prevRune: -1}
+}
+
+var (
+	_ io.ReadSeeker  = (*BytesReader)(nil)
+	_ io.ReaderAt    = (*BytesReader)(nil)
+	_ io.RuneScanner = (*BytesReader)(nil)
+	_ io.ByteScanner = (*BytesReader)(nil)
+	_ io.WriterTo    = (*BytesReader)(nil)
+)
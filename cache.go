@@ -0,0 +1,313 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache shares read-only mappings of files between their users, so that
+// opening a file several times maps it only once.
+//
+// Mappings are reference-counted, and kept around once unused according to
+// the limits of the cache, least recently used first.
+// A Cache is safe for concurrent use.
+type Cache struct {
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	idle      *list.List // unused entries, most recently used first.
+	idleBytes int64
+
+	maxIdle      int
+	maxIdleBytes int64
+}
+
+type cacheEntry struct {
+	path  string
+	f     *File
+	refs  int
+	elem  *list.Element // elem is non-nil while the entry is unused.
+	stale bool          // stale entries are closed once unused.
+}
+
+// CachedFile is a view of a file mapped by a Cache.
+// Each view has its own cursor.
+//
+// Views only read the mapping they share with the other views of the file:
+// the methods of File that map it again, or that hand it out beyond the
+// life of the view, such as Remap or Clone, are not available.
+type CachedFile struct {
+	f *File // the view, sharing the mapping of e.
+
+	c *Cache
+	e *cacheEntry
+}
+
+var defaultCache = NewCache(0, 0)
+
+// OpenCached memory-maps the named file for reading, sharing the mapping
+// with the other users of the file that opened it with OpenCached.
+// The file is unmapped when the last of them closes it.
+func OpenCached(filename string) (*CachedFile, error) {
+	return defaultCache.Open(filename)
+}
+
+// NewCache returns a cache keeping up to maxIdle unused mappings, holding
+// at most maxIdleBytes bytes in total, ready to be handed out again.
+// If maxIdleBytes is zero or negative, unused mappings are only limited in
+// number.
+// Mappings in use are never evicted.
+func NewCache(maxIdle int, maxIdleBytes int64) *Cache {
+	return &Cache{
+		entries:      make(map[string]*cacheEntry),
+		idle:         list.New(),
+		maxIdle:      maxIdle,
+		maxIdleBytes: maxIdleBytes,
+	}
+}
+
+// Open memory-maps the named file for reading, reusing the mapping of the
+// cache for that file if any.
+// Files are identified by their absolute path, once symbolic links are
+// resolved: a mapping is not reused if the file was replaced or modified
+// since it was mapped.
+func (c *Cache) Open(filename string) (*CachedFile, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not resolve %q: %w", filename, err)
+	}
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		path = p
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not stat %q: %w", filename, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[path]; ok {
+		if sameFile(fi, e.f.fi) {
+			c.acquire(e)
+			return c.view(e), nil
+		}
+		delete(c.entries, path)
+		e.stale = true
+		if e.refs == 0 {
+			c.evict(e)
+		}
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &cacheEntry{path: path, f: f}
+	c.entries[path] = e
+	c.acquire(e)
+	return c.view(e), nil
+}
+
+// Close closes the unused mappings of the cache.
+// Mappings in use are closed once their last view is closed.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for path, e := range c.entries {
+		delete(c.entries, path)
+		e.stale = true
+		if e.refs == 0 {
+			if e := c.evict(e); err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Close closes the view.
+// The mapping of the file is released or kept around according to the
+// limits of the cache once all its views are closed.
+func (cf *CachedFile) Close() error {
+	if cf.e == nil {
+		return nil
+	}
+	e := cf.e
+	cf.e = nil
+	cf.f.data = nil
+	cf.f.w = nil
+	cf.f.isClosed = true
+	return cf.c.release(e)
+}
+
+// Len returns the length of the file.
+func (cf *CachedFile) Len() int {
+	return cf.f.Len()
+}
+
+// Size returns the length of the file.
+func (cf *CachedFile) Size() int64 {
+	return cf.f.Size()
+}
+
+// At returns the byte at index i, like File.At.
+func (cf *CachedFile) At(i int) byte {
+	return cf.f.At(i)
+}
+
+// Stat returns the FileInfo structure describing the file.
+func (cf *CachedFile) Stat() (os.FileInfo, error) {
+	return cf.f.Stat()
+}
+
+// Read implements the io.Reader interface.
+func (cf *CachedFile) Read(p []byte) (int, error) {
+	return cf.f.Read(p)
+}
+
+// ReadByte implements the io.ByteReader interface.
+func (cf *CachedFile) ReadByte() (byte, error) {
+	return cf.f.ReadByte()
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (cf *CachedFile) ReadAt(p []byte, off int64) (int, error) {
+	return cf.f.ReadAt(p, off)
+}
+
+// ReadFullAt reads exactly len(p) bytes of the file at off into p, like
+// File.ReadFullAt.
+func (cf *CachedFile) ReadFullAt(p []byte, off int64) error {
+	return cf.f.ReadFullAt(p, off)
+}
+
+// Peek returns the next n bytes without advancing the cursor, like
+// File.Peek.
+func (cf *CachedFile) Peek(n int) ([]byte, error) {
+	return cf.f.Peek(n)
+}
+
+// Discard skips the next n bytes, like File.Discard.
+func (cf *CachedFile) Discard(n int) (int, error) {
+	return cf.f.Discard(n)
+}
+
+// Seek implements the io.Seeker interface.
+func (cf *CachedFile) Seek(offset int64, whence int) (int64, error) {
+	return cf.f.Seek(offset, whence)
+}
+
+// Pos returns the current position of the cursor.
+func (cf *CachedFile) Pos() int64 {
+	return cf.f.Pos()
+}
+
+// SectionReader returns a reader of the [off, off+n) range of the file.
+func (cf *CachedFile) SectionReader(off, n int64) *io.SectionReader {
+	return cf.f.SectionReader(off, n)
+}
+
+// WriteTo implements the io.WriterTo interface.
+// The contents are always written from the mapping: views share the
+// descriptor of the file, whose offset the kernel copies of File.WriteTo
+// move.
+func (cf *CachedFile) WriteTo(w io.Writer) (int64, error) {
+	return cf.f.WriteTo(onlyWriter{w})
+}
+
+// onlyWriter hides the concrete type of the writer it wraps.
+type onlyWriter struct {
+	io.Writer
+}
+
+// view returns a new view of the mapping of e.
+// view must be called with c.mu held.
+func (c *Cache) view(e *cacheEntry) *CachedFile {
+	f := e.f
+	return &CachedFile{
+		f: &File{
+			data: f.data,
+			w:    f.w,
+			fd:   f.fd,
+			name: f.name,
+			flag: f.flag,
+			fi:   f.fi,
+			cfg:  f.cfg,
+		},
+		c: c,
+		e: e,
+	}
+}
+
+// acquire takes a reference on e.
+// acquire must be called with c.mu held.
+func (c *Cache) acquire(e *cacheEntry) {
+	if e.elem != nil {
+		c.idle.Remove(e.elem)
+		c.idleBytes -= e.f.size()
+		e.elem = nil
+	}
+	e.refs++
+}
+
+// release drops a reference on e, and evicts unused entries beyond the
+// limits of the cache.
+func (c *Cache) release(e *cacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.refs--
+	if e.refs > 0 {
+		return nil
+	}
+	if e.stale {
+		return c.evict(e)
+	}
+	e.elem = c.idle.PushFront(e)
+	c.idleBytes += e.f.size()
+
+	var err error
+	for c.idle.Len() > c.maxIdle || (c.maxIdleBytes > 0 && c.idleBytes > c.maxIdleBytes) {
+		old := c.idle.Back().Value.(*cacheEntry)
+		delete(c.entries, old.path)
+		if e := c.evict(old); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// evict closes the unused entry e.
+// evict must be called with c.mu held.
+func (c *Cache) evict(e *cacheEntry) error {
+	if e.elem != nil {
+		c.idle.Remove(e.elem)
+		c.idleBytes -= e.f.size()
+		e.elem = nil
+	}
+	return e.f.Close()
+}
+
+// sameFile reports whether fi and old describe the same, unmodified, file.
+func sameFile(fi, old os.FileInfo) bool {
+	return os.SameFile(fi, old) && fi.Size() == old.Size() && fi.ModTime().Equal(old.ModTime())
+}
+
+var (
+	_ io.Reader     = (*CachedFile)(nil)
+	_ io.ReaderAt   = (*CachedFile)(nil)
+	_ io.ByteReader = (*CachedFile)(nil)
+	_ io.Seeker     = (*CachedFile)(nil)
+	_ io.WriterTo   = (*CachedFile)(nil)
+	_ io.Closer     = (*CachedFile)(nil)
+)
@@ -0,0 +1,203 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// ErrChecksum is returned when the contents of a page do not match its
+// checksum.
+var ErrChecksum = errors.New("mmap: checksum mismatch")
+
+// checksumPage is the size of the pages covered by a checksum.
+// It does not depend on the platform, so checksum files are portable.
+const checksumPage = 4096
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksummed maintains CRC32C checksums of the pages of a memory-mapped
+// file in a sidecar file, to detect silent corruption of its contents.
+//
+// Pages are verified when they are read through ReadAt, and the checksums
+// of the pages written through WriteAt are updated by Sync.
+//
+// A Checksummed must not be used from several goroutines at once.
+type Checksummed struct {
+	f     *File
+	sums  *File
+	dirty map[int64]struct{} // pages written since the last sync.
+}
+
+// SumsSize returns the size of the sidecar file holding the checksums of
+// a file of the given size.
+func SumsSize(size int64) int64 {
+	return (size + checksumPage - 1) / checksumPage * 4
+}
+
+// NewChecksummed returns f, with the checksums of its pages kept in sums.
+// sums must be opened for reading and writing, and hold at least
+// SumsSize(f.Size()) bytes.
+// Use Rehash to compute the checksums of a file for the first time.
+func NewChecksummed(f, sums *File) (*Checksummed, error) {
+	if f == nil || sums == nil {
+		return nil, os.ErrInvalid
+	}
+	if !sums.wflag() {
+		return nil, errBadFD
+	}
+	if got, want := sums.Size(), SumsSize(f.Size()); got < want {
+		return nil, fmt.Errorf("mmap: checksum file too small: got %d bytes, want %d", got, want)
+	}
+	return &Checksummed{
+		f:     f,
+		sums:  sums,
+		dirty: make(map[int64]struct{}),
+	}, nil
+}
+
+// ReadAt implements the io.ReaderAt interface.
+// ReadAt fails with an error wrapping ErrChecksum if a page holding the
+// requested range is corrupted.
+func (c *Checksummed) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("mmap: invalid ReadAt offset %d", off)
+	}
+	end := off + int64(len(p))
+	if size := c.f.Size(); end > size {
+		end = size
+	}
+	for i := off / checksumPage; i*checksumPage < end; i++ {
+		err := c.verify(i)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.f.ReadAt(p, off)
+}
+
+// WriteAt implements the io.WriterAt interface.
+// The checksums of the written pages are updated by the next Sync.
+func (c *Checksummed) WriteAt(p []byte, off int64) (int, error) {
+	n, err := c.f.WriteAt(p, off)
+	for i := off / checksumPage; i*checksumPage < off+int64(n); i++ {
+		c.dirty[i] = struct{}{}
+	}
+	return n, err
+}
+
+// Verify checks all the pages of the file against their checksums.
+// Progress is reported as set up with WithProgress on the file.
+func (c *Checksummed) Verify() error {
+	for i := int64(0); i*checksumPage < c.f.Size(); i++ {
+		err := c.verify(i)
+		if err == nil {
+			err = c.reportPage(i)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync updates the checksums of the pages written since the last sync,
+// and commits both the file and its checksums to stable storage.
+func (c *Checksummed) Sync() error {
+	for i := range c.dirty {
+		err := c.update(i)
+		if err != nil {
+			return err
+		}
+		delete(c.dirty, i)
+	}
+	err := c.f.Sync()
+	if err != nil {
+		return err
+	}
+	return c.sums.Sync()
+}
+
+// Rehash computes the checksums of all the pages of the file, trusting
+// their current contents, and commits them to stable storage.
+// Progress is reported as set up with WithProgress on the file.
+func (c *Checksummed) Rehash() error {
+	for i := int64(0); i*checksumPage < c.f.Size(); i++ {
+		err := c.update(i)
+		if err == nil {
+			err = c.reportPage(i)
+		}
+		if err != nil {
+			return err
+		}
+		delete(c.dirty, i)
+	}
+	err := c.f.Sync()
+	if err != nil {
+		return err
+	}
+	return c.sums.Sync()
+}
+
+// reportPage reports the progress of processing the file up to the i-th
+// page, every syncChunk bytes and at the end of the file.
+func (c *Checksummed) reportPage(i int64) error {
+	done, total := (i+1)*checksumPage, c.f.Size()
+	if done >= total {
+		return c.f.progress(total, total)
+	}
+	if done%syncChunk != 0 {
+		return nil
+	}
+	return c.f.progress(done, total)
+}
+
+// verify checks the i-th page of the file against its checksum.
+// Pages written since the last sync are not verified.
+func (c *Checksummed) verify(i int64) error {
+	if _, ok := c.dirty[i]; ok {
+		return nil
+	}
+	sum, err := c.sum(i)
+	if err != nil {
+		return err
+	}
+	var b [4]byte
+	_, err = c.sums.ReadAt(b[:], i*4)
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(b[:]) != sum {
+		return fmt.Errorf("mmap: page at offset %d: %w", i*checksumPage, ErrChecksum)
+	}
+	return nil
+}
+
+// update stores the checksum of the i-th page of the file.
+func (c *Checksummed) update(i int64) error {
+	sum, err := c.sum(i)
+	if err != nil {
+		return err
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], sum)
+	_, err = c.sums.WriteAt(b[:], i*4)
+	return err
+}
+
+// sum computes the checksum of the i-th page of the file.
+func (c *Checksummed) sum(i int64) (uint32, error) {
+	var page [checksumPage]byte
+	n, err := c.f.ReadAt(page[:], i*checksumPage)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return crc32.Checksum(page[:n], castagnoli), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package mmap
+
+import "iter"
+
+// Chunk is a chunk of a file, as yielded by Chunks.
+type Chunk struct {
+	Off  int64  // Offset of the chunk in the file.
+	Data []byte // Contents of the chunk.
+}
+
+// Chunks returns an iterator over successive n-byte chunks of the file,
+// along with their offsets.
+// The last chunk may be shorter than n bytes.
+//
+// Chunks alias the mapping, unless the file is mapped through a sliding
+// window: they are then read into a buffer reused across iterations.
+// Either way, a chunk must not be retained past its iteration.
+// Reading a chunk through the window may fail: the error is then yielded,
+// with the offset of the chunk, and the iteration stops.
+//
+// If the file was opened WithSequential, each chunk is prefetched when the
+// previous one is yielded.
+//
+// Chunks panics if n is not positive.
+func (f *File) Chunks(n int) iter.Seq2[Chunk, error] {
+	if n <= 0 {
+		panic("mmap: invalid chunk size")
+	}
+	return func(yield func(Chunk, error) bool) {
+		var buf []byte
+		if f.w != nil {
+			buf = make([]byte, n)
+		}
+		size := f.size()
+		for off := int64(0); off < size; off += int64(n) {
+			end := off + int64(n)
+			if end > size {
+				end = size
+			}
+			if f.cfg.advice == adviceSequential && end < size {
+				next := size - end
+				if next > int64(n) {
+					next = int64(n)
+				}
+				_ = f.Prefetch(end, next)
+			}
+
+			var chunk []byte
+			if f.w != nil {
+				m, err := f.readAt(buf[:end-off], off)
+				if err != nil {
+					yield(Chunk{Off: off}, err)
+					return
+				}
+				chunk = buf[:m]
+			} else {
+				chunk = f.data[off:end:end]
+			}
+			if !yield(Chunk{Off: off, Data: chunk}, nil) {
+				return
+			}
+		}
+	}
+}
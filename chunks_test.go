@@ -0,0 +1,96 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package mmap
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "chunks.txt")
+	content := []byte("hello world!\nbye.\n")
+	err := os.WriteFile(fname, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"flat", nil},
+		{"sequential", []Option{WithSequential()}},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(fname, Read, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			var got []byte
+			next := int64(0)
+			for chunk, err := range f.Chunks(5) {
+				if err != nil {
+					t.Fatalf("could not read chunk: %+v", err)
+				}
+				if chunk.Off != next {
+					t.Fatalf("invalid chunk offset: got=%d, want=%d", chunk.Off, next)
+				}
+				got = append(got, chunk.Data...)
+				next += int64(len(chunk.Data))
+			}
+			if !bytes.Equal(got, content) {
+				t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, content)
+			}
+
+			for chunk := range f.Chunks(5) {
+				if chunk.Off != 0 {
+					t.Fatalf("iteration did not stop")
+				}
+				break
+			}
+		})
+	}
+}
+
+func TestChunksError(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "chunks.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	fault := false
+	f, err := OpenFile(fname, Read, withWindow(1<<16), WithFaults(func(call Syscall) error {
+		if fault && call == SyscallMmap {
+			return errors.New("injected")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	fault = true
+	var errs []error
+	for chunk, err := range f.Chunks(5) {
+		if chunk.Off != 0 || chunk.Data != nil {
+			t.Fatalf("invalid chunk on error: %+v", chunk)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Fatalf("failing window read should yield its error once: %v", errs)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var errShared = errors.New("mmap: mapping shared with clones")
+
+// Clone returns a new handle on the mapping of the file, with its own
+// cursor, starting at the beginning of the file, and the flags of the file.
+// Clones share the mapping, which is only released once the file and all
+// its clones are closed: each may be handed to its own goroutine.
+// The mapping of a cloned file can not be remapped or extended while
+// clones are open.
+//
+// Clone returns nil if the file is closed.
+// Clone may be called concurrently on the same file, but not concurrently
+// with Close, on the file or on any of its clones.
+func (f *File) Clone() *File {
+	if f == nil || f.closed() {
+		return nil
+	}
+	return f.clone(f.flag)
+}
+
+// CloneFlag is like Clone, but the clone is opened with flag, which must
+// not grant accesses the file does not have: a read-only clone of a file
+// opened for writing may be handed to code that must not modify it.
+func (f *File) CloneFlag(flag Flag) (*File, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	if flag&^f.flag != 0 || flag&(Read|Write) == 0 {
+		return nil, fmt.Errorf("mmap: could not clone %q with mode %q: %w", f.fd.Name(), flag.mode(), os.ErrPermission)
+	}
+	return f.clone(flag), nil
+}
+
+// clone returns a new handle on the mapping of the file, opened with flag.
+func (f *File) clone(flag Flag) *File {
+	f.refs.Add(1)
+	c := &File{
+		data: f.data,
+		w:    f.w,
+		fd:   f.fd,
+		name: f.name,
+		flag: flag,
+		fi:   f.fi,
+		cfg:  f.cfg,
+		refs: f.refs,
+	}
+	c.cfg.watch = false
+	c.cfg.onChange = nil
+	c.opened()
+	return c
+}
+
+// initRefs sets up the reference count of the mapping of a newly opened
+// file, before it may be cloned from several goroutines.
+func (f *File) initRefs() {
+	if f.refs == nil {
+		f.refs = new(atomic.Int32)
+		f.refs.Store(1)
+	}
+}
+
+// shared reports whether the mapping of the file is shared with clones.
+func (f *File) shared() bool {
+	return f.refs != nil && f.refs.Load() > 1
+}
+
+// release detaches the file from the mapping it shares with its clones, if
+// any, and reports whether clones still hold the mapping.
+func (f *File) release() bool {
+	if f.refs == nil || f.refs.Add(-1) == 0 {
+		return false
+	}
+	f.data = nil
+	f.w = nil
+	f.isClosed = true
+	return true
+}
+
+// CloneTo copies the contents of the file to a new file at path.
+// CloneTo fails if a file already exists at path.
+//
+// When the filesystem supports it (Btrfs, XFS, APFS, ReFS, ...), the new
+// file shares the data blocks of the original until either is modified,
+// which makes cloning large files nearly free.
+// Otherwise, the contents of the mapping are copied to the new file, as
+// they always are for snapshots, for files mapped past their end, and for
+// files holding their writes in memory until synced.
+//
+// The new file is not synced to stable storage.
+func (f *File) CloneTo(path string) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if f.closed() {
+		return errClosed
+	}
+
+	if f.mirrored() {
+		if !cloneSeesWrites && f.wflag() {
+			err := f.sync()
+			if err != nil {
+				return fmt.Errorf("mmap: could not clone %q to %q: %w", f.fd.Name(), path, err)
+			}
+		}
+		ok, err := cloneFile(f.fd, path, f.fi.Mode().Perm())
+		if err != nil {
+			return fmt.Errorf("mmap: could not clone %q to %q: %w", f.fd.Name(), path, err)
+		}
+		if ok {
+			return f.progress(f.size(), f.size())
+		}
+	}
+
+	err := f.copyTo(path)
+	if err != nil {
+		return fmt.Errorf("mmap: could not copy %q to %q: %w", f.fd.Name(), path, err)
+	}
+	return nil
+}
+
+// mirrored reports whether the file holds the contents of the mapping, so
+// that they can be read from its descriptor.
+func (f *File) mirrored() bool {
+	if f.cfg.private || f.cfg.dirty && dirtyCopied {
+		return false
+	}
+	return f.fi != nil && f.fi.Mode().IsRegular() && f.fi.Size() >= f.size()
+}
+
+// copyTo writes the contents of the mapping to a new file at path.
+func (f *File) copyTo(path string) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	err = f.writeTo(dst)
+	if err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path)
+		return err
+	}
+
+	err = dst.Close()
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// writeTo writes the contents of the mapping to dst.
+func (f *File) writeTo(dst *os.File) error {
+	if f.w == nil {
+		if f.reports() {
+			return f.chunked(0, f.size(), func(off, n int64) error {
+				_, err := dst.Write(f.data[off : off+n])
+				return err
+			})
+		}
+		_, err := dst.Write(f.data)
+		return err
+	}
+
+	buf := make([]byte, windowSpan)
+	for off := int64(0); off < f.w.size; {
+		n, err := f.w.readAt(f, buf, off)
+		if err != nil {
+			return err
+		}
+		_, err = dst.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+		off += int64(n)
+		err = f.progress(off, f.w.size)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
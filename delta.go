@@ -0,0 +1,188 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Patch is a change to the contents of a file: Data replaces the bytes at
+// offset Off.
+type Patch struct {
+	Off  int64
+	Data []byte
+}
+
+// DeltaTo returns the patches turning the contents of old into the ones of
+// f, to replicate f by applying them to a copy of old with ApplyPatches.
+//
+// The files are compared page by page, and runs of adjacent changed pages
+// are merged into a single patch. Bytes of f past the end of old make up a
+// final patch, while bytes of old past the end of f are not part of the
+// delta: a copy of old must then be truncated to the size of f.
+// The patches hold copies of the contents of f.
+func (f *File) DeltaTo(old *File) ([]Patch, error) {
+	if f == nil || old == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() || old.closed() {
+		return nil, errClosed
+	}
+
+	var (
+		page    = int64(os.Getpagesize())
+		size    = f.size()
+		common  = old.size()
+		patches []Patch
+		cur     *Patch // patch covering the previous page, if it changed.
+		a       = make([]byte, page)
+		b       = make([]byte, page)
+	)
+	if size < common {
+		common = size
+	}
+	for off := int64(0); off < common; off += page {
+		n := common - off
+		if n > page {
+			n = page
+		}
+		_, err := f.readAt(a[:n], off)
+		if err != nil {
+			return nil, err
+		}
+		_, err = old.readAt(b[:n], off)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(a[:n], b[:n]) {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			patches = append(patches, Patch{Off: off})
+			cur = &patches[len(patches)-1]
+		}
+		cur.Data = append(cur.Data, a[:n]...)
+	}
+
+	if common < size {
+		tail := make([]byte, size-common)
+		_, err := f.readAt(tail, common)
+		if err != nil {
+			return nil, err
+		}
+		if cur != nil {
+			cur.Data = append(cur.Data, tail...)
+		} else {
+			patches = append(patches, Patch{Off: common, Data: tail})
+		}
+	}
+	return patches, nil
+}
+
+// ApplyPatches writes the data of each patch at its offset, as computed by
+// DeltaTo, and returns the number of bytes written.
+//
+// All the patches are validated before any is applied: ApplyPatches fails
+// without writing anything if a patch has a negative offset, overlaps with
+// another one, or ends past the end of the file.
+// Files opened with WithAutoExtend grow once to hold all the patches
+// instead. The patches are then applied in order of offset, so that each
+// page is touched in turn.
+// Use SyncPatches to commit the patched ranges to stable storage.
+func (f *File) ApplyPatches(patches []Patch) (int64, error) {
+	if f == nil {
+		return 0, os.ErrInvalid
+	}
+	if f.closed() {
+		return 0, errClosed
+	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
+	if !f.wflag() {
+		return 0, errBadFD
+	}
+
+	ps, end, err := sortPatches(patches)
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.writeSpans(end, len(ps), func(i int) (int64, []byte) {
+		return ps[i].Off, ps[i].Data
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, f.wrote(n)
+}
+
+// SyncPatches commits the ranges of the file written by the patches to
+// stable storage, only flushing the pages they span.
+func (f *File) SyncPatches(patches []Patch) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	ps, _, err := sortPatches(patches)
+	if err != nil {
+		return err
+	}
+	if f.w != nil {
+		return f.Sync()
+	}
+
+	page := int64(os.Getpagesize())
+	var beg, end int64 = 0, -1
+	for _, p := range ps {
+		if len(p.Data) == 0 {
+			continue
+		}
+		pbeg := p.Off &^ (page - 1)
+		pend := p.Off + int64(len(p.Data))
+		if end >= pbeg {
+			// Merge ranges sharing or touching a page.
+			if pend > end {
+				end = pend
+			}
+			continue
+		}
+		if end >= 0 {
+			if err := f.SyncRange(beg, end-beg); err != nil {
+				return err
+			}
+		}
+		beg, end = pbeg, pend
+	}
+	if end < 0 {
+		return nil
+	}
+	return f.SyncRange(beg, end-beg)
+}
+
+// sortPatches returns a copy of patches sorted by offset, and the offset of
+// the end of the last patch.
+// It fails if a patch has a negative offset or overlaps with another one.
+func sortPatches(patches []Patch) ([]Patch, int64, error) {
+	ps := append([]Patch(nil), patches...)
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].Off < ps[j].Off
+	})
+	var end int64
+	for i, p := range ps {
+		if p.Off < 0 {
+			return nil, 0, fmt.Errorf("mmap: invalid patch offset %d", p.Off)
+		}
+		if i > 0 && p.Off < end && len(p.Data) > 0 {
+			return nil, 0, fmt.Errorf("mmap: patch at offset %d overlaps with the previous one", p.Off)
+		}
+		if e := p.Off + int64(len(p.Data)); e > end {
+			end = e
+		}
+	}
+	return ps, end, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "os"
+
+// WithDirtyTracking asks the OS to track the pages of the mapping that are
+// written to, including through the slice returned by Bytes, so that they
+// can be retrieved with GetDirtyPages, and so that Sync only flushes them.
+//
+// On Linux, pages are tracked through their soft-dirty bits, which the
+// kernel can only reset for the whole process at once: other users of
+// soft-dirty bits in the process, such as checkpointing tools, interfere
+// with the tracking.
+//
+// On Windows, which can not track writes to file views, the file is loaded
+// into memory watched for writes (MEM_WRITE_WATCH) instead of being mapped:
+// Sync and Close then write the dirty pages back to the file, and changes
+// are not visible to other processes until then.
+// Files too large to be mapped as a whole can not be tracked, and opening
+// the file fails on other platforms.
+func WithDirtyTracking() Option {
+	return func(o *options) {
+		o.dirty = true
+	}
+}
+
+// GetDirtyPages returns the offsets of the pages of the mapping written to
+// since the file was opened or last synced, in increasing order.
+// The file must be opened WithDirtyTracking.
+func (f *File) GetDirtyPages() ([]int64, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if !f.cfg.dirty {
+		return nil, errUnsupported
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	return f.dirtyPages(false)
+}
@@ -0,0 +1,23 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package mmap
+
+// trackDirty starts tracking the pages of data written to.
+// Darwin and FreeBSD do not expose dirty pages.
+func trackDirty(data []byte) error {
+	return errUnsupported
+}
+
+// untrackDirty stops tracking the pages of data written to.
+func untrackDirty(data []byte) {}
+
+// dirtyPages returns the offsets of the pages written to since the last
+// reset of the tracking, resetting it if requested.
+func (f *File) dirtyPages(reset bool) ([]int64, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// pagemapSoftDirty is the bit of the entries of /proc/self/pagemap set for
+// pages written to since soft-dirty bits were last cleared.
+const pagemapSoftDirty = 1 << 55
+
+// softDirty tracks the pages written to through the soft-dirty bits of the
+// page table entries of the process.
+//
+// The kernel only clears soft-dirty bits for the whole process at once:
+// before doing so, the pages dirtied in the other tracked mappings are
+// recorded, so that they are still reported.
+var softDirty = struct {
+	sync.Mutex
+	maps map[uintptr]map[int64]struct{} // dirty pages recorded for each mapping, by address.
+	lens map[uintptr]int
+}{
+	maps: make(map[uintptr]map[int64]struct{}),
+	lens: make(map[uintptr]int),
+}
+
+// trackDirty starts tracking the pages of data written to.
+func trackDirty(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(&data[0]))
+
+	softDirty.Lock()
+	defer softDirty.Unlock()
+
+	// New mappings report all their pages as soft-dirty, unless the kernel
+	// does not support soft-dirty bits.
+	pages, err := scanSoftDirty(addr, len(data))
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return errUnsupported
+	}
+
+	softDirty.maps[addr] = make(map[int64]struct{})
+	softDirty.lens[addr] = len(data)
+	err = clearSoftDirty(addr)
+	if err != nil {
+		delete(softDirty.maps, addr)
+		delete(softDirty.lens, addr)
+		return err
+	}
+	return nil
+}
+
+// untrackDirty stops tracking the pages of data written to.
+func untrackDirty(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	addr := uintptr(unsafe.Pointer(&data[0]))
+
+	softDirty.Lock()
+	defer softDirty.Unlock()
+	delete(softDirty.maps, addr)
+	delete(softDirty.lens, addr)
+}
+
+// dirtyPages returns the offsets of the pages written to since the last
+// reset of the tracking, resetting it if requested.
+func (f *File) dirtyPages(reset bool) ([]int64, error) {
+	if len(f.data) == 0 {
+		return nil, nil
+	}
+	addr := uintptr(unsafe.Pointer(&f.data[0]))
+
+	softDirty.Lock()
+	defer softDirty.Unlock()
+
+	recorded, ok := softDirty.maps[addr]
+	if !ok {
+		return nil, errUnsupported
+	}
+	pages, err := scanSoftDirty(addr, len(f.data))
+	if err != nil {
+		return nil, err
+	}
+	for off := range recorded {
+		pages = append(pages, off)
+	}
+	pages = dedup(pages)
+	if !reset {
+		return pages, nil
+	}
+
+	err = clearSoftDirty(addr)
+	if err != nil {
+		return nil, err
+	}
+	softDirty.maps[addr] = make(map[int64]struct{})
+	return pages, nil
+}
+
+// clearSoftDirty clears the soft-dirty bits of the process, after recording
+// the dirty pages of the tracked mappings other than the one at skip.
+// clearSoftDirty must be called with softDirty held.
+func clearSoftDirty(skip uintptr) error {
+	for addr, recorded := range softDirty.maps {
+		if addr == skip {
+			continue
+		}
+		pages, err := scanSoftDirty(addr, softDirty.lens[addr])
+		if err != nil {
+			return err
+		}
+		for _, off := range pages {
+			recorded[off] = struct{}{}
+		}
+	}
+
+	err := os.WriteFile("/proc/self/clear_refs", []byte("4"), 0)
+	if err != nil {
+		return fmt.Errorf("mmap: could not clear soft-dirty bits: %w", err)
+	}
+	return nil
+}
+
+// scanSoftDirty returns the offsets of the soft-dirty pages of the n bytes
+// mapped at addr.
+func scanSoftDirty(addr uintptr, n int) ([]int64, error) {
+	page := uintptr(os.Getpagesize())
+	f, err := os.Open("/proc/self/pagemap")
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not open page map: %w", err)
+	}
+	defer f.Close()
+
+	entries := make([]byte, (uintptr(n)+page-1)/page*8)
+	_, err = f.ReadAt(entries, int64(addr/page*8))
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not read page map: %w", err)
+	}
+
+	var pages []int64
+	for i := 0; i < len(entries); i += 8 {
+		if binary.LittleEndian.Uint64(entries[i:])&pagemapSoftDirty != 0 {
+			pages = append(pages, int64(i/8)*int64(page))
+		}
+	}
+	return pages, nil
+}
+
+// dedup sorts offs and removes its duplicates.
+func dedup(offs []int64) []int64 {
+	sort.Slice(offs, func(i, j int) bool { return offs[i] < offs[j] })
+	out := offs[:0]
+	for i, off := range offs {
+		if i == 0 || off != offs[i-1] {
+			out = append(out, off)
+		}
+	}
+	return out
+}
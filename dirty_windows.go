@@ -0,0 +1,146 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"unsafe"
+
+	syscall "golang.org/x/sys/windows"
+)
+
+// writeWatchReset is the flag of GetWriteWatch resetting the tracking of
+// the pages it returns.
+const writeWatchReset = 0x1
+
+// mapWatched loads the size bytes of the file into memory watched for
+// writes, in place of a view of the file.
+func (f *File) mapWatched(size int64) error {
+	filename := f.fd.Name()
+	ptr, err := syscall.VirtualAlloc(f.cfg.addr, uintptr(size), syscall.MEM_RESERVE|syscall.MEM_COMMIT|syscall.MEM_WRITE_WATCH, syscall.PAGE_READWRITE)
+	if err != nil {
+		if f.cfg.addr != 0 {
+			return ErrAddrNotAvailable
+		}
+		return fmt.Errorf("mmap: could not allocate memory for %q: %w", filename, err)
+	}
+	data := sliceAt(ptr, int(size))
+
+	_, err = f.fd.ReadAt(data, 0)
+	if err == nil {
+		err = resetWriteWatch(data)
+	}
+	if err == nil && !f.wflag() {
+		var old uint32
+		err = syscall.VirtualProtect(ptr, uintptr(size), syscall.PAGE_READONLY, &old)
+	}
+	if err != nil {
+		_ = syscall.VirtualFree(ptr, 0, syscall.MEM_RELEASE)
+		return fmt.Errorf("mmap: could not load %q: %w", filename, err)
+	}
+	statMap(data)
+	f.data = data
+	return nil
+}
+
+// unmapWatched writes the dirty pages back to the file, and releases the
+// memory holding it.
+func (f *File) unmapWatched() error {
+	var err error
+	if f.wflag() {
+		err = f.writeBack()
+	}
+	statUnmap(f.data)
+	addr := f.addr()
+	f.data = nil
+	if e := syscall.VirtualFree(addr, 0, syscall.MEM_RELEASE); err == nil {
+		err = e
+	}
+	return err
+}
+
+// syncWatched writes the dirty pages back to the file, and commits it to
+// stable storage.
+func (f *File) syncWatched() error {
+	err := f.writeBack()
+	if err != nil {
+		return err
+	}
+	return f.flushFile()
+}
+
+// writeBack writes the dirty pages back to the file.
+// Once writing them failed, the pages left are no longer reported, and the
+// next call writes the whole file back instead.
+func (f *File) writeBack() error {
+	offs, err := f.dirtyPages(true)
+	if err != nil {
+		return err
+	}
+	if f.resync {
+		_, err = f.fd.WriteAt(f.data, 0)
+		if err != nil {
+			return fmt.Errorf("mmap: could not write back dirty pages: %w", err)
+		}
+		f.resync = false
+		return nil
+	}
+	page := int64(syscall.Getpagesize())
+	for i := 0; i < len(offs); {
+		// Coalesce runs of contiguous pages.
+		beg := offs[i]
+		end := beg + page
+		for i++; i < len(offs) && offs[i] == end; i++ {
+			end += page
+		}
+		if end > int64(len(f.data)) {
+			end = int64(len(f.data))
+		}
+		_, err = f.fd.WriteAt(f.data[beg:end], beg)
+		if err != nil {
+			f.resync = true
+			return fmt.Errorf("mmap: could not write back dirty pages: %w", err)
+		}
+	}
+	return nil
+}
+
+// dirtyPages returns the offsets of the pages written to since the last
+// reset of the tracking, resetting it if requested.
+func (f *File) dirtyPages(reset bool) ([]int64, error) {
+	if len(f.data) == 0 {
+		return nil, nil
+	}
+	var flags uintptr
+	if reset {
+		flags = writeWatchReset
+	}
+	base := f.addr()
+	addrs := make([]uintptr, (len(f.data)+syscall.Getpagesize()-1)/syscall.Getpagesize())
+	count := uintptr(len(addrs))
+	var granularity uint32
+	r1, _, e1 := procGetWriteWatch.Call(
+		flags, base, uintptr(len(f.data)),
+		uintptr(unsafe.Pointer(&addrs[0])), uintptr(unsafe.Pointer(&count)),
+		uintptr(unsafe.Pointer(&granularity)),
+	)
+	if r1 != 0 {
+		return nil, fmt.Errorf("mmap: could not get dirty pages: %w", e1)
+	}
+	offs := make([]int64, count)
+	for i, addr := range addrs[:count] {
+		offs[i] = int64(addr - base)
+	}
+	return offs, nil
+}
+
+// resetWriteWatch resets the tracking of the pages of data written to.
+func resetWriteWatch(data []byte) error {
+	r1, _, e1 := procResetWriteWatch.Call(uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)))
+	if r1 != 0 {
+		return e1
+	}
+	return nil
+}
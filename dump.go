@@ -0,0 +1,104 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// Dump writes the [off, off+n) range of the file to w in the canonical
+// hexdump format, as printed by hexdump -C: offsets, bytes in hexadecimal
+// and printable characters, 16 bytes per line.
+// Lines identical to the previous one are collapsed into a single "*" line.
+func (f *File) Dump(w io.Writer, off, n int64) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if f.closed() {
+		return errClosed
+	}
+	if size := f.size(); off < 0 || n < 0 || size < off || size-off < n {
+		return fmt.Errorf("mmap: invalid range [%d, %d)", off, off+n)
+	}
+
+	bw := bufio.NewWriter(w)
+	var (
+		line, prev [16]byte
+		squeezed   bool
+		end        = off + n
+	)
+	for pos := off; pos < end; pos += int64(len(line)) {
+		m := len(line)
+		if int64(m) > end-pos {
+			m = int(end - pos)
+		}
+		_, err := f.readAt(line[:m], pos)
+		if err != nil {
+			return err
+		}
+		if pos > off && m == len(line) && line == prev {
+			if !squeezed {
+				bw.WriteString("*\n")
+				squeezed = true
+			}
+			continue
+		}
+		squeezed = false
+		prev = line
+		dumpLine(bw, pos, line[:m])
+	}
+	if n > 0 {
+		fmt.Fprintf(bw, "%08x\n", end)
+	}
+	return bw.Flush()
+}
+
+// dumpLine writes a line of the canonical hexdump format.
+func dumpLine(w *bufio.Writer, off int64, b []byte) {
+	fmt.Fprintf(w, "%08x ", off)
+	for i := 0; i < 16; i++ {
+		if i == 8 {
+			w.WriteByte(' ')
+		}
+		if i < len(b) {
+			fmt.Fprintf(w, " %02x", b[i])
+		} else {
+			w.WriteString("   ")
+		}
+	}
+	w.WriteString("  |")
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		w.WriteByte(c)
+	}
+	w.WriteString("|\n")
+}
+
+// String returns a summary of the file: its name, size, access flags and
+// the address of its mapping.
+func (f *File) String() string {
+	if f == nil {
+		return "mmap.File(nil)"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "mmap.File(%q, %d bytes, %s", f.name, f.size(), f.flag.mode())
+	switch {
+	case f.closed():
+		buf.WriteString(", closed")
+	case f.w != nil:
+		buf.WriteString(", windowed")
+	case len(f.data) > 0:
+		fmt.Fprintf(&buf, ", at %#x", uintptr(unsafe.Pointer(&f.data[0])))
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package encrypted
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+// mapAnon maps n bytes of private, zeroed memory not backed by any file.
+func mapAnon(n int) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	return syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+// lockAnon locks the pages of the mapping in memory, so that they are
+// never written to swap.
+func lockAnon(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return syscall.Mlock(data)
+}
+
+func unmapAnon(data []byte) error {
+	return syscall.Munmap(data)
+}
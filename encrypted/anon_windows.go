@@ -0,0 +1,39 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package encrypted
+
+import (
+	"unsafe"
+
+	syscall "golang.org/x/sys/windows"
+)
+
+// mapAnon maps n bytes of private, zeroed memory not backed by any file.
+func mapAnon(n int) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	addr, err := syscall.VirtualAlloc(0, uintptr(n), syscall.MEM_RESERVE|syscall.MEM_COMMIT, syscall.PAGE_READWRITE)
+	if err != nil {
+		return nil, err
+	}
+	// addr is not a Go pointer: convert it without going through a uintptr
+	// expression, which is what the unsafe.Pointer rules disallow.
+	p := *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+	return unsafe.Slice((*byte)(p), n), nil
+}
+
+// lockAnon locks the pages of the mapping in memory, so that they are
+// never written to the paging file.
+func lockAnon(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return syscall.VirtualLock(uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)))
+}
+
+func unmapAnon(data []byte) error {
+	return syscall.VirtualFree(uintptr(unsafe.Pointer(&data[0])), 0, syscall.MEM_RELEASE)
+}
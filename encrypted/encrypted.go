@@ -0,0 +1,362 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package encrypted provides memory-mapped access to encrypted files.
+//
+// The contents of an encrypted file are split into pages, each sealed with
+// AES-GCM under its own random nonce and bound to its index and to the size
+// of the file, so that tampered pages and pages moved around are detected.
+// Rollback is not: a page replaced with an older ciphertext of the same
+// page, from a copy of the file of the same size, decrypts to the older
+// plaintext without error.
+//
+// Pages are decrypted on first access into an anonymous mapping, which is
+// never backed by the file, and encrypted back to the file on Sync. The
+// mapping is locked in memory, where the OS allows it, so that the
+// plaintext is never written to swap: see File.Locked.
+package encrypted
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-mmap/mmap"
+)
+
+const (
+	// pageSize is the size of the plaintext of a page.
+	pageSize = 4096
+
+	// hdrSize is the size of the header of an encrypted file: a magic
+	// string followed by the size of the plaintext.
+	hdrSize = 16
+)
+
+var magic = [8]byte{'m', 'm', 'a', 'p', 'e', 'n', 'c', '1'}
+
+var (
+	errBadFD  = errors.New("bad file descriptor")
+	errClosed = errors.New("encrypted: closed")
+)
+
+// File is a memory-mapped encrypted file.
+// A File must not be used from several goroutines at once.
+type File struct {
+	f    *mmap.File // encrypted contents
+	aead cipher.AEAD
+	flag mmap.Flag
+	size int64 // size of the plaintext
+
+	closed bool
+
+	plain  []byte // anonymous mapping holding the decrypted pages
+	locked bool   // plain is locked in memory.
+	loaded []bool // loaded[i] is set once the i-th page was decrypted.
+	dirty  []bool // dirty[i] is set when the i-th page was written since the last sync.
+}
+
+// Create creates the named file, holding size zero bytes encrypted with
+// key, and memory-maps it for reading and writing.
+// key must be 16, 24 or 32 bytes long, to select AES-128, AES-192 or AES-256.
+func Create(filename string, size int64, key []byte) (*File, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("encrypted: invalid size %d", size)
+	}
+
+	n := pages(size)
+	raw := make([]byte, hdrSize+n*sealedSize(aead))
+	copy(raw, magic[:])
+	binary.LittleEndian.PutUint64(raw[8:], uint64(size))
+	zero := make([]byte, pageSize)
+	for i := int64(0); i < n; i++ {
+		beg := hdrSize + i*sealedSize(aead)
+		err = seal(aead, raw[beg:beg:beg+sealedSize(aead)], zero[:plainLen(size, i)], i, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = os.WriteFile(filename, raw, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted: could not create %q: %w", filename, err)
+	}
+	return Open(filename, mmap.Read|mmap.Write, key)
+}
+
+// Open memory-maps the named encrypted file for reading/writing, depending
+// on the flag value, and decrypts it with key.
+func Open(filename string, flag mmap.Flag, key []byte) (*File, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := mmap.OpenFile(filename, flag|mmap.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	var hdr [hdrSize]byte
+	_, err = f.ReadAt(hdr[:], 0)
+	if err != nil || !bytes.Equal(hdr[:8], magic[:]) {
+		_ = f.Close()
+		return nil, fmt.Errorf("encrypted: %q is not an encrypted file", filename)
+	}
+	size := int64(binary.LittleEndian.Uint64(hdr[8:]))
+	n := pages(size)
+	if size < 0 || f.Size() != hdrSize+n*sealedSize(aead) {
+		_ = f.Close()
+		return nil, fmt.Errorf("encrypted: %q has an invalid size", filename)
+	}
+
+	plain, err := mapAnon(int(n * pageSize))
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("encrypted: could not map decrypted view of %q: %w", filename, err)
+	}
+	// Locking fails when the process may not lock that much memory, which
+	// Locked reports rather than failing to open the file.
+	locked := lockAnon(plain) == nil
+
+	return &File{
+		f:      f,
+		aead:   aead,
+		flag:   flag,
+		size:   size,
+		plain:  plain,
+		locked: locked,
+		loaded: make([]bool, n),
+		dirty:  make([]bool, n),
+	}, nil
+}
+
+// Locked reports whether the decrypted pages of the file are locked in
+// memory, so that they are never written to swap.
+// Locking them is best effort: it fails when the process may not lock that
+// much memory, as limited by RLIMIT_MEMLOCK on unix, or by the working set
+// of the process on Windows.
+func (f *File) Locked() bool {
+	return f.locked && !f.closed
+}
+
+// Len returns the length of the decrypted contents of the file.
+func (f *File) Len() int {
+	return int(f.size)
+}
+
+// Size returns the length of the decrypted contents of the file.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.flag&mmap.Read == 0 {
+		return 0, errBadFD
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("encrypted: invalid ReadAt offset %d", off)
+	}
+	if f.size <= off {
+		return 0, io.EOF
+	}
+	n, err := f.load(off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n = copy(p[:n], f.plain[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements the io.WriterAt interface.
+// Written pages are only encrypted to the file by Sync.
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	if f.flag&mmap.Write == 0 {
+		return 0, errBadFD
+	}
+	if off < 0 || f.size < off {
+		return 0, fmt.Errorf("encrypted: invalid WriteAt offset %d", off)
+	}
+	n, err := f.load(off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n = copy(f.plain[off:off+int64(n)], p)
+	for i := off / pageSize; i*pageSize < off+int64(n); i++ {
+		f.dirty[i] = true
+	}
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Sync encrypts the pages written since the last sync to the file, and
+// commits it to stable storage.
+func (f *File) Sync() error {
+	if f.flag&mmap.Write == 0 {
+		return errBadFD
+	}
+	if f.closed {
+		return errClosed
+	}
+	buf := make([]byte, 0, sealedSize(f.aead))
+	for i, dirty := range f.dirty {
+		if !dirty {
+			continue
+		}
+		i := int64(i)
+		off := i * pageSize
+		err := seal(f.aead, buf, f.plain[off:off+plainLen(f.size, i)], i, f.size)
+		if err != nil {
+			return err
+		}
+		_, err = f.f.WriteAt(buf[:cap(buf)], hdrSize+i*sealedSize(f.aead))
+		if err != nil {
+			return err
+		}
+		f.dirty[i] = false
+	}
+	return f.f.Sync()
+}
+
+// Close closes the file.
+// Writes that were not synced are discarded.
+// The decrypted pages are wiped from memory.
+func (f *File) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	var err error
+	if f.plain != nil {
+		for i := range f.plain {
+			f.plain[i] = 0
+		}
+		err = unmapAnon(f.plain)
+		f.plain = nil
+	}
+	if e := f.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// load decrypts the pages overlapping the [off, off+n) range that were not
+// decrypted yet.
+// It returns the number of bytes of the range that lie within the file.
+func (f *File) load(off, n int64) (int, error) {
+	if f.closed {
+		return 0, errClosed
+	}
+	if n > f.size-off {
+		n = f.size - off
+	}
+
+	buf := make([]byte, sealedSize(f.aead))
+	for i := off / pageSize; i*pageSize < off+n; i++ {
+		if f.loaded[i] {
+			continue
+		}
+		_, err := f.f.ReadAt(buf, hdrSize+i*sealedSize(f.aead))
+		if err != nil {
+			return 0, err
+		}
+		beg := i * pageSize
+		out, err := open(f.aead, f.plain[beg:beg], buf[:sealedLen(f.aead, f.size, i)], i, f.size)
+		if err != nil {
+			return 0, err
+		}
+		if int64(len(out)) != plainLen(f.size, i) {
+			return 0, fmt.Errorf("encrypted: page %d has an invalid size", i)
+		}
+		f.loaded[i] = true
+	}
+	return int(n), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted: invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// pages returns the number of pages holding size bytes of plaintext.
+func pages(size int64) int64 {
+	return (size + pageSize - 1) / pageSize
+}
+
+// plainLen returns the size of the plaintext of the i-th page of a file
+// holding size bytes: all pages are full, except maybe the last one.
+func plainLen(size, i int64) int64 {
+	if n := size - i*pageSize; n < pageSize {
+		return n
+	}
+	return pageSize
+}
+
+// sealedSize returns the space taken by a sealed page in the file.
+// Pages are padded to this size, so that they can be located by index.
+func sealedSize(aead cipher.AEAD) int64 {
+	return int64(aead.NonceSize() + pageSize + aead.Overhead())
+}
+
+// sealedLen returns the length of the meaningful part of the i-th sealed
+// page of a file holding size bytes.
+func sealedLen(aead cipher.AEAD, size, i int64) int64 {
+	return int64(aead.NonceSize()+aead.Overhead()) + plainLen(size, i)
+}
+
+// additionalData binds a sealed page to its index and to the size of the
+// file.
+func additionalData(i, size int64) []byte {
+	var ad [16]byte
+	binary.LittleEndian.PutUint64(ad[:8], uint64(i))
+	binary.LittleEndian.PutUint64(ad[8:], uint64(size))
+	return ad[:]
+}
+
+// seal encrypts the plaintext of the i-th page into dst, which must have
+// room for sealedSize bytes.
+func seal(aead cipher.AEAD, dst, plain []byte, i, size int64) error {
+	dst = dst[:aead.NonceSize()]
+	_, err := rand.Read(dst)
+	if err != nil {
+		return fmt.Errorf("encrypted: could not generate nonce: %w", err)
+	}
+	out := aead.Seal(dst, dst, plain, additionalData(i, size))
+	// Clear the padding, so that no stale data lingers in the file.
+	pad := dst[len(out):cap(dst)]
+	for j := range pad {
+		pad[j] = 0
+	}
+	return nil
+}
+
+// open decrypts the sealed i-th page and appends its plaintext to dst.
+func open(aead cipher.AEAD, dst, sealed []byte, i, size int64) ([]byte, error) {
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	out, err := aead.Open(dst, nonce, ciphertext, additionalData(i, size))
+	if err != nil {
+		return nil, fmt.Errorf("encrypted: could not decrypt page %d: %w", i, err)
+	}
+	return out, nil
+}
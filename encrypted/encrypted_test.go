@@ -0,0 +1,108 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package encrypted
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestEncrypted(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "secret.bin")
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	f, err := Create(fname, 2*pageSize+10, key)
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+	defer f.Close()
+
+	msg := []byte("attack at dawn")
+	_, err = f.WriteAt(msg, pageSize-4)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	if !f.Locked() {
+		t.Logf("decrypted pages not locked in memory")
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+	if f.Locked() {
+		t.Fatalf("closed file reported as locked")
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if bytes.Contains(raw, msg) {
+		t.Fatalf("plaintext leaked to the file")
+	}
+
+	r, err := Open(fname, mmap.Read, key)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer r.Close()
+
+	if got, want := r.Size(), int64(2*pageSize+10); got != want {
+		t.Fatalf("invalid size: got=%d, want=%d", got, want)
+	}
+	got := make([]byte, len(msg))
+	_, err = r.ReadAt(got, pageSize-4)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Fatalf("invalid content: %q", got)
+	}
+	_, err = r.WriteAt(msg, 0)
+	if err == nil {
+		t.Fatalf("expected an error writing to a read-only file")
+	}
+
+	w, err := Open(fname, mmap.Read, bytes.Repeat([]byte{0x43}, 32))
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer w.Close()
+	_, err = w.ReadAt(got, pageSize-4)
+	if err == nil {
+		t.Fatalf("expected an error reading with the wrong key")
+	}
+
+	// Tamper with the ciphertext of the last page.
+	// Mapped files can not be rewritten on Windows.
+	_ = r.Close()
+	_ = w.Close()
+	raw[hdrSize+2*(12+pageSize+16)+20] ^= 0xff
+	err = os.WriteFile(fname, raw, 0600)
+	if err != nil {
+		t.Fatalf("could not write file: %+v", err)
+	}
+	c, err := Open(fname, mmap.Read, key)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer c.Close()
+	_, err = c.ReadAt(got[:10], 2*pageSize)
+	if err == nil {
+		t.Fatalf("expected an error reading a tampered page")
+	}
+	_, err = c.ReadAt(got[:10], 0)
+	if err != nil {
+		t.Fatalf("could not read-at intact page: %+v", err)
+	}
+}
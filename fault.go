@@ -0,0 +1,39 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+// Syscall identifies a system call made by the package, for fault
+// injection.
+type Syscall string
+
+const (
+	SyscallMmap   Syscall = "mmap"   // mmap, or MapViewOfFile on Windows.
+	SyscallMunmap Syscall = "munmap" // munmap, or UnmapViewOfFile on Windows.
+	SyscallMsync  Syscall = "msync"  // msync, or FlushViewOfFile on Windows.
+
+	// SyscallCreateFileMapping is only made on Windows.
+	SyscallCreateFileMapping Syscall = "CreateFileMapping"
+)
+
+// WithFaults makes fn intercept the system calls mapping, unmapping and
+// syncing the file: when fn returns a non-nil error, the system call is
+// skipped and fails with that error.
+// A skipped munmap leaves the memory mapped.
+//
+// WithFaults is meant for testing the handling of errors that are hard to
+// trigger otherwise.
+func WithFaults(fn func(call Syscall) error) Option {
+	return func(o *options) {
+		o.faults = fn
+	}
+}
+
+// fault returns the error injected for the call, if any.
+func (f *File) fault(call Syscall) error {
+	if f.cfg.faults == nil {
+		return nil
+	}
+	return f.cfg.faults(call)
+}
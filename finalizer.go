@@ -0,0 +1,66 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// FinalizerMode tells what happens to a file that becomes unreachable
+// without being closed.
+type FinalizerMode int
+
+const (
+	// FinalizerClose closes unreachable files, releasing their mapping
+	// and descriptor. This is the default.
+	FinalizerClose FinalizerMode = iota
+	// FinalizerNone sets no finalizer on files, for callers guaranteeing
+	// they close every file, and opening so many short-lived ones that
+	// the cost of finalizers shows. Unreachable files that were not
+	// closed leak their mapping and descriptor.
+	FinalizerNone
+	// FinalizerPanic panics when an unreachable file was not closed,
+	// reporting where it was opened, to catch leaks in tests.
+	FinalizerPanic
+)
+
+// WithFinalizer sets what happens to the file if it becomes unreachable
+// without being closed.
+// The clones of the file have the same mode, while its snapshots are
+// always closed when unreachable.
+func WithFinalizer(mode FinalizerMode) Option {
+	return func(o *options) {
+		o.finalizer = mode
+	}
+}
+
+// setFinalizer sets the finalizer of f, as its mode asks.
+func (f *File) setFinalizer() {
+	switch f.cfg.finalizer {
+	case FinalizerNone:
+	case FinalizerPanic:
+		var pcs [32]uintptr
+		f.stack = pcs[:runtime.Callers(3, pcs[:])]
+		runtime.SetFinalizer(f, (*File).leaked)
+	default:
+		runtime.SetFinalizer(f, (*File).Close)
+	}
+}
+
+// leaked is the finalizer of files opened with FinalizerPanic.
+func (f *File) leaked() {
+	var b strings.Builder
+	frames := runtime.CallersFrames(f.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	panic(fmt.Sprintf("mmap: file %q was not closed, opened at:%s", f.fd.Name(), b.String()))
+}
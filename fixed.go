@@ -0,0 +1,66 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errPinned = errors.New("mmap: mapping registered as a fixed buffer")
+
+// RegisterBuffer returns the mapping of the file, for registering it as a
+// fixed buffer of an asynchronous I/O interface, such as io_uring with
+// IORING_REGISTER_BUFFERS, so that the mapped bytes are sent to sockets or
+// written to other files without being copied.
+//
+// The mapping then stays at the same address, with the same length, until
+// the file is closed: Remap, Seal and writes growing files opened with
+// WithAutoExtend fail instead of mapping the file again.
+// Close calls unregister, if not nil, before unmapping the file, so that
+// the buffer is unregistered while still mapped; its error is reported by
+// Close. The hooks of several registrations run in reverse order.
+//
+// Files mapped through a sliding window, or read through their descriptor,
+// have no mapping to register, nor have empty files.
+// Note that Linux only registers the pages of files backed by memory, such
+// as the ones of memfd, tmpfs and hugetlbfs, and fails for regular files.
+func (f *File) RegisterBuffer(unregister func() error) ([]byte, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	if f.w != nil || len(f.data) == 0 {
+		return nil, fmt.Errorf("mmap: file %q has no mapping to register: %w", f.fd.Name(), errUnsupported)
+	}
+	if unregister == nil {
+		unregister = func() error { return nil }
+	}
+	f.pins = append(f.pins, unregister)
+	return f.data, nil
+}
+
+// pinned reports whether the mapping of the file is registered as a fixed
+// buffer, and must not move.
+func (f *File) pinned() bool {
+	return len(f.pins) > 0
+}
+
+// unpin calls the hooks unregistering the buffers of the mapping, in
+// reverse order.
+func (f *File) unpin() error {
+	var errs []error
+	for i := len(f.pins) - 1; i >= 0; i-- {
+		err := f.pins[i]()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mmap: could not unregister buffer of %q: %w", f.fd.Name(), err))
+		}
+	}
+	f.pins = nil
+	return joinErrors(errs...)
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "io"
+
+// ReadFullAt reads exactly len(p) bytes of the file at off into p, as
+// io.ReadFull does from a reader.
+// The error is io.EOF only if no bytes were read, because off is at or
+// past the end of the file, and io.ErrUnexpectedEOF if some, but not all,
+// of the bytes were read.
+//
+// Reads are retried for as long as they make progress, so that reads
+// spanning several views of a file mapped through a sliding window, or a
+// remap of a file growing meanwhile, complete.
+func (f *File) ReadFullAt(p []byte, off int64) error {
+	n := 0
+	for n < len(p) {
+		m, err := f.ReadAt(p[n:], off+int64(n))
+		n += m
+		if n == len(p) {
+			return nil
+		}
+		if m > 0 && (err == nil || err == io.EOF) {
+			continue
+		}
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			err = io.ErrNoProgress
+		}
+		return err
+	}
+	return nil
+}
+
+// WriteFullAt writes all of p to the file at off.
+// Writing past the end of a file not opened with WithAutoExtend fails with
+// ErrNoSpace, after writing the bytes that fit.
+//
+// Writes are retried for as long as they make progress, as with
+// ReadFullAt.
+func (f *File) WriteFullAt(p []byte, off int64) error {
+	n := 0
+	for n < len(p) {
+		m, err := f.WriteAt(p[n:], off+int64(n))
+		n += m
+		if n == len(p) {
+			return err
+		}
+		if m > 0 && (err == nil || err == ErrNoSpace) {
+			continue
+		}
+		if err == nil {
+			err = io.ErrNoProgress
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzSeekReadWrite drives random sequences of Seek, Read, Write, ReadAt
+// and WriteAt against a File and against an os.File over a copy of the same
+// contents, and checks that they agree.
+//
+// Unlike an os.File, a File never grows: writes are cut at the end of the
+// file. Seek with io.SeekEnd moves the cursor offset bytes before the end
+// of the file. And empty reads at or past the end of the file return
+// io.EOF.
+func FuzzSeekReadWrite(f *testing.F) {
+	f.Add([]byte{0, 10, 0, 2, 4, 0, 1, 8, 0})
+	f.Add([]byte{3, 60, 10, 4, 70, 2, 1, 200, 0, 2, 0, 0, 0, 5, 1})
+	f.Add([]byte{0, 0x80, 1, 0, 200, 1, 0, 5, 2, 2, 16, 0})
+
+	const size = 64
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		dir := t.TempDir()
+		init := make([]byte, size)
+		for i := range init {
+			init[i] = byte(i)
+		}
+		fname := filepath.Join(dir, "mmap.bin")
+		rname := filepath.Join(dir, "ref.bin")
+		for _, name := range []string{fname, rname} {
+			err := os.WriteFile(name, init, 0644)
+			if err != nil {
+				t.Fatalf("could not seed file: %+v", err)
+			}
+		}
+
+		m, err := OpenFile(fname, Read|Write)
+		if err != nil {
+			t.Fatalf("could not mmap file: %+v", err)
+		}
+		defer m.Close()
+		ref, err := os.OpenFile(rname, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("could not open reference file: %+v", err)
+		}
+		defer ref.Close()
+
+		pos := int64(0) // cursor of the reference file.
+		for len(ops) >= 3 {
+			op, a, b := ops[0]%5, int64(int8(ops[1])), int(ops[2]%(2*size))
+			ops = ops[3:]
+			p := bytes.Repeat([]byte{byte(a)}, b)
+
+			switch op {
+			case 0: // Seek
+				whence := b % 3
+				got, gerr := m.Seek(a, whence)
+				if whence == io.SeekEnd {
+					a = -a
+				}
+				want, werr := ref.Seek(a, whence)
+				if (gerr == nil) != (werr == nil) {
+					t.Fatalf("Seek(%d, %d): got err=%v, want err=%v", a, whence, gerr, werr)
+				}
+				if gerr == nil {
+					if got != want {
+						t.Fatalf("Seek(%d, %d): got=%d, want=%d", a, whence, got, want)
+					}
+					pos = want
+				}
+
+			case 1: // Read
+				got := make([]byte, b)
+				want := make([]byte, b)
+				gn, gerr := m.Read(got)
+				wn, werr := ref.Read(want)
+				if werr == io.EOF {
+					wn = 0
+				}
+				pos += int64(wn)
+				if gn != wn || !bytes.Equal(got[:gn], want[:wn]) {
+					t.Fatalf("Read(%d): got=%q, want=%q", b, got[:gn], want[:wn])
+				}
+				if b > 0 && (gerr == nil) != (werr == nil) {
+					t.Fatalf("Read(%d): got err=%v, want err=%v", b, gerr, werr)
+				}
+
+			case 2: // ReadAt
+				got := make([]byte, b)
+				want := make([]byte, b)
+				gn, gerr := m.ReadAt(got, a)
+				wn, werr := ref.ReadAt(want, a)
+				if gn != wn || !bytes.Equal(got[:gn], want[:wn]) {
+					t.Fatalf("ReadAt(%d, %d): got=%q, want=%q", b, a, got[:gn], want[:wn])
+				}
+				if b > 0 && ((gerr == nil) != (werr == nil) || errors.Is(gerr, io.EOF) != errors.Is(werr, io.EOF)) {
+					t.Fatalf("ReadAt(%d, %d): got err=%v, want err=%v", b, a, gerr, werr)
+				}
+
+			case 3: // Write
+				gn, gerr := m.Write(p)
+				wn := 0
+				if pos < size {
+					q := p
+					if int64(len(q)) > size-pos {
+						q = q[:size-pos]
+					}
+					wn, err = ref.Write(q)
+					if err != nil {
+						t.Fatalf("could not write reference file: %+v", err)
+					}
+					pos += int64(wn)
+				}
+				if gn != wn {
+					t.Fatalf("Write(%d) at %d: got n=%d, want n=%d", len(p), pos, gn, wn)
+				}
+				if len(p) > 0 && (gerr == nil) != (wn == len(p)) {
+					t.Fatalf("Write(%d): got err=%v, with %d bytes written", len(p), gerr, wn)
+				}
+
+			case 4: // WriteAt
+				off := a + size/2
+				gn, gerr := m.WriteAt(p, off)
+				if off < 0 || size < off {
+					if gerr == nil {
+						t.Fatalf("WriteAt(%d, %d): expected an error", len(p), off)
+					}
+					continue
+				}
+				q := p
+				if int64(len(q)) > size-off {
+					q = q[:size-off]
+				}
+				wn, err := ref.WriteAt(q, off)
+				if err != nil {
+					t.Fatalf("could not write reference file: %+v", err)
+				}
+				if gn != wn {
+					t.Fatalf("WriteAt(%d, %d): got n=%d, want n=%d", len(p), off, gn, wn)
+				}
+				if (gerr == nil) != (wn == len(p)) {
+					t.Fatalf("WriteAt(%d, %d): got err=%v, with %d bytes written", len(p), off, gerr, wn)
+				}
+			}
+		}
+
+		got := make([]byte, size)
+		want := make([]byte, size)
+		_, err = m.ReadAt(got, 0)
+		if err != nil {
+			t.Fatalf("could not read file: %+v", err)
+		}
+		_, err = ref.ReadAt(want, 0)
+		if err != nil {
+			t.Fatalf("could not read reference file: %+v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("contents differ:\ngot= %q\nwant=%q", got, want)
+		}
+	})
+}
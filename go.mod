@@ -2,4 +2,4 @@ module github.com/go-mmap/mmap
 
 go 1.19
 
-require golang.org/x/sys v0.6.0
+require golang.org/x/sys v0.30.0
@@ -0,0 +1,389 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package index provides a persistent hash table laid out in a
+// memory-mapped file, mapping fixed-size keys to fixed-size values.
+//
+// The table uses open addressing with linear probing. It grows by
+// rehashing into a new file that atomically replaces the previous one, so
+// that a crash while growing leaves the table as it was before.
+// Other updates are made in place: they reach the file when synced, or when
+// the OS writes the pages back, and an update interrupted by a crash may
+// leave the value of its slot partially written.
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/go-mmap/mmap"
+)
+
+const (
+	// hdrSize is the size of the header of an index file: a magic string,
+	// the sizes of keys and values, the number of slots, and the numbers
+	// of used and deleted slots.
+	hdrSize = 64
+
+	// minSlots is the number of slots of a new table.
+	minSlots = 64
+)
+
+var magic = [8]byte{'m', 'm', 'a', 'p', 'i', 'd', 'x', '1'}
+
+// ErrClosed is returned when using a closed index.
+var ErrClosed = errors.New("index: closed")
+
+// States of a slot.
+const (
+	slotEmpty   = 0
+	slotUsed    = 1
+	slotDeleted = 2
+)
+
+// Index is a persistent hash table held by a memory-mapped file.
+// An Index must not be used from several goroutines at once.
+type Index struct {
+	f       *mmap.File
+	name    string
+	keySize int
+	valSize int
+	slots   uint64 // number of slots, a power of two.
+	hdr     []byte // header of the file.
+	data    []byte // slots, each made of a state byte, the key and the value.
+}
+
+// Create creates the named file holding an empty index, mapping keys of
+// keySize bytes to values of valueSize bytes, and opens it.
+// An existing file is replaced.
+func Create(filename string, keySize, valueSize int) (*Index, error) {
+	if keySize <= 0 || valueSize < 0 {
+		return nil, fmt.Errorf("index: invalid key or value size %d, %d", keySize, valueSize)
+	}
+	ix := &Index{name: filename, keySize: keySize, valSize: valueSize}
+	a, err := ix.create(minSlots)
+	if err != nil {
+		return nil, err
+	}
+	err = a.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filename)
+}
+
+// Open opens the index held by the named file, created with Create.
+func Open(filename string) (*Index, error) {
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	ix := &Index{f: f, name: filename}
+	err = ix.load(f.Bytes())
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return ix, nil
+}
+
+// load checks the header of the index file mapped at b, and sets up ix to
+// use it.
+func (ix *Index) load(b []byte) error {
+	if len(b) < hdrSize || !bytes.Equal(b[:8], magic[:]) {
+		return fmt.Errorf("index: %q is not an index", ix.name)
+	}
+	keySize := int(binary.LittleEndian.Uint32(b[8:]))
+	valSize := int(binary.LittleEndian.Uint32(b[12:]))
+	slots := binary.LittleEndian.Uint64(b[16:])
+	if keySize == 0 || slots == 0 || slots&(slots-1) != 0 ||
+		uint64(len(b)-hdrSize) != slots*uint64(1+keySize+valSize) {
+		return fmt.Errorf("index: invalid header of %q", ix.name)
+	}
+	ix.keySize = keySize
+	ix.valSize = valSize
+	ix.slots = slots
+	ix.hdr = b[:hdrSize]
+	ix.data = b[hdrSize:]
+	return nil
+}
+
+// create creates a temporary index file of the given number of slots, to
+// replace the index file once filled.
+func (ix *Index) create(slots uint64) (*mmap.AtomicFile, error) {
+	size := hdrSize + int64(slots)*int64(ix.slotSize())
+	a, err := mmap.CreateAtomic(ix.name, size)
+	if err != nil {
+		return nil, err
+	}
+	b := a.Bytes()
+	copy(b, magic[:])
+	binary.LittleEndian.PutUint32(b[8:], uint32(ix.keySize))
+	binary.LittleEndian.PutUint32(b[12:], uint32(ix.valSize))
+	binary.LittleEndian.PutUint64(b[16:], slots)
+	return a, nil
+}
+
+func (ix *Index) slotSize() int {
+	return 1 + ix.keySize + ix.valSize
+}
+
+// slot returns the i-th slot.
+func (ix *Index) slot(i uint64) []byte {
+	beg := i * uint64(ix.slotSize())
+	return ix.data[beg : beg+uint64(ix.slotSize())]
+}
+
+func (ix *Index) header() []byte {
+	return ix.hdr
+}
+
+func (ix *Index) used() uint64 {
+	return binary.LittleEndian.Uint64(ix.header()[24:])
+}
+
+func (ix *Index) deleted() uint64 {
+	return binary.LittleEndian.Uint64(ix.header()[32:])
+}
+
+func (ix *Index) setCounts(used, deleted uint64) {
+	binary.LittleEndian.PutUint64(ix.header()[24:], used)
+	binary.LittleEndian.PutUint64(ix.header()[32:], deleted)
+}
+
+// hash returns the FNV-1a hash of key, which is part of the file format.
+func hash(key []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range key {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// find returns the slot holding key, and reports whether it was found.
+// If not, it returns the first slot where key can be inserted.
+func (ix *Index) find(key []byte) (uint64, bool) {
+	mask := ix.slots - 1
+	free, hasFree := uint64(0), false
+	for i, n := hash(key)&mask, uint64(0); n < ix.slots; i, n = (i+1)&mask, n+1 {
+		s := ix.slot(i)
+		switch s[0] {
+		case slotEmpty:
+			if !hasFree {
+				free = i
+			}
+			return free, false
+		case slotDeleted:
+			if !hasFree {
+				free, hasFree = i, true
+			}
+		case slotUsed:
+			if bytes.Equal(s[1:1+ix.keySize], key) {
+				return i, true
+			}
+		}
+	}
+	return free, false
+}
+
+func (ix *Index) check(key []byte) error {
+	if ix.f == nil {
+		return ErrClosed
+	}
+	if len(key) != ix.keySize {
+		return fmt.Errorf("index: invalid key size %d, want %d", len(key), ix.keySize)
+	}
+	return nil
+}
+
+// KeySize returns the size of the keys, in bytes.
+func (ix *Index) KeySize() int {
+	return ix.keySize
+}
+
+// ValueSize returns the size of the values, in bytes.
+func (ix *Index) ValueSize() int {
+	return ix.valSize
+}
+
+// Len returns the number of keys in the index.
+func (ix *Index) Len() int {
+	if ix.f == nil {
+		return 0
+	}
+	return int(ix.used())
+}
+
+// Get returns a copy of the value associated with key, and reports whether
+// key is in the index.
+func (ix *Index) Get(key []byte) ([]byte, bool) {
+	if ix.check(key) != nil {
+		return nil, false
+	}
+	i, ok := ix.find(key)
+	if !ok {
+		return nil, false
+	}
+	v := make([]byte, ix.valSize)
+	copy(v, ix.slot(i)[1+ix.keySize:])
+	return v, true
+}
+
+// Put associates value with key, replacing the previous value of key.
+// The index grows as needed.
+func (ix *Index) Put(key, value []byte) error {
+	if err := ix.check(key); err != nil {
+		return err
+	}
+	if len(value) != ix.valSize {
+		return fmt.Errorf("index: invalid value size %d, want %d", len(value), ix.valSize)
+	}
+
+	i, ok := ix.find(key)
+	if ok {
+		copy(ix.slot(i)[1+ix.keySize:], value)
+		return nil
+	}
+	// Keep at least a quarter of the slots empty, for probing to stay
+	// short and to always end on an empty slot.
+	used, deleted := ix.used(), ix.deleted()
+	if (used+deleted+1)*4 > ix.slots*3 {
+		err := ix.grow(used + 1)
+		if err != nil {
+			return err
+		}
+		i, _ = ix.find(key)
+		used, deleted = ix.used(), ix.deleted()
+	}
+
+	s := ix.slot(i)
+	if s[0] == slotDeleted {
+		deleted--
+	}
+	copy(s[1:], key)
+	copy(s[1+ix.keySize:], value)
+	// Mark the slot used last, so that an interrupted insertion is not
+	// observed.
+	s[0] = slotUsed
+	ix.setCounts(used+1, deleted)
+	return nil
+}
+
+// Delete removes key from the index, and reports whether it was there.
+func (ix *Index) Delete(key []byte) (bool, error) {
+	if err := ix.check(key); err != nil {
+		return false, err
+	}
+	i, ok := ix.find(key)
+	if !ok {
+		return false, nil
+	}
+	ix.slot(i)[0] = slotDeleted
+	ix.setCounts(ix.used()-1, ix.deleted()+1)
+	return true, nil
+}
+
+// Iterate calls fn for each key of the index and its value, in no
+// particular order, until fn returns false.
+// The slices passed to fn alias the mapping: they are only valid during
+// the call, and must not be modified.
+// The index must not be modified during the iteration.
+func (ix *Index) Iterate(fn func(key, value []byte) bool) {
+	if ix.f == nil {
+		return
+	}
+	for i := uint64(0); i < ix.slots; i++ {
+		s := ix.slot(i)
+		if s[0] != slotUsed {
+			continue
+		}
+		if !fn(s[1:1+ix.keySize:1+ix.keySize], s[1+ix.keySize:]) {
+			return
+		}
+	}
+}
+
+// grow rehashes the index into a new file with room for n keys, which
+// atomically replaces the index file.
+func (ix *Index) grow(n uint64) error {
+	slots := ix.slots
+	for n*2 > slots {
+		slots *= 2
+	}
+	a, err := ix.create(slots)
+	if err != nil {
+		return fmt.Errorf("index: could not grow %q: %w", ix.name, err)
+	}
+	dst := &Index{name: ix.name, keySize: ix.keySize, valSize: ix.valSize}
+	err = dst.load(a.Bytes())
+	if err != nil {
+		_ = a.Close()
+		return err
+	}
+	ix.Iterate(func(key, value []byte) bool {
+		i, _ := dst.find(key)
+		s := dst.slot(i)
+		s[0] = slotUsed
+		copy(s[1:], key)
+		copy(s[1+ix.keySize:], value)
+		return true
+	})
+	dst.setCounts(ix.used(), 0)
+
+	// The index file must be unmapped before being replaced, as Windows
+	// can not rename files over mapped ones.
+	err = ix.f.Close()
+	if err != nil {
+		_ = a.Close()
+		return fmt.Errorf("index: could not grow %q: %w", ix.name, err)
+	}
+	err = a.Commit()
+	if err != nil {
+		return ix.reopen(fmt.Errorf("index: could not grow %q: %w", ix.name, err))
+	}
+	return ix.reopen(nil)
+}
+
+// reopen maps the index file again after it was replaced, or not.
+// It returns cause, or the error reopening the file.
+func (ix *Index) reopen(cause error) error {
+	f, err := mmap.OpenFile(ix.name, mmap.Read|mmap.Write)
+	if err == nil {
+		err = ix.load(f.Bytes())
+		if err != nil {
+			_ = f.Close()
+		}
+	}
+	if err != nil {
+		ix.f, ix.hdr, ix.data = nil, nil, nil
+		if cause != nil {
+			return cause
+		}
+		return fmt.Errorf("index: could not reopen %q: %w", ix.name, err)
+	}
+	ix.f = f
+	return cause
+}
+
+// Sync commits the contents of the index to stable storage.
+func (ix *Index) Sync() error {
+	if ix.f == nil {
+		return ErrClosed
+	}
+	return ix.f.Sync()
+}
+
+// Close closes the index.
+// It does not sync it.
+func (ix *Index) Close() error {
+	if ix.f == nil {
+		return nil
+	}
+	f := ix.f
+	ix.f, ix.hdr, ix.data = nil, nil, nil
+	return f.Close()
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+)
+
+func key(i int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(i))
+	return k
+}
+
+func value(i int) []byte {
+	v := make([]byte, 4)
+	binary.LittleEndian.PutUint32(v, uint32(i*i))
+	return v
+}
+
+func TestIndex(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "index")
+	ix, err := Create(fname, 8, 4)
+	if err != nil {
+		t.Fatalf("could not create index: %+v", err)
+	}
+	defer ix.Close()
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		err = ix.Put(key(i), value(i))
+		if err != nil {
+			t.Fatalf("could not put key %d: %+v", i, err)
+		}
+	}
+	for i := 0; i < n; i += 2 {
+		ok, err := ix.Delete(key(i))
+		if err != nil || !ok {
+			t.Fatalf("could not delete key %d: %v, %+v", i, ok, err)
+		}
+	}
+	err = ix.Put(key(1), value(2))
+	if err != nil {
+		t.Fatalf("could not replace key: %+v", err)
+	}
+	if got, want := ix.Len(), n/2; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+
+	err = ix.Close()
+	if err != nil {
+		t.Fatalf("could not close index: %+v", err)
+	}
+	ix, err = Open(fname)
+	if err != nil {
+		t.Fatalf("could not reopen index: %+v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		v, ok := ix.Get(key(i))
+		switch {
+		case i%2 == 0 && ok:
+			t.Fatalf("deleted key %d found", i)
+		case i%2 == 0:
+		case !ok:
+			t.Fatalf("key %d not found", i)
+		case i == 1 && string(v) != string(value(2)):
+			t.Fatalf("invalid replaced value: %x", v)
+		case i != 1 && string(v) != string(value(i)):
+			t.Fatalf("invalid value of key %d: %x", i, v)
+		}
+	}
+
+	seen := 0
+	ix.Iterate(func(k, v []byte) bool {
+		if binary.BigEndian.Uint64(k)%2 == 0 {
+			t.Fatalf("deleted key %x iterated", k)
+		}
+		seen++
+		return true
+	})
+	if seen != n/2 {
+		t.Fatalf("invalid number of iterated keys: got=%d, want=%d", seen, n/2)
+	}
+
+	err = ix.Put([]byte("short"), value(0))
+	if err == nil {
+		t.Fatalf("expected an error putting a short key")
+	}
+}
+
+func TestIndexOpenInvalid(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "index")
+	_, err := Open(fname)
+	if err == nil {
+		t.Fatalf("expected an error opening a missing index")
+	}
+	_, err = Create(fname, 0, 4)
+	if err == nil {
+		t.Fatalf("expected an error creating an index of empty keys")
+	}
+}
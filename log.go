@@ -0,0 +1,36 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "time"
+
+// eventLogger records the lifecycle events of files: opening, closing,
+// syncing and remapping.
+type eventLogger interface {
+	logEvent(event, filename string, flag Flag, size int64, d time.Duration, err error)
+}
+
+// logEvent records an event of the file, started at beg, that left it
+// holding size bytes.
+func (f *File) logEvent(event string, size int64, beg time.Time, err error) {
+	if f.cfg.logger == nil {
+		return
+	}
+	f.cfg.logger.logEvent(event, f.fd.Name(), f.flag, size, time.Since(beg), err)
+}
+
+// mode returns a short description of the access granted by the flag.
+func (fl Flag) mode() string {
+	switch fl & (Read | Write) {
+	case Read:
+		return "r"
+	case Write:
+		return "w"
+	case Read | Write:
+		return "rw"
+	default:
+		return "-"
+	}
+}
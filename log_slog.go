@@ -0,0 +1,48 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+
+package mmap
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithLogger makes the file record its lifecycle events to l at debug
+// level: opening, closing, syncing and remapping.
+// Each record holds the name, size and access flags of the file, the
+// duration of the operation and its error, if any.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		if l == nil {
+			o.logger = nil
+			return
+		}
+		o.logger = slogLogger{l}
+	}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (l slogLogger) logEvent(event, filename string, flag Flag, size int64, d time.Duration, err error) {
+	ctx := context.Background()
+	if !l.l.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("file", filename),
+		slog.Int64("size", size),
+		slog.String("flag", flag.mode()),
+		slog.Duration("duration", d),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.l.LogAttrs(ctx, slog.LevelDebug, "mmap: "+event, attrs...)
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+
+package mmap
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "logged.bin")
+	err := os.WriteFile(fname, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	f, err := OpenFile(fname, Read|Write, WithLogger(l))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync file: %+v", err)
+	}
+	err = f.Remap()
+	if err != nil {
+		t.Fatalf("could not remap file: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	events := []string{"open", "sync", "remap", "close"}
+	if got, want := len(lines), len(events); got != want {
+		t.Fatalf("invalid number of records: got=%d, want=%d\n%s", got, want, buf.String())
+	}
+	for i, event := range events {
+		line := lines[i]
+		for _, want := range []string{
+			"level=DEBUG",
+			`msg="mmap: ` + event + `"`,
+			"size=4096",
+			"flag=rw",
+			"duration=",
+		} {
+			if !strings.Contains(line, want) {
+				t.Fatalf("record %d does not contain %q: %s", i, want, line)
+			}
+		}
+	}
+
+	buf.Reset()
+	l = slog.New(slog.NewTextHandler(&buf, nil))
+	f, err = OpenFile(fname, Read, WithLogger(l))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	_ = f.Close()
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected records above debug level: %s", buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMappedLimit is returned when opening a file would map more bytes than
+// allowed.
+var ErrMappedLimit = errors.New("mmap: mapped bytes limit exceeded")
+
+// Mapper opens files and tracks them until they are closed, so that
+// services holding many mappings can report on them, sync them and close
+// them at once, and bound the number of bytes they map.
+// Tracked files are not closed when they become unreachable, as the mapper
+// still references them: they must be closed, or closed with CloseAll.
+// A Mapper is safe for concurrent use.
+type Mapper struct {
+	mu       sync.Mutex
+	files    map[*File]int64 // sizes of the tracked files.
+	opts     []Option
+	maxBytes int64
+}
+
+// MapperStats describes the files tracked by a Mapper.
+type MapperStats struct {
+	Files       int   // Number of open files.
+	MappedBytes int64 // Total size of the open files.
+}
+
+// NewMapper returns a mapper opening files with the given options, before
+// the options of each call, and mapping at most maxBytes bytes in total.
+// If maxBytes is zero or negative, the mapped bytes are not limited.
+func NewMapper(maxBytes int64, opts ...Option) *Mapper {
+	return &Mapper{
+		files:    make(map[*File]int64),
+		opts:     opts,
+		maxBytes: maxBytes,
+	}
+}
+
+// Open memory-maps the named file for reading, and tracks it.
+func (m *Mapper) Open(filename string) (*File, error) {
+	return m.OpenFile(filename, Read)
+}
+
+// OpenFile memory-maps the named file for reading/writing, depending on the
+// flag value, and tracks it until it is closed.
+// It fails with an error wrapping ErrMappedLimit if the file would take the
+// tracked files over the limit of the mapper.
+//
+// The limit is checked against the sizes of the files when they are
+// opened, as last mapped: files growing afterwards may take the mapper
+// over it.
+func (m *Mapper) OpenFile(filename string, flag Flag, opts ...Option) (*File, error) {
+	all := append(append([]Option(nil), m.opts...), opts...)
+	f, err := OpenFile(filename, flag, all...)
+	if err != nil {
+		return nil, err
+	}
+
+	size := f.Size()
+	m.mu.Lock()
+	if m.maxBytes > 0 {
+		if used := m.mappedBytes(); used+size > m.maxBytes {
+			m.mu.Unlock()
+			_ = f.Close()
+			return nil, fmt.Errorf("mmap: could not map %q, %d bytes on top of %d: %w", filename, size, used, ErrMappedLimit)
+		}
+	}
+	m.files[f] = size
+	f.mapper = m
+	m.mu.Unlock()
+	return f, nil
+}
+
+// mappedBytes returns the total size of the tracked files.
+// mappedBytes must be called with m.mu held.
+func (m *Mapper) mappedBytes() int64 {
+	var n int64
+	for _, size := range m.files {
+		n += size
+	}
+	return n
+}
+
+// resized records the size of f, once it was mapped again.
+// The sizes are pushed by the goroutines using the files, as the mapper
+// can not read them concurrently.
+func (m *Mapper) resized(f *File, size int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if _, ok := m.files[f]; ok {
+		m.files[f] = size
+	}
+	m.mu.Unlock()
+}
+
+// forget stops tracking f, once closed.
+func (m *Mapper) forget(f *File) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	delete(m.files, f)
+	m.mu.Unlock()
+}
+
+// tracked returns the tracked files.
+func (m *Mapper) tracked() []*File {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make([]*File, 0, len(m.files))
+	for f := range m.files {
+		files = append(files, f)
+	}
+	return files
+}
+
+// Stats returns statistics on the files tracked by m.
+func (m *Mapper) Stats() MapperStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MapperStats{
+		Files:       len(m.files),
+		MappedBytes: m.mappedBytes(),
+	}
+}
+
+// SyncAll commits the tracked files opened for writing to stable storage.
+// All the files are synced, and the errors of those that failed are
+// reported.
+func (m *Mapper) SyncAll() error {
+	var errs []error
+	for _, f := range m.tracked() {
+		if f.wflag() {
+			errs = append(errs, f.Sync())
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// CloseAll closes the tracked files.
+// All the files are closed, and the errors of those that failed are
+// reported.
+func (m *Mapper) CloseAll() error {
+	var errs []error
+	for _, f := range m.tracked() {
+		errs = append(errs, f.Close())
+	}
+	return joinErrors(errs...)
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// MappingInfo describes a live mapping made by the package, as listed by
+// the OS, as returned by Mappings.
+type MappingInfo struct {
+	Addr   uintptr // Address of the mapping.
+	Len    int     // Length of the mapping.
+	Perm   string  // Permissions of the mapping, such as "rw-s" for a shared writable mapping.
+	Offset int64   // Offset of the mapping in the backing file.
+	Path   string  // Path of the backing file, empty for anonymous mappings.
+}
+
+// mappings is the registry of the live mappings made by the package, by
+// address, holding their length.
+var mappings struct {
+	mu     sync.Mutex
+	ranges map[uintptr]int
+}
+
+func trackMapping(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	mappings.mu.Lock()
+	if mappings.ranges == nil {
+		mappings.ranges = make(map[uintptr]int)
+	}
+	mappings.ranges[uintptr(unsafe.Pointer(&data[0]))] = len(data)
+	mappings.mu.Unlock()
+}
+
+func untrackMapping(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	mappings.mu.Lock()
+	delete(mappings.ranges, uintptr(unsafe.Pointer(&data[0])))
+	mappings.mu.Unlock()
+}
+
+// mappedRange is the range of addresses of a live mapping.
+type mappedRange struct {
+	addr uintptr
+	n    int
+}
+
+// mappedRanges returns the ranges of the live mappings, sorted by address.
+func mappedRanges() []mappedRange {
+	mappings.mu.Lock()
+	rs := make([]mappedRange, 0, len(mappings.ranges))
+	for addr, n := range mappings.ranges {
+		rs = append(rs, mappedRange{addr, n})
+	}
+	mappings.mu.Unlock()
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].addr < rs[j].addr
+	})
+	return rs
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Mappings returns the live mappings made by the package, in order of
+// address, as listed by /proc/self/maps, to hunt for leaked mappings or
+// account for the memory of the process.
+//
+// The kernel may split a mapping, once parts of it are advised or locked,
+// or merge it with adjacent ones: mappings are then reported as the parts
+// of the entries of /proc/self/maps they span.
+//
+// Mappings is only supported on Linux.
+func Mappings() ([]MappingInfo, error) {
+	ranges := mappedRanges()
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+
+	maps, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not open mappings: %w", err)
+	}
+	defer maps.Close()
+
+	var infos []MappingInfo
+	sc := bufio.NewScanner(maps)
+	for sc.Scan() {
+		e, ok := parseMapsEntry(sc.Text())
+		if !ok {
+			continue
+		}
+		lo, hi := e.Addr, e.Addr+uintptr(e.Len)
+		for _, r := range ranges {
+			beg, end := r.addr, r.addr+uintptr(r.n)
+			if end <= lo || beg >= hi {
+				continue
+			}
+			if beg < lo {
+				beg = lo
+			}
+			if end > hi {
+				end = hi
+			}
+			info := e
+			info.Addr, info.Len = beg, int(end-beg)
+			info.Offset += int64(beg - lo)
+			infos = append(infos, info)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("mmap: could not read mappings: %w", err)
+	}
+	return infos, nil
+}
+
+// parseMapsEntry parses a line of /proc/self/maps, such as:
+//
+//	7f2c4a000000-7f2c4a010000 rw-s 00000000 08:01 1234    /path/to/file
+func parseMapsEntry(line string) (MappingInfo, bool) {
+	var fields [5]string
+	for i := range fields {
+		line = strings.TrimLeft(line, " ")
+		j := strings.IndexByte(line, ' ')
+		if j < 0 {
+			if i < len(fields)-1 {
+				return MappingInfo{}, false
+			}
+			j = len(line)
+		}
+		fields[i], line = line[:j], line[j:]
+	}
+
+	addrs := strings.SplitN(fields[0], "-", 2)
+	if len(addrs) != 2 {
+		return MappingInfo{}, false
+	}
+	lo, err1 := strconv.ParseUint(addrs[0], 16, 64)
+	hi, err2 := strconv.ParseUint(addrs[1], 16, 64)
+	off, err3 := strconv.ParseInt(fields[2], 16, 64)
+	if err1 != nil || err2 != nil || err3 != nil || hi < lo {
+		return MappingInfo{}, false
+	}
+	return MappingInfo{
+		Addr:   uintptr(lo),
+		Len:    int(hi - lo),
+		Perm:   fields[1],
+		Offset: off,
+		Path:   strings.TrimLeft(line, " "),
+	}, true
+}
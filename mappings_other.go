@@ -0,0 +1,16 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package mmap
+
+// Mappings returns the live mappings made by the package, as listed by
+// /proc/self/maps.
+//
+// Mappings is only supported on Linux.
+func Mappings() ([]MappingInfo, error) {
+	return nil, errUnsupported
+}
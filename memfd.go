@@ -0,0 +1,17 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+// SealFlag is a set of seals restricting the changes that can be made to a
+// memfd-backed file, as created by CreateMemfd.
+type SealFlag int
+
+const (
+	SealSeal        SealFlag = 0x1  // SealSeal prevents adding seals.
+	SealShrink      SealFlag = 0x2  // SealShrink prevents shrinking the file.
+	SealGrow        SealFlag = 0x4  // SealGrow prevents growing the file.
+	SealWrite       SealFlag = 0x8  // SealWrite prevents writing to the file.
+	SealFutureWrite SealFlag = 0x10 // SealFutureWrite prevents new writable mappings and writes.
+)
@@ -0,0 +1,111 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"os"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// CreateMemfd creates an anonymous file of size bytes, living in memory,
+// and memory-maps it for reading and writing.
+// name is only used for debugging, and need not be unique.
+// The file supports sealing, with Seal.
+//
+// CreateMemfd is only supported on Linux.
+func CreateMemfd(name string, size int64, opts ...Option) (*File, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("mmap: invalid memfd size %d", size)
+	}
+	fd, err := syscall.MemfdCreate(name, syscall.MFD_CLOEXEC|syscall.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not create memfd %q: %w", name, err)
+	}
+	file := os.NewFile(uintptr(fd), "memfd:"+name)
+	err = file.Truncate(size)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("mmap: could not size memfd %q: %w", name, err)
+	}
+
+	f := &File{
+		fd:   file,
+		name: file.Name(),
+		flag: Read | Write,
+		cfg:  newOptions(opts),
+	}
+	f.cfg.noPath = true
+	err = f.mapFile()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	f.opened()
+	return f, nil
+}
+
+// Seal adds the given seals to a file created by CreateMemfd.
+// Sealing a file with SealWrite maps it again for reading only, as writable
+// mappings would prevent the seal.
+//
+// Seal is only supported on Linux.
+func (f *File) Seal(flags SealFlag) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if f.closed() {
+		return errClosed
+	}
+	if flags&SealWrite == 0 || !f.wflag() {
+		return f.addSeals(flags)
+	}
+
+	if f.shared() {
+		return errShared
+	}
+	if f.pinned() {
+		return errPinned
+	}
+	err := f.unmapFile()
+	if err != nil {
+		return err
+	}
+	err = f.addSeals(flags)
+	if err == nil {
+		f.flag = Read
+	}
+	if e := f.mapFile(); err == nil {
+		err = e
+	}
+	f.updated()
+	return err
+}
+
+func (f *File) addSeals(flags SealFlag) error {
+	_, err := syscall.FcntlInt(f.fd.Fd(), syscall.F_ADD_SEALS, int(flags))
+	if err != nil {
+		return fmt.Errorf("mmap: could not seal %q: %w", f.name, err)
+	}
+	return nil
+}
+
+// Seals returns the seals of a file created by CreateMemfd.
+//
+// Seals is only supported on Linux.
+func (f *File) Seals() (SealFlag, error) {
+	if f == nil {
+		return 0, os.ErrInvalid
+	}
+	if f.closed() {
+		return 0, errClosed
+	}
+	seals, err := syscall.FcntlInt(f.fd.Fd(), syscall.F_GET_SEALS, 0)
+	if err != nil {
+		return 0, fmt.Errorf("mmap: could not get seals of %q: %w", f.name, err)
+	}
+	return SealFlag(seals), nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package mmap
+
+import "os"
+
+// CreateMemfd creates an anonymous file of size bytes, living in memory,
+// and memory-maps it for reading and writing.
+// name is only used for debugging, and need not be unique.
+// The file supports sealing, with Seal.
+//
+// CreateMemfd is only supported on Linux.
+func CreateMemfd(name string, size int64, opts ...Option) (*File, error) {
+	return nil, errUnsupported
+}
+
+// Seal adds the given seals to a file created by CreateMemfd.
+// Sealing a file with SealWrite maps it again for reading only, as writable
+// mappings would prevent the seal.
+//
+// Seal is only supported on Linux.
+func (f *File) Seal(flags SealFlag) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	return errUnsupported
+}
+
+// Seals returns the seals of a file created by CreateMemfd.
+//
+// Seals is only supported on Linux.
+func (f *File) Seals() (SealFlag, error) {
+	if f == nil {
+		return 0, os.ErrInvalid
+	}
+	return 0, errUnsupported
+}
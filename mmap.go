@@ -9,10 +9,42 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var errBadFD = errors.New("bad file descriptor")
+var (
+	errBadFD    = errors.New("bad file descriptor")
+	errClosed   = errors.New("mmap: closed")
+	errLockMode = errors.New("mmap: invalid lock mode")
+
+	errUnsupported = errors.New("mmap: unsupported operation")
+)
+
+// pathError wraps err, the failure of the operation op on the named file,
+// in an *fs.PathError, so that errors.As and os.IsNotExist, os.IsPermission
+// and co. work with the errors of the package, as with the ones of os.
+// The *fs.PathError from os naming the file are unwrapped first.
+func pathError(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if pe, ok := err.(*fs.PathError); ok {
+		if strings.HasPrefix(pe.Op, "mmap.") {
+			return err
+		}
+		err = pe.Err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// ErrAddrNotAvailable is returned when a file can not be mapped at the
+// address requested with MapAt.
+var ErrAddrNotAvailable = errors.New("mmap: address not available")
 
 // Flag specifies how a mmap file should be opened.
 type Flag int
@@ -37,37 +69,429 @@ func (fl Flag) flag() int {
 	return flag
 }
 
+// Option configures how a file is opened and memory-mapped.
+//
+// Options are the way to tune OpenFile and the other functions opening
+// files: new settings are added as new options, rather than as new
+// functions or parameters.
+// Options are applied in order, so later options override earlier ones.
+type Option func(*options)
+
+type options struct {
+	advice     advice
+	largePages bool
+	window     int64
+	share      ShareMode
+	addr       uintptr
+	noReserve  bool
+	lock       LockMode
+	watch      bool
+	onChange   func(f *File)
+	private    bool
+	extend     bool
+	align      int
+	numa       bool
+	node       int
+	fork       forkMode
+	dirty      bool
+	populate   bool
+	size       int64
+	logger     eventLogger
+	progress   func(done, total int64) error
+	fallback   bool
+	remote     RemotePolicy
+	remoteWarn func(filename, fstype string)
+	direct     bool // read and write through the descriptor, set by checkRemote.
+	noPath     bool // the file has no path, set by CreateMemfd.
+	budgetWait bool
+	finalizer  FinalizerMode
+
+	create   bool
+	perm     fs.FileMode
+	excl     bool
+	trunc    bool
+	noFollow bool
+	inherit  bool
+	dir      *os.File
+	faults   func(call Syscall) error
+
+	syncOnClose bool
+	syncEvery   int64
+	noSync      bool
+	fullSync    bool
+	mapSync     bool
+	syncWorkers int
+	openWorkers int
+}
+
+func newOptions(opts []Option) options {
+	cfg := options{
+		share: ShareRead | ShareWrite,
+		perm:  0666,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// check reports whether the options are valid to open the named file with
+// the flag value.
+func (cfg options) check(filename string, flag Flag) error {
+	if cfg.trunc && flag&Write == 0 {
+		return fmt.Errorf("mmap: could not truncate %q opened with mode %q: %w", filename, flag.mode(), os.ErrInvalid)
+	}
+	return nil
+}
+
+// advice describes the expected access pattern of a mapping.
+type advice int
+
+const (
+	adviceNormal advice = iota
+	adviceSequential
+	adviceRandom
+)
+
+// WithSequential hints that the file will be accessed sequentially,
+// so the OS can read ahead aggressively and drop pages sooner.
+func WithSequential() Option {
+	return func(o *options) {
+		o.advice = adviceSequential
+	}
+}
+
+// WithRandom hints that the file will be accessed in random order,
+// so the OS can disable read-ahead.
+func WithRandom() Option {
+	return func(o *options) {
+		o.advice = adviceRandom
+	}
+}
+
+// WithLargePages requests the file to be mapped with large pages
+// (huge pages on Linux, superpages on FreeBSD.)
+// When the OS, the filesystem or the privileges of the process do not
+// allow it, the file is mapped with regular pages.
+// Windows never does: it only maps sections backed by the paging file with
+// large pages, and not the ones of files.
+func WithLargePages() Option {
+	return func(o *options) {
+		o.largePages = true
+	}
+}
+
+// ShareMode specifies which accesses other processes are granted to a file
+// while it is open.
+// It is only honored on Windows: unix systems always grant all of them.
+type ShareMode int
+
+const (
+	ShareRead   ShareMode = 0x1 // ShareRead lets others open the file for reading.
+	ShareWrite  ShareMode = 0x2 // ShareWrite lets others open the file for writing.
+	ShareDelete ShareMode = 0x4 // ShareDelete lets others rename or delete the file.
+)
+
+// WithShareMode sets the accesses other processes are granted to the file
+// while it is open.
+// The default is ShareRead|ShareWrite.
+func WithShareMode(mode ShareMode) Option {
+	return func(o *options) {
+		o.share = mode
+	}
+}
+
+// MapAt requests the file to be mapped at the address addr, which must be
+// a multiple of the allocation granularity of the platform.
+// Opening the file fails with ErrAddrNotAvailable if the address range is
+// already in use: existing mappings are never replaced.
+//
+// MapAt is meant for persistent data structures holding pointers into the
+// mapping itself, that need a stable address across runs.
+func MapAt(addr uintptr) Option {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithPopulate prefaults the pages of the mapping when the file is opened or
+// remapped, so that first accesses do not wait for the file to be read.
+// Opening the file then takes longer, and the whole file is read into
+// memory, which only suits files that fit comfortably in memory.
+// On Linux and FreeBSD, this is done when the file is mapped (MAP_POPULATE,
+// MAP_PREFAULT_READ); elsewhere, the pages are prefetched once mapped.
+// WithPopulate has no effect on files mapped through a sliding window.
+func WithPopulate() Option {
+	return func(o *options) {
+		o.populate = true
+	}
+}
+
+// WithSize maps the first n bytes of the file, rather than the size reported
+// by the file system.
+// It is meant for devices and special files that report no size; block
+// devices are otherwise sized with the OS-specific ioctl where supported.
+// For regular files, n must not exceed the size of the file.
+// Remap and Grow keep mapping n bytes.
+func WithSize(n int64) Option {
+	return func(o *options) {
+		o.size = n
+	}
+}
+
+// mapSize returns the number of bytes of the file described by fi to map.
+func (f *File) mapSize(fi os.FileInfo) (int64, error) {
+	filename := f.fd.Name()
+	switch {
+	case f.cfg.size < 0:
+		return 0, fmt.Errorf("mmap: invalid size %d for %q", f.cfg.size, filename)
+	case f.cfg.size > 0 && fi.Mode().IsRegular() && f.cfg.size > fi.Size():
+		return 0, fmt.Errorf("mmap: file %q is smaller than the requested size %d", filename, f.cfg.size)
+	case f.cfg.size > 0:
+		return f.cfg.size, nil
+	case fi.Size() == 0 && fi.Mode()&fs.ModeDevice != 0 && fi.Mode()&fs.ModeCharDevice == 0:
+		size, err := deviceSize(f.fd)
+		if err != nil {
+			return 0, fmt.Errorf("mmap: could not get size of device %q: %w", filename, err)
+		}
+		return size, nil
+	}
+	return fi.Size(), nil
+}
+
+// WithNoReserve requests the OS not to reserve memory or swap space for the
+// mapping up front, so that huge sparse mappings do not count against the
+// overcommit limits until their pages are actually touched.
+//
+// It only makes a difference on Linux, for mappings the kernel accounts
+// for, such as the ones of files on hugetlbfs: regular file-backed mappings
+// are never charged, and other platforms do not reserve them either.
+func WithNoReserve() Option {
+	return func(o *options) {
+		o.noReserve = true
+	}
+}
+
+// LockMode specifies the kind of advisory lock taken on a file.
+type LockMode int
+
+const (
+	Shared    LockMode = 0x1 // Shared locks may be held by several processes at once.
+	Exclusive LockMode = 0x2 // Exclusive locks may be held by one process at a time.
+)
+
+// WithLock locks the file with the given mode when it is opened, waiting
+// for other processes to release conflicting locks.
+func WithLock(mode LockMode) Option {
+	return func(o *options) {
+		o.lock = mode
+	}
+}
+
+// WithAutoExtend makes writes past the end of the file extend it, as they
+// would with an os.File, instead of failing with ErrNoSpace.
+// The file is grown with zeros up to the end of the write, then mapped
+// again: slices previously obtained from the mapping must not be used
+// after such a write.
+func WithAutoExtend() Option {
+	return func(o *options) {
+		o.extend = true
+	}
+}
+
+// WithNUMANode binds the pages of the mapping to the memory of the given
+// NUMA node, so that they are local to the threads processing them.
+//
+// It is only honored on Linux, for the pages that the kernel allocates on
+// behalf of the mapping: private mappings, and files on tmpfs or hugetlbfs.
+// Pages of regular files are shared through the page cache, and are
+// allocated on the node of the thread first reading them.
+func WithNUMANode(node int) Option {
+	return func(o *options) {
+		o.numa = true
+		o.node = node
+	}
+}
+
+// forkMode describes what child processes inherit of a mapping.
+type forkMode int
+
+const (
+	forkShare forkMode = iota // children share the mapping.
+	forkNone                  // children do not inherit the mapping.
+	forkWipe                  // children inherit a zeroed mapping.
+)
+
+// WithDontFork prevents child processes created with fork from inheriting
+// the mapping, so that they neither hold huge mappings nor access their
+// contents.
+//
+// It is only honored on Linux: Windows never maps files in child
+// processes, and other unix systems do not support it.
+func WithDontFork() Option {
+	return func(o *options) {
+		o.fork = forkNone
+	}
+}
+
+// WithWipeOnFork makes child processes created with fork observe the
+// mapping as zeroed, so that they never inherit the material it holds.
+// When the OS can not wipe a mapping, as with Linux for mappings backed by
+// files, children do not inherit it at all, as with WithDontFork.
+//
+// It is only honored on Linux: Windows never maps files in child
+// processes, and other unix systems do not support it.
+func WithWipeOnFork() Option {
+	return func(o *options) {
+		o.fork = forkWipe
+	}
+}
+
 // File reads/writes a memory-mapped file.
+//
+// Files opened empty are mapped on the first read or write following their
+// growth, as size changes are otherwise only picked up by Remap.
+// On Linux, files of pseudo filesystems such as procfs, which report no
+// size, are read once and their contents mapped read-only instead.
 type File struct {
-	data []byte
-	c    int
+	data  []byte
+	c     int64
+	saved []int64 // positions of the cursor saved by PushPos.
+	w     *window // w is non-nil for files mapped through a sliding window.
 
 	fd   *os.File
+	name string // name the file was opened with.
 	flag Flag
 	fi   os.FileInfo
+	cfg  options
+
+	watcher  *watcher
+	mapper   *Mapper        // mapper tracking the file, if opened by one.
+	reg      *openEntry     // entry of the file in the registry, if any.
+	stack    []uintptr      // callers opening the file, under FinalizerPanic.
+	pins     []func() error // hooks unregistering the buffers of the mapping.
+	origin   *options       // options of the file a snapshot was taken of.
+	isClosed bool           // set once the file is closed.
+	refs     *atomic.Int32  // number of open handles sharing the mapping.
+	dirty    atomic.Int64   // bytes written since the last sync, for SyncEveryNBytes.
+	resync   bool           // set when a sync failed after resetting the tracking of dirty pages.
+
+	fast    bool        // set once a read checked that reads only need bounds checks.
+	empty   atomic.Bool // set while the file is mapped empty.
+	emptyMu sync.Mutex  // serializes the mapping of empty files that grew.
 }
 
 // Open memory-maps the named file for reading.
 func Open(filename string) (*File, error) {
-	return openFile(filename, Read)
+	return openLogged(filename, Read, newOptions(nil))
 }
 
 // OpenFile memory-maps the named file for reading/writing, depending on
 // the flag value.
-func OpenFile(filename string, flag Flag) (*File, error) {
-	return openFile(filename, flag)
+// Options may be provided to further tune how the file is opened and mapped.
+func OpenFile(filename string, flag Flag, opts ...Option) (*File, error) {
+	return openLogged(filename, flag, newOptions(opts))
+}
+
+// openLogged opens the named file, and records the event to the logger, if any.
+func openLogged(filename string, flag Flag, cfg options) (*File, error) {
+	beg := time.Now()
+	var f *File
+	err := cfg.check(filename, flag)
+	if err == nil {
+		f, err = openFile(filename, flag, cfg)
+	}
+	if cfg.logger != nil {
+		var size int64
+		if f != nil {
+			size = f.size()
+		}
+		cfg.logger.logEvent("open", filename, flag, size, time.Since(beg), err)
+	}
+	return f, err
 }
 
 // Len returns the length of the underlying memory-mapped file.
+// Use Size for files whose length may not fit in an int.
 func (f *File) Len() int {
-	return len(f.data)
+	return int(f.size())
+}
+
+// Size returns the length of the underlying memory-mapped file.
+func (f *File) Size() int64 {
+	return f.size()
+}
+
+func (f *File) size() int64 {
+	if f.w != nil {
+		return f.w.size
+	}
+	return int64(len(f.data))
 }
 
 // At returns the byte at index i.
+// At panics if i is out of range, and if the file is closed: use AtOK
+// where invalid indices are expected.
 func (f *File) At(i int) byte {
+	if err := f.refresh(); err != nil {
+		panic(err)
+	}
+	if f.w != nil {
+		var b [1]byte
+		if int64(i) < 0 || f.w.size <= int64(i) {
+			panic("index out of range")
+		}
+		if _, err := f.readAt(b[:], int64(i)); err != nil {
+			panic(err)
+		}
+		return b[0]
+	}
 	return f.data[i]
 }
 
+// AtOK returns the byte at index i, and whether i is a valid index of the
+// open file.
+func (f *File) AtOK(i int) (byte, bool) {
+	if f == nil || f.refresh() != nil {
+		return 0, false
+	}
+	if f.closed() || int64(i) < 0 || f.size() <= int64(i) {
+		return 0, false
+	}
+	if f.w != nil {
+		var b [1]byte
+		if _, err := f.readAt(b[:], int64(i)); err != nil {
+			return 0, false
+		}
+		return b[0], true
+	}
+	return f.data[i], true
+}
+
+// Range returns the bytes from index i to index j, excluded.
+// The returned slice aliases the mapping, unless the file is mapped through
+// a sliding window: it is then a copy of the bytes.
+func (f *File) Range(i, j int) ([]byte, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	if i < 0 || j < i || f.size() < int64(j) {
+		return nil, fmt.Errorf("mmap: invalid range [%d, %d)", i, j)
+	}
+	if f.w != nil {
+		p := make([]byte, j-i)
+		_, err := f.readAt(p, int64(i))
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return f.data[i:j:j], nil
+}
+
 // Stat returns the FileInfo structure describing file.
 // If there is an error, it will be of type *os.PathError.
 func (f *File) Stat() (os.FileInfo, error) {
@@ -78,6 +502,217 @@ func (f *File) Stat() (os.FileInfo, error) {
 	return f.fi, nil
 }
 
+// StatLive returns the FileInfo structure describing file as it is now,
+// rather than as it was when it was last mapped, as Stat does.
+// If there is an error, it will be of type *os.PathError.
+func (f *File) StatLive() (os.FileInfo, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	return f.fd.Stat()
+}
+
+// Changed reports whether the file was modified since it was last mapped:
+// its size or modification time changed, or its path now names another
+// file, or none, after it was replaced or removed.
+//
+// Writes through the mapping may update the modification time of the file,
+// so Changed is meant for files mapped for reading, to detect modifications
+// by other processes and call Remap or open the path again.
+func (f *File) Changed() (bool, error) {
+	fi, err := f.StatLive()
+	if err != nil {
+		return false, err
+	}
+	if fi.Size() != f.fi.Size() || !fi.ModTime().Equal(f.fi.ModTime()) {
+		return true, nil
+	}
+
+	if f.cfg.noPath {
+		return false, nil
+	}
+	cur, err := os.Stat(f.fd.Name())
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return !os.SameFile(cur, fi), nil
+}
+
+// Lock locks the file with the given mode, waiting for other processes to
+// release conflicting locks.
+// The lock is held until Unlock is called or the file is closed.
+//
+// On unix, locks are advisory: they only coordinate processes that lock
+// the file.
+// On Windows, locks span the whole file and prevent other processes from
+// reading or writing it through regular I/O.
+func (f *File) Lock(mode LockMode) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	_, err := lockFile(f.fd, mode, true)
+	return err
+}
+
+// TryLock tries to lock the file with the given mode, without waiting.
+// It reports whether the lock was acquired.
+func (f *File) TryLock(mode LockMode) (bool, error) {
+	if f == nil {
+		return false, os.ErrInvalid
+	}
+	return lockFile(f.fd, mode, false)
+}
+
+// Unlock releases the lock held on the file.
+func (f *File) Unlock() error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	return unlockFile(f.fd)
+}
+
+// Remap maps the file again, so that the mapping reflects the current size
+// of the file.
+// The position of the cursor is preserved.
+func (f *File) Remap() error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if f.shared() {
+		return errShared
+	}
+	if f.pinned() {
+		return errPinned
+	}
+	stats.remaps.Add(1)
+	beg := time.Now()
+	err := f.unmapFile()
+	if err == nil {
+		err = f.mapFile()
+	}
+	f.updated()
+	f.logEvent("remap", f.size(), beg, err)
+	return err
+}
+
+// snapshot returns the options used to map a snapshot of the file.
+func (cfg options) snapshot() options {
+	return options{
+		advice:     cfg.advice,
+		largePages: cfg.largePages,
+		noReserve:  cfg.noReserve,
+		private:    true,
+	}
+}
+
+// grow extends the file so that n bytes can be written at off, if the file
+// was opened with WithAutoExtend, and maps it again.
+func (f *File) grow(off, n int64) error {
+	if !f.cfg.extend || n == 0 || off+n <= f.size() {
+		return nil
+	}
+	if f.closed() {
+		return errClosed
+	}
+	if f.shared() {
+		return errShared
+	}
+	if f.pinned() {
+		return errPinned
+	}
+	err := f.unmapFile()
+	if err != nil {
+		return err
+	}
+	err = f.fd.Truncate(off + n)
+	if err != nil {
+		_ = f.mapFile()
+		return fmt.Errorf("mmap: could not extend %q: %w", f.fd.Name(), err)
+	}
+	err = f.mapFile()
+	f.updated()
+	return err
+}
+
+// refreshEmpty maps the file if it was mapped empty and grew since, so that
+// files opened before being written to become readable.
+// Clones of an empty file are never mapped this way.
+func (f *File) refreshEmpty() error {
+	f.emptyMu.Lock()
+	defer f.emptyMu.Unlock()
+	if !f.empty.Load() || f.closed() || f.shared() {
+		return nil
+	}
+	fi, err := f.fd.Stat()
+	if err != nil {
+		return fmt.Errorf("mmap: could not stat %q: %w", f.fd.Name(), err)
+	}
+	if fi.Size() == 0 {
+		return nil
+	}
+	err = f.mapFile()
+	f.updated()
+	return err
+}
+
+func (f *File) closed() bool {
+	return f.isClosed
+}
+
+// PrefetchAsync is like Prefetch, but prefetches the range from a new
+// goroutine and returns at once, so that sequential readers never wait for
+// the read requests to be issued.
+// The returned channel receives the error of Prefetch, and is then closed.
+// The file must not be closed before the prefetch completes.
+func (f *File) PrefetchAsync(off, n int64) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		done <- f.Prefetch(off, n)
+	}()
+	return done
+}
+
+// region returns the page-aligned part of the mapping covering [off, off+n).
+// Files mapped through a sliding window have no such part: region then
+// returns a nil slice once the range has been validated.
+func (f *File) region(off, n int64) ([]byte, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	if size := f.size(); off < 0 || n < 0 || size < off || size-off < n {
+		return nil, fmt.Errorf("mmap: invalid range [%d, %d)", off, off+n)
+	}
+	if f.w != nil {
+		return nil, nil
+	}
+	beg := off &^ int64(os.Getpagesize()-1)
+	return f.data[beg : off+n], nil
+}
+
+// lockRegion returns the part of the mapping to lock in memory to cover
+// [off, off+n).
+// Files mapped through a sliding window can not be locked.
+func (f *File) lockRegion(off, n int64) ([]byte, error) {
+	b, err := f.region(off, n)
+	if err != nil {
+		return nil, err
+	}
+	if f.w != nil {
+		return nil, errUnsupported
+	}
+	return b, nil
+}
+
 func (f *File) rflag() bool {
 	return f.flag&Read != 0
 }
@@ -86,117 +721,307 @@ func (f *File) wflag() bool {
 	return f.flag&Write != 0
 }
 
+// readAt copies the mapped bytes starting at off into p.
+func (f *File) readAt(p []byte, off int64) (int, error) {
+	if f.w != nil {
+		return f.w.readAt(f, p, off)
+	}
+	return copy(p, f.data[off:]), nil
+}
+
+// writeAt copies p into the mapped bytes starting at off.
+func (f *File) writeAt(p []byte, off int64) (int, error) {
+	if f.w != nil {
+		return f.w.writeAt(f, p, off)
+	}
+	return copy(f.data[off:], p), nil
+}
+
 // Read implements the io.Reader interface.
 func (f *File) Read(p []byte) (int, error) {
+	if f != nil && f.fast && f.c < int64(len(f.data)) {
+		n := copy(p, f.data[f.c:])
+		f.c += int64(n)
+		return n, nil
+	}
 	if f == nil {
 		return 0, os.ErrInvalid
 	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
 
 	if !f.rflag() {
 		return 0, errBadFD
 	}
-	if f.c >= len(f.data) {
+	if f.c >= f.size() {
 		return 0, io.EOF
 	}
-	n := copy(p, f.data[f.c:])
-	f.c += n
-	return n, nil
+	n, err := f.readAt(p, f.c)
+	f.c += int64(n)
+	f.checked()
+	return n, err
 }
 
 // ReadByte implements the io.ByteReader interface.
 func (f *File) ReadByte() (byte, error) {
+	if f != nil && f.fast && f.c < int64(len(f.data)) {
+		v := f.data[f.c]
+		f.c++
+		return v, nil
+	}
 	if f == nil {
 		return 0, os.ErrInvalid
 	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
 
 	if !f.rflag() {
 		return 0, errBadFD
 	}
-	if f.c >= len(f.data) {
+	if f.c >= f.size() {
 		return 0, io.EOF
 	}
+	if f.w != nil {
+		var b [1]byte
+		_, err := f.readAt(b[:], f.c)
+		if err != nil {
+			return 0, err
+		}
+		f.c++
+		return b[0], nil
+	}
 	v := f.data[f.c]
 	f.c++
+	f.checked()
 	return v, nil
 }
 
+// checked records that the file passed the checks of a read, so that the
+// next reads from the cursor only check its bounds, as long as the file is
+// mapped at once.
+//
+// The flag of a file never changes, and its mapping only does when it is
+// remapped or closed, which updates the bounds: only the files refreshed by
+// a watcher, or mapped through a window, need checking before each read.
+func (f *File) checked() {
+	f.fast = f.w == nil && f.watcher == nil
+}
+
 // ReadAt implements the io.ReaderAt interface.
+// As with os.File, reading up to the end of the file exactly returns a nil
+// error, while reading past it returns the available bytes and io.EOF.
 func (f *File) ReadAt(p []byte, off int64) (int, error) {
 	if f == nil {
 		return 0, os.ErrInvalid
 	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
 
 	if !f.rflag() {
 		return 0, errBadFD
 	}
-	if f.data == nil {
-		return 0, errors.New("mmap: closed")
+	if f.closed() {
+		return 0, errClosed
 	}
-	if off < 0 || int64(len(f.data)) < off {
+	if off < 0 {
 		return 0, fmt.Errorf("mmap: invalid ReadAt offset %d", off)
 	}
-	n := copy(p, f.data[off:])
+	if f.size() <= off {
+		return 0, io.EOF
+	}
+	n, err := f.readAt(p, off)
+	if err != nil {
+		return n, err
+	}
 	if n < len(p) {
 		return n, io.EOF
 	}
 	return n, nil
 }
 
+// Peek returns the next n bytes without advancing the cursor.
+// If fewer than n bytes remain, Peek returns them along with io.EOF.
+// The returned slice aliases the mapping, unless the file is mapped through
+// a sliding window: it is then a copy of the bytes.
+func (f *File) Peek(n int) ([]byte, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if err := f.refresh(); err != nil {
+		return nil, err
+	}
+
+	if !f.rflag() {
+		return nil, errBadFD
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("mmap: invalid Peek count %d", n)
+	}
+	if f.c >= f.size() {
+		return nil, io.EOF
+	}
+	var err error
+	if rem := f.size() - f.c; int64(n) > rem {
+		n = int(rem)
+		err = io.EOF
+	}
+	if f.w != nil {
+		p := make([]byte, n)
+		m, rerr := f.readAt(p, f.c)
+		if rerr != nil {
+			return p[:m], rerr
+		}
+		return p, err
+	}
+	return f.data[f.c : f.c+int64(n) : f.c+int64(n)], err
+}
+
+// Discard skips the next n bytes, returning the number of bytes discarded.
+// If fewer than n bytes remain, Discard skips them and returns io.EOF.
+func (f *File) Discard(n int) (int, error) {
+	if f == nil {
+		return 0, os.ErrInvalid
+	}
+	if !f.rflag() {
+		return 0, errBadFD
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("mmap: invalid Discard count %d", n)
+	}
+	var err error
+	if rem := f.size() - f.c; int64(n) > rem {
+		n = int(rem)
+		if n < 0 {
+			n = 0
+		}
+		err = io.EOF
+	}
+	f.c += int64(n)
+	return n, err
+}
+
+// ErrNoSpace is returned by writes reaching the end of a file not opened
+// with WithAutoExtend, as the size of a mapping is fixed.
+// It wraps io.ErrShortWrite.
+var ErrNoSpace = fmt.Errorf("mmap: no space left in file: %w", io.ErrShortWrite)
+
+// Remaining returns the number of bytes between the cursor and the end of
+// the file, that Write can write before failing with ErrNoSpace, unless
+// the file was opened with WithAutoExtend.
+func (f *File) Remaining() int64 {
+	if f == nil || f.c >= f.size() {
+		return 0
+	}
+	return f.size() - f.c
+}
+
 // Write implements the io.Writer interface.
+// If p does not fit before the end of the file, Write writes the bytes
+// that fit, advances the cursor past them, and returns their number along
+// with ErrNoSpace, so that the caller knows which bytes were written.
 func (f *File) Write(p []byte) (int, error) {
 	if f == nil {
 		return 0, os.ErrInvalid
 	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
 
 	if !f.wflag() {
 		return 0, errBadFD
 	}
-	if f.c >= len(f.data) {
-		return 0, io.ErrShortWrite
+	if err := f.grow(f.c, int64(len(p))); err != nil {
+		return 0, err
+	}
+	if f.c >= f.size() {
+		return 0, ErrNoSpace
+	}
+	n, err := f.writeAt(p, f.c)
+	f.c += int64(n)
+	if err != nil {
+		return n, err
+	}
+	err = f.wrote(int64(n))
+	if err != nil {
+		return n, err
 	}
-	n := copy(f.data[f.c:], p)
-	f.c += n
 	if len(p) > n {
-		return n, io.ErrShortWrite
+		return n, ErrNoSpace
 	}
 	return n, nil
 }
 
 // WriteByte implements the io.ByteWriter interface.
+// It returns ErrNoSpace at the end of the file.
 func (f *File) WriteByte(c byte) error {
 	if f == nil {
 		return os.ErrInvalid
 	}
+	if err := f.refresh(); err != nil {
+		return err
+	}
 
 	if !f.wflag() {
 		return errBadFD
 	}
-	if f.c >= len(f.data) {
-		return io.ErrShortWrite
+	if err := f.grow(f.c, 1); err != nil {
+		return err
+	}
+	if f.c >= f.size() {
+		return ErrNoSpace
+	}
+	if f.w != nil {
+		_, err := f.writeAt([]byte{c}, f.c)
+		if err != nil {
+			return err
+		}
+		f.c++
+		return f.wrote(1)
 	}
 	f.data[f.c] = c
 	f.c++
-	return nil
+	return f.wrote(1)
 }
 
 // WriteAt implements the io.WriterAt interface.
+// If p does not fit before the end of the file, WriteAt writes the bytes
+// that fit, and returns their number along with ErrNoSpace.
 func (f *File) WriteAt(p []byte, off int64) (int, error) {
 	if f == nil {
 		return 0, os.ErrInvalid
 	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
 
 	if !f.wflag() {
 		return 0, errBadFD
 	}
-	if f.data == nil {
-		return 0, errors.New("mmap: closed")
+	if off < 0 {
+		return 0, fmt.Errorf("mmap: invalid WriteAt offset %d", off)
+	}
+	if err := f.grow(off, int64(len(p))); err != nil {
+		return 0, err
+	}
+	if f.closed() {
+		return 0, errClosed
 	}
-	if off < 0 || int64(len(f.data)) < off {
+	if f.size() < off {
 		return 0, fmt.Errorf("mmap: invalid WriteAt offset %d", off)
 	}
-	n := copy(f.data[off:], p)
+	n, err := f.writeAt(p, off)
+	if err != nil {
+		return n, err
+	}
+	err = f.wrote(int64(n))
+	if err != nil {
+		return n, err
+	}
 	if n < len(p) {
-		return n, io.ErrShortWrite
+		return n, ErrNoSpace
 	}
 	return n, nil
 }
@@ -206,20 +1031,55 @@ func (f *File) Seek(offset int64, whence int) (int64, error) {
 		return 0, os.ErrInvalid
 	}
 
+	var c int64
 	switch whence {
 	case io.SeekStart:
-		f.c = int(offset)
+		c = offset
 	case io.SeekCurrent:
-		f.c += int(offset)
+		c = f.c + offset
 	case io.SeekEnd:
-		f.c = len(f.data) - int(offset)
+		c = f.size() - offset
 	default:
 		return 0, fmt.Errorf("mmap: invalid whence")
 	}
-	if f.c < 0 {
+	if c < 0 {
 		return 0, fmt.Errorf("mmap: negative position")
 	}
-	return int64(f.c), nil
+	f.c = c
+	return f.c, nil
+}
+
+// Pos returns the position of the cursor.
+func (f *File) Pos() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.c
+}
+
+// PushPos saves the position of the cursor on a stack, for PopPos to
+// restore it, so that parsers can read ahead speculatively and rewind.
+func (f *File) PushPos() {
+	if f == nil {
+		return
+	}
+	f.saved = append(f.saved, f.c)
+}
+
+// PopPos restores the position of the cursor saved by the last call to
+// PushPos, whose position it removes from the stack.
+// PopPos fails if no position was saved.
+func (f *File) PopPos() error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	n := len(f.saved)
+	if n == 0 {
+		return fmt.Errorf("mmap: no saved position")
+	}
+	f.c = f.saved[n-1]
+	f.saved = f.saved[:n-1]
+	return nil
 }
 
 var (
@@ -0,0 +1,154 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"os"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// mapLargePages is the mmap flag requesting a mapping backed by large pages.
+// Darwin has no such flag for file-backed mappings.
+const mapLargePages = 0
+
+// mapPopulate is the mmap flag prefaulting the pages of a mapping.
+// Darwin has no such flag: the pages are prefetched once mapped instead.
+const mapPopulate = 0
+
+// Darwin can not map at a given address without replacing existing
+// mappings: the address is only given as a hint, and the resulting mapping
+// checked against it.
+const (
+	mapFixed     = 0
+	errAddrInUse = syscall.Errno(0)
+)
+
+const (
+	// madvHugePage and madvNoHugePage are the madvise advices enabling or
+	// disabling transparent huge pages.
+	// Darwin has no transparent huge pages.
+	madvHugePage   = 0
+	madvNoHugePage = 0
+)
+
+const (
+	// madvDontFork and madvWipeOnFork are the madvise advices preventing
+	// child processes from inheriting a mapping, or making them inherit it
+	// zeroed.
+	// Darwin does not support them.
+	madvDontFork   = 0
+	madvWipeOnFork = 0
+)
+
+// madvDontDump is the madvise advice excluding a mapping from core dumps.
+// Darwin does not support it.
+const madvDontDump = 0
+
+// mapNoReserve is the mmap flag requesting no swap space to be reserved
+// for the mapping.
+// Darwin ignores MAP_NORESERVE.
+const mapNoReserve = 0
+
+// mapSync are the mmap flags requesting a shared mapping whose metadata is
+// kept in sync with the file. Darwin has no such flags.
+const mapSync = 0
+
+// cloneFile creates a file at path sharing the data blocks of src.
+// It reports whether the file was created.
+func cloneFile(src *os.File, path string, perm os.FileMode) (bool, error) {
+	err := syscall.Fclonefileat(int(src.Fd()), syscall.AT_FDCWD, path, 0)
+	switch err {
+	case nil:
+		return true, nil
+	case syscall.ENOTSUP, syscall.EXDEV:
+		return false, nil
+	}
+	return false, err
+}
+
+// punchHole deallocates the [off, off+n) range of fd, which then reads back
+// as zeros.
+func punchHole(fd *os.File, off, n int64) error {
+	return errUnsupported
+}
+
+// fileCopyOffload reports whether copies between files are offloaded to
+// the kernel, or to the filesystem, by (*os.File).ReadFrom. On Darwin, it
+// copies them through a buffer.
+const fileCopyOffload = false
+
+// fsyncFull reports whether fsync commits the buffers of descriptors to
+// the storage device itself. On Darwin, it only hands them to the drive,
+// which may keep them in its cache, and reorder their writes.
+const fsyncFull = false
+
+// fullFsync commits the buffers of fd to the storage device, and asks the
+// drive to flush its cache, with F_FULLFSYNC.
+// Filesystems not supporting it, such as network ones, fall back to fsync.
+func fullFsync(fd *os.File) error {
+	_, err := syscall.FcntlInt(fd.Fd(), syscall.F_FULLFSYNC, 0)
+	switch err {
+	case nil:
+		return nil
+	case syscall.ENOTSUP, syscall.ENOTTY, syscall.EINVAL:
+		return fd.Sync()
+	}
+	return err
+}
+
+// bindNode binds the memory of data to the NUMA node.
+// NUMA bindings are only supported on Linux.
+func bindNode(data []byte, node int) error {
+	return nil
+}
+
+// mlockOnFault locks the pages of b in memory as they are accessed.
+// Darwin only locks pages up front.
+func mlockOnFault(b []byte) error {
+	return errUnsupported
+}
+
+const (
+	dkiocGetBlockSize  = 0x40046418 // DKIOCGETBLOCKSIZE
+	dkiocGetBlockCount = 0x40086419 // DKIOCGETBLOCKCOUNT
+)
+
+// deviceSize returns the size of the disk device fd.
+func deviceSize(fd *os.File) (int64, error) {
+	// The block size is a 32-bit integer, read into the low half of an
+	// int on the little-endian, 64-bit platforms Darwin runs on.
+	bsize, err := syscall.IoctlGetInt(int(fd.Fd()), dkiocGetBlockSize)
+	if err != nil {
+		return 0, err
+	}
+	count, err := syscall.IoctlGetInt(int(fd.Fd()), dkiocGetBlockCount)
+	if err != nil {
+		return 0, err
+	}
+	return int64(bsize) * int64(count), nil
+}
+
+// pseudoFile reports whether fd lives on a pseudo filesystem, such as procfs
+// or sysfs, whose files report no size but still have contents.
+// Such files are only read through on Linux.
+func pseudoFile(fd *os.File) bool {
+	return false
+}
+
+// remoteFS returns the type of the network or FUSE filesystem fd lives on,
+// or "" if it is local.
+func remoteFS(fd *os.File) string {
+	var st syscall.Statfs_t
+	if err := syscall.Fstatfs(int(fd.Fd()), &st); err != nil {
+		return ""
+	}
+	fstype := syscall.ByteSliceToString(st.Fstypename[:])
+	switch fstype {
+	case "nfs", "smbfs", "afpfs", "webdav", "ftp", "macfuse", "osxfuse":
+		return fstype
+	}
+	return ""
+}
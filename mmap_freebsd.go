@@ -0,0 +1,129 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"os"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// mapLargePages is the mmap flag requesting a mapping backed by large pages.
+// On FreeBSD, aligning the mapping lets the kernel promote it to superpages.
+const mapLargePages = syscall.MAP_ALIGNED_SUPER
+
+// mapPopulate is the mmap flag prefaulting the pages of a mapping.
+const mapPopulate = syscall.MAP_PREFAULT_READ
+
+const (
+	// mapFixed is the mmap flag requesting a mapping at a given address,
+	// without replacing existing mappings.
+	mapFixed = syscall.MAP_FIXED | syscall.MAP_EXCL
+
+	// errAddrInUse is the error returned by mmap when the requested address
+	// range is already in use.
+	errAddrInUse = syscall.EINVAL
+)
+
+const (
+	// madvHugePage and madvNoHugePage are the madvise advices enabling or
+	// disabling transparent huge pages.
+	// FreeBSD promotes mappings to superpages on its own.
+	madvHugePage   = 0
+	madvNoHugePage = 0
+)
+
+const (
+	// madvDontFork and madvWipeOnFork are the madvise advices preventing
+	// child processes from inheriting a mapping, or making them inherit it
+	// zeroed.
+	// FreeBSD controls inheritance with minherit instead.
+	madvDontFork   = 0
+	madvWipeOnFork = 0
+)
+
+// madvDontDump is the madvise advice excluding a mapping from core dumps.
+const madvDontDump = syscall.MADV_NOCORE
+
+// mapNoReserve is the mmap flag requesting no swap space to be reserved
+// for the mapping.
+// FreeBSD ignores MAP_NORESERVE.
+const mapNoReserve = 0
+
+// mapSync are the mmap flags requesting a shared mapping whose metadata is
+// kept in sync with the file. FreeBSD has no such flags.
+const mapSync = 0
+
+// cloneFile creates a file at path sharing the data blocks of src.
+// FreeBSD filesystems do not support cloning.
+func cloneFile(src *os.File, path string, perm os.FileMode) (bool, error) {
+	return false, nil
+}
+
+// punchHole deallocates the [off, off+n) range of fd, which then reads back
+// as zeros.
+func punchHole(fd *os.File, off, n int64) error {
+	return errUnsupported
+}
+
+// fileCopyOffload reports whether copies between files are offloaded to
+// the kernel, or to the filesystem, by (*os.File).ReadFrom. On FreeBSD, it
+// copies them through a buffer.
+const fileCopyOffload = false
+
+// fsyncFull reports whether fsync commits the buffers of descriptors to
+// the storage device itself.
+const fsyncFull = true
+
+// fullFsync commits the buffers of fd to the storage device. On FreeBSD,
+// fsync does.
+func fullFsync(fd *os.File) error {
+	return fd.Sync()
+}
+
+// bindNode binds the memory of data to the NUMA node.
+// NUMA bindings are only supported on Linux.
+func bindNode(data []byte, node int) error {
+	return nil
+}
+
+// mlockOnFault locks the pages of b in memory as they are accessed.
+// FreeBSD only locks pages up front.
+func mlockOnFault(b []byte) error {
+	return errUnsupported
+}
+
+// deviceSize returns the size of the disk device fd.
+func deviceSize(fd *os.File) (int64, error) {
+	var size int64
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd.Fd(), syscall.DIOCGMEDIASIZE, uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return size, nil
+}
+
+// pseudoFile reports whether fd lives on a pseudo filesystem, such as procfs
+// or sysfs, whose files report no size but still have contents.
+// Such files are only read through on Linux.
+func pseudoFile(fd *os.File) bool {
+	return false
+}
+
+// remoteFS returns the type of the network or FUSE filesystem fd lives on,
+// or "" if it is local.
+func remoteFS(fd *os.File) string {
+	var st syscall.Statfs_t
+	if err := syscall.Fstatfs(int(fd.Fd()), &st); err != nil {
+		return ""
+	}
+	fstype := syscall.ByteSliceToString(st.Fstypename[:])
+	switch fstype {
+	case "nfs", "smbfs", "fusefs":
+		return fstype
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"io"
+	"math/bits"
+	"os"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// mapLargePages is the mmap flag requesting a mapping backed by huge pages.
+// It is only honored for files living on a hugetlbfs filesystem.
+const mapLargePages = syscall.MAP_HUGETLB
+
+// mapPopulate is the mmap flag prefaulting the pages of a mapping.
+const mapPopulate = syscall.MAP_POPULATE
+
+const (
+	// mapFixed is the mmap flag requesting a mapping at a given address,
+	// without replacing existing mappings.
+	mapFixed = syscall.MAP_FIXED_NOREPLACE
+
+	// errAddrInUse is the error returned by mmap when the requested address
+	// range is already in use.
+	errAddrInUse = syscall.EEXIST
+)
+
+const (
+	// madvHugePage and madvNoHugePage are the madvise advices enabling or
+	// disabling transparent huge pages.
+	madvHugePage   = syscall.MADV_HUGEPAGE
+	madvNoHugePage = syscall.MADV_NOHUGEPAGE
+)
+
+const (
+	// madvDontFork and madvWipeOnFork are the madvise advices preventing
+	// child processes from inheriting a mapping, or making them inherit it
+	// zeroed.
+	madvDontFork   = syscall.MADV_DONTFORK
+	madvWipeOnFork = syscall.MADV_WIPEONFORK
+)
+
+// madvDontDump is the madvise advice excluding a mapping from core dumps.
+const madvDontDump = syscall.MADV_DONTDUMP
+
+// mapNoReserve is the mmap flag requesting no swap space to be reserved
+// for the mapping.
+const mapNoReserve = syscall.MAP_NORESERVE
+
+// mapSync are the mmap flags requesting a shared mapping whose metadata is
+// kept in sync with the file, so that stores to the pages of persistent
+// memory are durable once flushed from the CPU caches. Filesystems not
+// mapping persistent memory directly (DAX) fail such mappings.
+const mapSync = syscall.MAP_SHARED_VALIDATE | syscall.MAP_SYNC
+
+// cloneFile creates a file at path sharing the data blocks of src, or
+// copies them in-kernel.
+// It reports whether the file was created.
+func cloneFile(src *os.File, path string, perm os.FileMode) (bool, error) {
+	fi, err := src.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return false, err
+	}
+
+	err = syscall.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+	if err != nil {
+		err = copyFileRange(dst, src, fi.Size())
+	}
+	if err == nil {
+		err = dst.Close()
+		if err == nil {
+			return true, nil
+		}
+	}
+
+	_ = dst.Close()
+	_ = os.Remove(path)
+	if err == errUnsupported {
+		return false, nil
+	}
+	return false, err
+}
+
+// copyFileRange copies the n first bytes of src to dst in-kernel.
+func copyFileRange(dst, src *os.File, n int64) error {
+	var roff, woff int64
+	for roff < n {
+		c, err := syscall.CopyFileRange(int(src.Fd()), &roff, int(dst.Fd()), &woff, int(n-roff), 0)
+		switch {
+		case err == syscall.EINTR:
+			continue
+		case err != nil && roff == 0:
+			// copy_file_range is not supported for these files.
+			switch err {
+			case syscall.ENOSYS, syscall.EXDEV, syscall.EINVAL, syscall.EOPNOTSUPP, syscall.EPERM:
+				return errUnsupported
+			}
+			return err
+		case err != nil:
+			return err
+		case c == 0:
+			return io.ErrUnexpectedEOF
+		}
+	}
+	return nil
+}
+
+// punchHole deallocates the [off, off+n) range of fd, which then reads back
+// as zeros.
+func punchHole(fd *os.File, off, n int64) error {
+	for {
+		err := syscall.Fallocate(int(fd.Fd()), syscall.FALLOC_FL_PUNCH_HOLE|syscall.FALLOC_FL_KEEP_SIZE, off, n)
+		if err == syscall.EINTR {
+			continue
+		}
+		return err
+	}
+}
+
+const (
+	mpolBind   = 2      // MPOL_BIND
+	mpolMFMove = 1 << 1 // MPOL_MF_MOVE
+)
+
+// fileCopyOffload reports whether copies between files are offloaded to
+// the kernel, or to the filesystem, by (*os.File).ReadFrom: Linux copies
+// them with copy_file_range or splice.
+const fileCopyOffload = true
+
+// fsyncFull reports whether fsync commits the buffers of descriptors to
+// the storage device itself.
+const fsyncFull = true
+
+// fullFsync commits the buffers of fd to the storage device. On Linux,
+// fsync does.
+func fullFsync(fd *os.File) error {
+	return fd.Sync()
+}
+
+// bindNode binds the memory of data to the NUMA node, migrating the pages
+// already allocated.
+func bindNode(data []byte, node int) error {
+	if node < 0 {
+		return syscall.EINVAL
+	}
+	mask := make([]uint, node/bits.UintSize+1)
+	mask[node/bits.UintSize] = 1 << (node % bits.UintSize)
+	_, _, errno := syscall.Syscall6(
+		syscall.SYS_MBIND,
+		uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)),
+		mpolBind,
+		uintptr(unsafe.Pointer(&mask[0])), uintptr(len(mask)*bits.UintSize+1),
+		mpolMFMove,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// mlockOnFault locks the pages of b in memory as they are accessed.
+func mlockOnFault(b []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MLOCK2, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), mlockOnFaultFlag)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// mlockOnFaultFlag is the MLOCK_ONFAULT flag of mlock2.
+const mlockOnFaultFlag = 0x1
+
+// deviceSize returns the size of the block device fd.
+func deviceSize(fd *os.File) (int64, error) {
+	var size uint64
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd.Fd(), syscall.BLKGETSIZE64, uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int64(size), nil
+}
+
+// pseudoFile reports whether fd lives on a pseudo filesystem, such as procfs
+// or sysfs, whose files report no size but still have contents.
+func pseudoFile(fd *os.File) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Fstatfs(int(fd.Fd()), &st); err != nil {
+		return false
+	}
+	switch int64(st.Type) {
+	case syscall.PROC_SUPER_MAGIC, syscall.SYSFS_MAGIC, syscall.DEBUGFS_MAGIC, syscall.TRACEFS_MAGIC,
+		syscall.CGROUP_SUPER_MAGIC, syscall.CGROUP2_SUPER_MAGIC, syscall.SECURITYFS_MAGIC:
+		return true
+	}
+	return false
+}
+
+// remoteFS returns the type of the network or FUSE filesystem fd lives on,
+// or "" if it is local.
+func remoteFS(fd *os.File) string {
+	var st syscall.Statfs_t
+	if err := syscall.Fstatfs(int(fd.Fd()), &st); err != nil {
+		return ""
+	}
+	// The magic numbers of CIFS and SMB2 do not fit in the 32-bit signed
+	// type of 32-bit platforms.
+	switch uint32(st.Type) {
+	case syscall.NFS_SUPER_MAGIC:
+		return "nfs"
+	case syscall.SMB_SUPER_MAGIC, syscall.SMB2_SUPER_MAGIC:
+		return "smb"
+	case syscall.CIFS_SUPER_MAGIC:
+		return "cifs"
+	case syscall.FUSE_SUPER_MAGIC:
+		return "fuse"
+	case syscall.V9FS_MAGIC:
+		return "9p"
+	case syscall.CEPH_SUPER_MAGIC:
+		return "ceph"
+	case syscall.AFS_SUPER_MAGIC, syscall.AFS_FS_MAGIC:
+		return "afs"
+	case syscall.CODA_SUPER_MAGIC:
+		return "coda"
+	}
+	return ""
+}
@@ -0,0 +1,441 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+func TestNUMANode(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "numa.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read, WithNUMANode(0))
+	switch {
+	case errors.Is(err, syscall.ENOSYS), errors.Is(err, syscall.EPERM):
+		t.Skipf("NUMA is not supported: %+v", err)
+	case err != nil:
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.At(0), byte('h'); got != want {
+		t.Fatalf("invalid byte: got=%q, want=%q", got, want)
+	}
+
+	_, err = OpenFile(fname, Read, WithNUMANode(-1))
+	if err == nil {
+		t.Fatalf("expected an error binding to an invalid node")
+	}
+}
+
+func TestBlockDevice(t *testing.T) {
+	devs, _ := filepath.Glob("/sys/block/*/size")
+	for _, dev := range devs {
+		raw, err := os.ReadFile(dev)
+		if err != nil {
+			continue
+		}
+		sectors, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+		if err != nil || sectors == 0 {
+			continue
+		}
+		fname := filepath.Join("/dev", filepath.Base(filepath.Dir(dev)))
+		f, err := Open(fname)
+		if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("could not mmap %q: %+v", fname, err)
+		}
+		defer f.Close()
+
+		if got, want := int64(f.Len()), sectors*512; got != want {
+			t.Fatalf("invalid length of %q: got=%d, want=%d", fname, got, want)
+		}
+		return
+	}
+	t.Skip("no readable block device")
+}
+
+func TestPseudoFile(t *testing.T) {
+	f, err := Open("/proc/self/status")
+	if err != nil {
+		t.Fatalf("could not mmap procfs file: %+v", err)
+	}
+	defer f.Close()
+
+	if !bytes.HasPrefix(f.Bytes(), []byte("Name:")) {
+		t.Fatalf("invalid contents: %q", f.Bytes())
+	}
+	_, err = f.WriteAt([]byte("x"), 0)
+	if err == nil {
+		t.Fatalf("expected an error writing to a pseudo file")
+	}
+	err = f.Remap()
+	if err != nil {
+		t.Fatalf("could not remap procfs file: %+v", err)
+	}
+	if f.Len() == 0 {
+		t.Fatalf("empty remapped procfs file")
+	}
+}
+
+func TestAdviseHugePages(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "thp.bin")
+	err := os.WriteFile(fname, make([]byte, 1<<16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	for _, enable := range []bool{true, false} {
+		err = f.AdviseHugePages(enable)
+		if errors.Is(err, syscall.EINVAL) {
+			t.Skipf("transparent huge pages are not supported: %+v", err)
+		}
+		if err != nil {
+			t.Fatalf("could not advise huge pages (enable=%v): %+v", enable, err)
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if got, want := f.AdviseHugePages(true), errClosed; got != want {
+		t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", got, want)
+	}
+}
+
+func TestDirtyPages(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "dirty.bin")
+	page := os.Getpagesize()
+	err := os.WriteFile(fname, make([]byte, 4*page), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, WithDirtyTracking())
+	if errors.Is(err, errUnsupported) {
+		t.Skipf("soft-dirty bits are not supported: %+v", err)
+	}
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	// Another tracked mapping, to check that resetting its tracking does
+	// not lose the dirty pages of f.
+	g, err := OpenFile(fname, Read|Write, WithDirtyTracking())
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer g.Close()
+
+	f.Bytes()[page+1] = 'x'
+	f.Bytes()[3*page] = 'y'
+
+	err = g.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+
+	offs, err := f.GetDirtyPages()
+	if err != nil {
+		t.Fatalf("could not get dirty pages: %+v", err)
+	}
+	if len(offs) != 2 || offs[0] != int64(page) || offs[1] != int64(3*page) {
+		t.Fatalf("invalid dirty pages: %v", offs)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	offs, err = f.GetDirtyPages()
+	if err != nil || len(offs) != 0 {
+		t.Fatalf("invalid dirty pages after sync: got=(%v, %v)", offs, err)
+	}
+}
+
+func TestDirtyPagesSyncFailure(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "dirty.bin")
+	page := os.Getpagesize()
+	err := os.WriteFile(fname, make([]byte, 4*page), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	var (
+		fail   = true
+		msyncs int
+	)
+	f, err := OpenFile(fname, Read|Write, WithDirtyTracking(), WithFaults(func(call Syscall) error {
+		if call != SyscallMsync {
+			return nil
+		}
+		msyncs++
+		if fail {
+			return errors.New("injected")
+		}
+		return nil
+	}))
+	if errors.Is(err, errUnsupported) {
+		t.Skipf("soft-dirty bits are not supported: %+v", err)
+	}
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	f.Bytes()[0] = 'x'
+	f.Bytes()[3*page] = 'y'
+	err = f.Sync()
+	if err == nil {
+		t.Fatalf("sync should have failed")
+	}
+
+	// The pages the failed sync did not commit are no longer reported: the
+	// next sync commits the whole mapping.
+	fail = false
+	msyncs = 0
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	if msyncs != 1 {
+		t.Fatalf("invalid number of msync calls after a failure: got=%d, want=1", msyncs)
+	}
+
+	f.Bytes()[0] = 'z'
+	msyncs = 0
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	if msyncs != 1 {
+		t.Fatalf("invalid number of msync calls: got=%d, want=1", msyncs)
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if raw[0] != 'z' || raw[3*page] != 'y' {
+		t.Fatalf("dirty pages not synced")
+	}
+}
+
+func TestChangedRelative(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("could not get working directory: %+v", err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatalf("could not change directory: %+v", err)
+	}
+	defer os.Chdir(wd)
+
+	// Regular files named like memfds are still checked against their path.
+	fname := "memfd:changed.txt"
+	err = os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	err = os.Remove(fname)
+	if err != nil {
+		t.Fatalf("could not remove file: %+v", err)
+	}
+	changed, err := f.Changed()
+	if err != nil || !changed {
+		t.Fatalf("removed file not reported as changed: %v, %+v", changed, err)
+	}
+
+	m, err := CreateMemfd("changed.txt", 16)
+	if err != nil {
+		t.Skipf("could not create memfd: %+v", err)
+	}
+	defer m.Close()
+	changed, err = m.Changed()
+	if err != nil || changed {
+		t.Fatalf("memfd reported as changed: %v, %+v", changed, err)
+	}
+}
+
+func TestMemfdSeal(t *testing.T) {
+	f, err := CreateMemfd("seal-test", 4096)
+	if err != nil {
+		t.Skipf("could not create memfd: %+v", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte("sealed"), 0)
+	if err != nil {
+		t.Fatalf("could not write memfd: %+v", err)
+	}
+
+	err = f.Seal(SealShrink | SealGrow | SealWrite)
+	if err != nil {
+		t.Fatalf("could not seal memfd: %+v", err)
+	}
+	seals, err := f.Seals()
+	if err != nil {
+		t.Fatalf("could not get seals: %+v", err)
+	}
+	if want := SealShrink | SealGrow | SealWrite; seals&want != want {
+		t.Fatalf("invalid seals: got=%#x, want=%#x", seals, want)
+	}
+
+	if got, want := string(f.data[:6]), "sealed"; got != want {
+		t.Fatalf("invalid contents after sealing: got=%q, want=%q", got, want)
+	}
+	if _, err := f.WriteAt([]byte("x"), 0); err == nil {
+		t.Fatalf("expected an error writing a sealed memfd")
+	}
+	if err := f.fd.Truncate(8192); err == nil {
+		t.Fatalf("expected an error growing a sealed memfd")
+	}
+
+	err = f.Seal(SealSeal)
+	if err != nil {
+		t.Fatalf("could not seal seals: %+v", err)
+	}
+	if err := f.Seal(SealFutureWrite); err == nil {
+		t.Fatalf("expected an error adding seals after SealSeal")
+	}
+}
+
+func TestMappings(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data file")
+	err := os.WriteFile(name, make([]byte, 3<<16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := OpenFile(name, Read|Write)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	r, err := OpenRegion(name, Read, 1<<16, 10)
+	if err != nil {
+		t.Fatalf("could not open region: %+v", err)
+	}
+	defer r.Close()
+
+	find := func(infos []MappingInfo, addr uintptr) *MappingInfo {
+		for i := range infos {
+			if infos[i].Addr == addr {
+				return &infos[i]
+			}
+		}
+		return nil
+	}
+
+	infos, err := Mappings()
+	if err != nil {
+		t.Fatalf("could not list mappings: %+v", err)
+	}
+	info := find(infos, f.Addr())
+	if info == nil {
+		t.Fatalf("file mapping is not listed: %+v", infos)
+	}
+	if want := (MappingInfo{Addr: f.Addr(), Len: 3 << 16, Perm: "rw-s", Path: name}); *info != want {
+		t.Fatalf("invalid file mapping:\ngot= %+v\nwant=%+v", *info, want)
+	}
+	info = find(infos, r.Addr())
+	if info == nil {
+		t.Fatalf("region mapping is not listed: %+v", infos)
+	}
+	if info.Perm != "r--s" || info.Offset != 1<<16 || info.Path != name || info.Len < r.MappedLen() {
+		t.Fatalf("invalid region mapping: %+v", *info)
+	}
+
+	addr := f.Addr()
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	infos, err = Mappings()
+	if err != nil {
+		t.Fatalf("could not list mappings: %+v", err)
+	}
+	if find(infos, addr) != nil {
+		t.Fatalf("closed file is still listed")
+	}
+}
+
+func TestParseMapsEntry(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		want MappingInfo
+		ok   bool
+	}{
+		{
+			line: "7f2c0000-7f2d0000 rw-s 00001000 08:01 1234                       /path/to/a file",
+			want: MappingInfo{Addr: 0x7f2c0000, Len: 0x10000, Perm: "rw-s", Offset: 0x1000, Path: "/path/to/a file"},
+			ok:   true,
+		},
+		{
+			line: "7f2c0000-7f2c1000 rw-p 00000000 00:00 0",
+			want: MappingInfo{Addr: 0x7f2c0000, Len: 0x1000, Perm: "rw-p"},
+			ok:   true,
+		},
+		{line: "garbage"},
+		{line: "zz-7f2c1000 rw-p 00000000 00:00 0"},
+	} {
+		got, ok := parseMapsEntry(tc.line)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("invalid entry for %q:\ngot= %+v, %v\nwant=%+v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestMapSync(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(name, Read|Write, WithMapSync())
+	if err != nil {
+		// Temporary directories are seldom on persistent memory.
+		if !errors.Is(err, syscall.EOPNOTSUPP) {
+			t.Fatalf("invalid error for non-DAX filesystem: %+v", err)
+		}
+		t.Skipf("filesystem of %q does not support MAP_SYNC", name)
+	}
+	defer f.Close()
+	_, err = f.WriteAt([]byte("hello"), 0)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = f.Persist(0, 5)
+	if err != nil {
+		t.Fatalf("could not persist: %+v", err)
+	}
+}
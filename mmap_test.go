@@ -5,11 +5,25 @@
 package mmap
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func TestOpen(t *testing.T) {
@@ -150,11 +164,8 @@ func TestOpen(t *testing.T) {
 
 			t.Run("sync", func(t *testing.T) {
 				err := r.Sync()
-				if err == nil {
-					t.Fatal("expected an error")
-				}
-				if got, want := err, errBadFD; got.Error() != want.Error() {
-					t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", got, want)
+				if err != nil {
+					t.Fatalf("could not sync read-only file: %+v", err)
 				}
 			})
 
@@ -283,3 +294,4843 @@ func TestWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenOptions(t *testing.T) {
+	const filename = "mmap_test.go"
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("os.ReadFile: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opt  Option
+	}{
+		{"sequential", WithSequential()},
+		{"random", WithRandom()},
+		{"large-pages", WithLargePages()},
+		{"no-reserve", WithNoReserve()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(filename, Read, tc.opt)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("invalid content")
+			}
+		})
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	f, err := Open("mmap_test.go")
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	size := int64(f.Len())
+	for _, tc := range []struct {
+		off, n int64
+		err    bool
+	}{
+		{0, size, false},
+		{1, 10, false},
+		{size, 0, false},
+		{-1, 10, true},
+		{0, size + 1, true},
+		{10, -1, true},
+	} {
+		err := f.Prefetch(tc.off, tc.n)
+		switch {
+		case err != nil && !tc.err:
+			t.Fatalf("could not prefetch [%d, %d): %+v", tc.off, tc.off+tc.n, err)
+		case err == nil && tc.err:
+			t.Fatalf("expected an error prefetching [%d, %d)", tc.off, tc.off+tc.n)
+		}
+		err = <-f.PrefetchAsync(tc.off, tc.n)
+		switch {
+		case err != nil && !tc.err:
+			t.Fatalf("could not prefetch [%d, %d) asynchronously: %+v", tc.off, tc.off+tc.n, err)
+		case err == nil && tc.err:
+			t.Fatalf("expected an error prefetching [%d, %d) asynchronously", tc.off, tc.off+tc.n)
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if err := f.Prefetch(0, 1); err == nil {
+		t.Fatalf("expected an error prefetching a closed file")
+	}
+}
+
+func TestRelease(t *testing.T) {
+	const filename = "mmap_test.go"
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("os.ReadFile: %v", err)
+	}
+
+	f, err := Open(filename)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	err = f.Release(0, int64(f.Len()))
+	if err != nil {
+		t.Fatalf("could not release: %+v", err)
+	}
+
+	got := make([]byte, f.Len())
+	_, err = f.ReadAt(got, 0)
+	if err != nil && err != io.EOF {
+		t.Fatalf("could not read after release: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("invalid content after release")
+	}
+
+	if err := f.Release(1, int64(f.Len())); err == nil {
+		t.Fatalf("expected an error releasing an invalid range")
+	}
+
+	s, err := f.Snapshot()
+	if err != nil {
+		t.Fatalf("could not snapshot file: %+v", err)
+	}
+	defer s.Close()
+	if err := s.Release(0, int64(s.Len())); !errors.Is(err, errUnsupported) {
+		t.Fatalf("invalid error releasing a snapshot: %+v", err)
+	}
+}
+
+func TestSyncRange(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "sync-range.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+
+	err = f.SyncRange(0, 5)
+	if err != nil {
+		t.Fatalf("could not sync range: %+v", err)
+	}
+
+	err = f.SyncRange(6, int64(f.Len()))
+	if err == nil {
+		t.Fatalf("expected an error syncing an invalid range")
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if got, want := raw, []byte("HELLO world!\nbye.\n"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want)
+	}
+
+	r, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer r.Close()
+
+	if err := r.SyncRange(0, 5); err != nil {
+		t.Fatalf("could not sync range of read-only file: %+v", err)
+	}
+	if err := r.SyncRange(0, 1<<20); err == nil {
+		t.Fatalf("expected an error syncing an invalid range of read-only file")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	const span = 1 << 16
+
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "window.bin")
+	want := make([]byte, 3*span+123)
+	for i := range want {
+		want[i] = byte(i % 251)
+	}
+	err := os.WriteFile(fname, want, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, withWindow(span))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	if f.w == nil {
+		t.Fatalf("file not mapped through a window")
+	}
+	if got, want := f.Size(), int64(len(want)); got != want {
+		t.Fatalf("invalid size: got=%d, want=%d", got, want)
+	}
+
+	got := make([]byte, 2*span)
+	_, err = f.ReadAt(got, span-10)
+	if err != nil {
+		t.Fatalf("could not read-at across views: %+v", err)
+	}
+	if !bytes.Equal(got, want[span-10:3*span-10]) {
+		t.Fatalf("invalid read-at content")
+	}
+
+	if got, want := f.At(2*span+1), want[2*span+1]; got != want {
+		t.Fatalf("invalid At: got=%d, want=%d", got, want)
+	}
+
+	all, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if !bytes.Equal(all, want) {
+		t.Fatalf("invalid read content")
+	}
+
+	n, err := f.ReadAt(got, int64(len(want))-10)
+	if err != io.EOF || n != 10 {
+		t.Fatalf("invalid read-at at end: n=%d, err=%v", n, err)
+	}
+
+	patch := bytes.Repeat([]byte("x"), 100)
+	_, err = f.WriteAt(patch, 2*span-50)
+	if err != nil {
+		t.Fatalf("could not write-at across views: %+v", err)
+	}
+	copy(want[2*span-50:], patch)
+
+	_, err = f.Seek(1, io.SeekEnd)
+	if err != nil {
+		t.Fatalf("could not seek: %+v", err)
+	}
+	err = f.WriteByte('!')
+	if err != nil {
+		t.Fatalf("could not write-byte: %+v", err)
+	}
+	want[len(want)-1] = '!'
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if !bytes.Equal(raw, want) {
+		t.Fatalf("invalid file content")
+	}
+}
+
+func TestShareDelete(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "share.txt")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read, WithShareMode(ShareRead|ShareWrite|ShareDelete))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	err = os.Rename(fname, fname+".old")
+	if err != nil {
+		t.Fatalf("could not rename mapped file: %+v", err)
+	}
+
+	got := make([]byte, f.Len())
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read renamed file: %+v", err)
+	}
+	if got, want := got, []byte("hello world!\n"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want)
+	}
+}
+
+func TestMapAt(t *testing.T) {
+	const filename = "mmap_test.go"
+
+	f, err := Open(filename)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	addr := uintptr(unsafe.Pointer(&f.data[0]))
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	f, err = OpenFile(filename, Read, MapAt(addr))
+	if err != nil {
+		t.Fatalf("could not map file at %#x: %+v", addr, err)
+	}
+	defer f.Close()
+
+	if got, want := uintptr(unsafe.Pointer(&f.data[0])), addr; got != want {
+		t.Fatalf("invalid address: got=%#x, want=%#x", got, want)
+	}
+
+	_, err = OpenFile(filename, Read, MapAt(addr))
+	if !errors.Is(err, ErrAddrNotAvailable) {
+		t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", err, ErrAddrNotAvailable)
+	}
+}
+
+func TestLock(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "lock.txt")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f1, err := OpenFile(fname, Read|Write, WithLock(Exclusive))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f1.Close()
+
+	f2, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f2.Close()
+
+	tryLock := func(f *File, mode LockMode, want bool) {
+		t.Helper()
+		ok, err := f.TryLock(mode)
+		if err != nil {
+			t.Fatalf("could not try-lock: %+v", err)
+		}
+		if ok != want {
+			t.Fatalf("invalid try-lock: got=%v, want=%v", ok, want)
+		}
+	}
+
+	tryLock(f2, Shared, false)
+
+	err = f1.Unlock()
+	if err != nil {
+		t.Fatalf("could not unlock: %+v", err)
+	}
+
+	tryLock(f2, Shared, true)
+	tryLock(f1, Exclusive, false)
+
+	err = f2.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+
+	tryLock(f1, Exclusive, true)
+
+	if _, err := f1.TryLock(0); err != errLockMode {
+		t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", err, errLockMode)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	seed := func(t *testing.T) string {
+		t.Helper()
+		fname := filepath.Join(t.TempDir(), "watch.txt")
+		err := os.WriteFile(fname, []byte("hello"), 0644)
+		if err != nil {
+			t.Fatalf("could not seed file: %+v", err)
+		}
+		return fname
+	}
+
+	grow := func(t *testing.T, fname string) {
+		t.Helper()
+		f, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+		_, err = f.Write([]byte(" world!"))
+		if err != nil {
+			t.Fatalf("could not grow file: %+v", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("could not close file: %+v", err)
+		}
+	}
+
+	const want = "hello world!"
+
+	t.Run("remap", func(t *testing.T) {
+		fname := seed(t)
+		f, err := OpenFile(fname, Read, WithWatch(nil))
+		if err != nil {
+			t.Fatalf("could not mmap file: %+v", err)
+		}
+		defer f.Close()
+
+		grow(t, fname)
+
+		buf := make([]byte, len(want))
+		deadline := time.Now().Add(5 * time.Second)
+		for f.Size() != int64(len(want)) {
+			if time.Now().After(deadline) {
+				t.Fatalf("file was not remapped: size=%d", f.Size())
+			}
+			time.Sleep(10 * time.Millisecond)
+			_, err = f.ReadAt(buf[:1], 0)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+		}
+
+		_, err = f.ReadAt(buf, 0)
+		if err != nil {
+			t.Fatalf("could not read file: %+v", err)
+		}
+		if got := string(buf); got != want {
+			t.Fatalf("invalid content: got=%q, want=%q", got, want)
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		fname := seed(t)
+		changed := make(chan *File, 16)
+		f, err := OpenFile(fname, Read, WithWatch(func(f *File) {
+			select {
+			case changed <- f:
+			default:
+			}
+		}))
+		if err != nil {
+			t.Fatalf("could not mmap file: %+v", err)
+		}
+		defer f.Close()
+
+		grow(t, fname)
+
+		select {
+		case got := <-changed:
+			if got != f {
+				t.Fatalf("callback called with an invalid file")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("callback not called")
+		}
+
+		err = f.Remap()
+		if err != nil {
+			t.Fatalf("could not remap file: %+v", err)
+		}
+		if got, want := f.Size(), int64(len(want)); got != want {
+			t.Fatalf("invalid size: got=%d, want=%d", got, want)
+		}
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "snapshot.txt")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	s, err := f.Snapshot()
+	if err != nil {
+		t.Fatalf("could not snapshot file: %+v", err)
+	}
+	defer s.Close()
+
+	_, err = s.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write to snapshot: %+v", err)
+	}
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("could not sync snapshot: %+v", err)
+	}
+
+	_, err = f.WriteAt([]byte("w"), 0)
+	if err != nil {
+		t.Fatalf("could not write to file: %+v", err)
+	}
+
+	got := make([]byte, s.Len())
+	_, err = s.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read snapshot: %+v", err)
+	}
+	if got, want := got, []byte("HELLO world!\n"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid snapshot content:\ngot= %q\nwant=%q\n", got, want)
+	}
+
+	err = s.Close()
+	if err != nil {
+		t.Fatalf("could not close snapshot: %+v", err)
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if got, want := raw, []byte("wello world!\n"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid file content:\ngot= %q\nwant=%q\n", got, want)
+	}
+}
+
+func TestCloneTo(t *testing.T) {
+	tmp := t.TempDir()
+	want := bytes.Repeat([]byte("hello world!\n"), 1<<12)
+	fname := filepath.Join(tmp, "src.txt")
+	err := os.WriteFile(fname, want, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"flat", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(fname, Read|Write, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			_, err = f.WriteAt([]byte("HELLO"), 0)
+			if err != nil {
+				t.Fatalf("could not write-at: %+v", err)
+			}
+			want := append([]byte("HELLO"), want[5:]...)
+
+			for _, clone := range []struct {
+				name string
+				fct  func(path string) error
+			}{
+				{"clone", f.CloneTo},
+				{"copy", f.copyTo},
+			} {
+				dst := filepath.Join(tmp, tc.name+"-"+clone.name+".txt")
+				err = clone.fct(dst)
+				if err != nil {
+					t.Fatalf("could not %s file: %+v", clone.name, err)
+				}
+
+				got, err := os.ReadFile(dst)
+				if err != nil {
+					t.Fatalf("could not read %s: %+v", clone.name, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("invalid %s content", clone.name)
+				}
+			}
+
+			err = f.CloneTo(fname)
+			if err == nil {
+				t.Fatalf("expected an error cloning to an existing file")
+			}
+		})
+	}
+}
+
+func TestCloneToMapped(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "src.txt")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		open func() (*File, error)
+	}{
+		{"snapshot", func() (*File, error) {
+			f, err := Open(fname)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return f.Snapshot()
+		}},
+		{"dirty", func() (*File, error) {
+			return OpenFile(fname, Read|Write, WithDirtyTracking())
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := tc.open()
+			if errors.Is(err, errUnsupported) {
+				t.Skipf("could not mmap file: %+v", err)
+			}
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			// The writes are not synced: the clone must hold them all the
+			// same, as the snapshot of the mapped contents.
+			_, err = f.WriteAt([]byte("HELLO"), 0)
+			if err != nil {
+				t.Fatalf("could not write-at: %+v", err)
+			}
+			dst := filepath.Join(tmp, tc.name+".txt")
+			err = f.CloneTo(dst)
+			if err != nil {
+				t.Fatalf("could not clone file: %+v", err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatalf("could not read clone: %+v", err)
+			}
+			if want := "HELLO world!\n"; string(got) != want {
+				t.Fatalf("invalid clone content: got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
+
+func TestZeroFill(t *testing.T) {
+	page := os.Getpagesize()
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"flat", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), "zero.bin")
+			want := bytes.Repeat([]byte{0xff}, 4*page+10)
+			err := os.WriteFile(fname, want, 0644)
+			if err != nil {
+				t.Fatalf("could not seed file: %+v", err)
+			}
+
+			f, err := OpenFile(fname, Read|Write, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			err = f.Zero(5, int64(3*page))
+			if err != nil {
+				t.Fatalf("could not zero range: %+v", err)
+			}
+			copy(want[5:], make([]byte, 3*page))
+
+			err = f.Fill('x', int64(4*page), 7)
+			if err != nil {
+				t.Fatalf("could not fill range: %+v", err)
+			}
+			copy(want[4*page:], "xxxxxxx")
+
+			err = f.Zero(int64(len(want)-2), 3)
+			if err == nil {
+				t.Fatalf("expected an error zeroing an invalid range")
+			}
+
+			got := make([]byte, len(want))
+			_, err = f.ReadAt(got, 0)
+			if err != nil {
+				t.Fatalf("could not read-at: %+v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("invalid mapped content")
+			}
+
+			err = f.Sync()
+			if err != nil {
+				t.Fatalf("could not sync: %+v", err)
+			}
+			got, err = os.ReadFile(fname)
+			if err != nil {
+				t.Fatalf("could not read back file: %+v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("invalid file content")
+			}
+
+			r, err := Open(fname)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer r.Close()
+
+			if got, want := r.Fill(0, 0, 1), errBadFD; got != want {
+				t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestAutoExtend(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "extend.txt")
+	err := os.WriteFile(fname, nil, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte("hello"), 3)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	if got, want := f.Len(), 8; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+
+	_, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatalf("could not seek: %+v", err)
+	}
+	_, err = f.Write([]byte(" world"))
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = f.WriteByte('!')
+	if err != nil {
+		t.Fatalf("could not write byte: %+v", err)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if got, want := raw, []byte("\x00\x00\x00hello world!"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want)
+	}
+}
+
+func TestSyncContext(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "sync-context.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+
+	err = f.SyncContext(context.Background())
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if got, want := raw, []byte("HELLO world!\nbye.\n"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, want := f.SyncContext(ctx), context.Canceled; got != want {
+		t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", got, want)
+	}
+}
+
+func TestSyncPolicy(t *testing.T) {
+	tmp := t.TempDir()
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		syncs int
+	}{
+		{"default", nil, 1},
+		{"sync-on-close", []Option{SyncOnClose()}, 2},
+		// The WriteAt, then the Zero, then the explicit Sync.
+		{"sync-every", []Option{SyncEveryNBytes(3)}, 3},
+		{"no-sync", []Option{NoSync()}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fname := filepath.Join(tmp, tc.name+".txt")
+			err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+			if err != nil {
+				t.Fatalf("could not seed file: %+v", err)
+			}
+
+			syncs := 0
+			opts := append(tc.opts, WithFaults(func(call Syscall) error {
+				if call == SyscallMsync {
+					syncs++
+				}
+				return nil
+			}))
+			f, err := OpenFile(fname, Read|Write, opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			_, err = f.WriteAt([]byte("HELLO"), 0)
+			if err != nil {
+				t.Fatalf("could not write-at: %+v", err)
+			}
+			err = f.WriteByte('H')
+			if err != nil {
+				t.Fatalf("could not write byte: %+v", err)
+			}
+			err = f.Zero(12, 2)
+			if err != nil {
+				t.Fatalf("could not zero: %+v", err)
+			}
+			err = f.Fill('!', 14, 1)
+			if err != nil {
+				t.Fatalf("could not fill: %+v", err)
+			}
+
+			err = f.Sync()
+			if err != nil {
+				t.Fatalf("could not sync: %+v", err)
+			}
+
+			err = f.Close()
+			if err != nil {
+				t.Fatalf("could not close file: %+v", err)
+			}
+			if syncs != tc.syncs {
+				t.Fatalf("invalid number of syncs: got=%d, want=%d", syncs, tc.syncs)
+			}
+
+			raw, err := os.ReadFile(fname)
+			if err != nil {
+				t.Fatalf("could not read back file: %+v", err)
+			}
+			if got, want := raw, []byte("HELLO world!\x00\x00!e.\n"); !bytes.Equal(got, want) {
+				t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want)
+			}
+		})
+	}
+}
+
+func TestCreateAtomic(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "atomic.txt")
+	err := os.WriteFile(fname, []byte("old content"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := CreateAtomic(fname, 5)
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte("hello"), 0)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := raw, []byte("old content"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content before commit:\ngot= %q\nwant=%q\n", got, want)
+	}
+
+	err = f.Commit()
+	if err != nil {
+		t.Fatalf("could not commit file: %+v", err)
+	}
+
+	raw, err = os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := raw, []byte("hello"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content after commit:\ngot= %q\nwant=%q\n", got, want)
+	}
+
+	d, err := CreateAtomic(fname, 0)
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+	err = d.Close()
+	if err != nil {
+		t.Fatalf("could not discard file: %+v", err)
+	}
+
+	for _, opt := range []Option{WithExclusive(), WithTruncate()} {
+		_, err = CreateAtomic(fname, 5, opt)
+		if !errors.Is(err, os.ErrInvalid) {
+			t.Fatalf("invalid error creating file with open option: %+v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatalf("could not read dir: %+v", err)
+	}
+	if got, want := len(entries), 1; got != want {
+		t.Fatalf("invalid number of files: got=%d, want=%d", got, want)
+	}
+
+	// Committed files keep the permissions of the file they replace, or
+	// get the ones of newly created files.
+	err = os.Chmod(fname, 0640)
+	if err != nil {
+		t.Fatalf("could not chmod file: %+v", err)
+	}
+	ref := filepath.Join(tmp, "ref.txt")
+	err = os.WriteFile(ref, nil, 0666)
+	if err != nil {
+		t.Fatalf("could not create reference file: %+v", err)
+	}
+	for _, name := range []string{fname, filepath.Join(tmp, "new.txt")} {
+		want := ref
+		if name == fname {
+			want = fname
+		}
+		fi, err := os.Stat(want)
+		if err != nil {
+			t.Fatalf("could not stat file: %+v", err)
+		}
+		a, err := CreateAtomic(name, 5)
+		if err != nil {
+			t.Fatalf("could not create file: %+v", err)
+		}
+		err = a.Commit()
+		if err != nil {
+			t.Fatalf("could not commit file: %+v", err)
+		}
+		got, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("could not stat committed file: %+v", err)
+		}
+		if got.Mode() != fi.Mode() {
+			t.Fatalf("invalid mode of %q: got=%v, want=%v", name, got.Mode(), fi.Mode())
+		}
+	}
+}
+
+func TestTxn(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "txn.bin")
+	page := os.Getpagesize()
+	orig := bytes.Repeat([]byte("a"), 2*page+10)
+	err := os.WriteFile(fname, orig, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	tx, err := f.Begin()
+	if err != nil {
+		t.Fatalf("could not begin transaction: %+v", err)
+	}
+	// Straddle the first two pages, and write to the last partial one.
+	_, err = tx.WriteAt([]byte("hello"), int64(page-2))
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	_, err = tx.WriteAt([]byte("bye"), int64(len(orig)-3))
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	_, err = tx.WriteAt([]byte("oops"), int64(len(orig)+1))
+	if err == nil {
+		t.Fatalf("expected an error writing past the end of the file")
+	}
+
+	want := append([]byte(nil), orig...)
+	copy(want[page-2:], "hello")
+	copy(want[len(want)-3:], "bye")
+
+	got := make([]byte, len(orig))
+	_, err = tx.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("transaction does not observe its writes")
+	}
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if !bytes.Equal(got, orig) {
+		t.Fatalf("file observes uncommitted writes")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("could not commit transaction: %+v", err)
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if !bytes.Equal(raw, want) {
+		t.Fatalf("invalid content after commit")
+	}
+
+	tx, err = f.Begin()
+	if err != nil {
+		t.Fatalf("could not begin transaction: %+v", err)
+	}
+	_, err = tx.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("could not rollback transaction: %+v", err)
+	}
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("file observes rolled back writes")
+	}
+	if got, want := tx.Commit(), errClosed; got != want {
+		t.Fatalf("invalid error:\ngot= %+v\nwant=%+v", got, want)
+	}
+}
+
+func TestTxnRetry(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "txn.bin")
+	page := os.Getpagesize()
+	orig := bytes.Repeat([]byte("a"), 2*page)
+	err := os.WriteFile(fname, orig, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	tx, err := f.Begin()
+	if err != nil {
+		t.Fatalf("could not begin transaction: %+v", err)
+	}
+	_, err = tx.WriteAt([]byte("hello"), int64(page+1))
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+
+	// Truncate the file below the written page: the commit is rejected,
+	// and may be retried once the file is restored.
+	for _, size := range []int{page, 2 * page} {
+		err = os.Truncate(fname, int64(size))
+		if err != nil {
+			t.Fatalf("could not truncate file: %+v", err)
+		}
+		err = f.Remap()
+		if err != nil {
+			t.Fatalf("could not remap file: %+v", err)
+		}
+		if size == 2*page {
+			break
+		}
+		err = tx.Commit()
+		if err == nil {
+			t.Fatalf("expected an error committing past the end of the file")
+		}
+		_, err = os.Stat(fname + "-journal")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("journal written for rejected commit: %+v", err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("could not retry commit: %+v", err)
+	}
+	got := make([]byte, 5)
+	_, err = f.ReadAt(got, int64(page+1))
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("invalid content after retry: got=(%q, %v)", got, err)
+	}
+	_, err = os.Stat(fname + "-journal")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("journal kept after commit: %+v", err)
+	}
+
+	// Transactions can also be rolled back after a rejected commit.
+	tx, err = f.Begin()
+	if err != nil {
+		t.Fatalf("could not begin transaction: %+v", err)
+	}
+	_, err = tx.WriteAt([]byte("oops"), int64(page+1))
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	err = os.Truncate(fname, int64(page))
+	if err == nil {
+		err = f.Remap()
+	}
+	if err != nil {
+		t.Fatalf("could not truncate file: %+v", err)
+	}
+	if tx.Commit() == nil {
+		t.Fatalf("expected an error committing past the end of the file")
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("could not rollback after rejected commit: %+v", err)
+	}
+}
+
+func TestTxnJournal(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "txn.bin")
+	page := os.Getpagesize()
+	orig := bytes.Repeat([]byte("a"), 3*page)
+	want := append([]byte(nil), orig...)
+	copy(want[10:], "first")
+	copy(want[2*page+10:], "last")
+
+	for _, tc := range []struct {
+		name string
+		torn bool
+	}{
+		{"complete", false},
+		{"torn", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := os.WriteFile(fname, orig, 0644)
+			if err != nil {
+				t.Fatalf("could not seed file: %+v", err)
+			}
+			f, err := OpenFile(fname, Read|Write)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			tx, err := f.Begin()
+			if err != nil {
+				t.Fatalf("could not begin transaction: %+v", err)
+			}
+			_, err = tx.WriteAt([]byte("first"), 10)
+			if err == nil {
+				_, err = tx.WriteAt([]byte("last"), int64(2*page+10))
+			}
+			if err != nil {
+				t.Fatalf("could not write-at: %+v", err)
+			}
+
+			// Crash after logging the commit, before applying it.
+			journal := tx.journal([]int64{0, 2})
+			if tc.torn {
+				journal = journal[:len(journal)-10]
+			}
+			err = f.writeJournal(journal)
+			if err != nil {
+				t.Fatalf("could not write journal: %+v", err)
+			}
+			err = tx.Rollback()
+			if err != nil {
+				t.Fatalf("could not rollback: %+v", err)
+			}
+
+			tx, err = f.Begin()
+			if err != nil {
+				t.Fatalf("could not begin transaction: %+v", err)
+			}
+			defer tx.Rollback()
+			raw, err := os.ReadFile(fname)
+			if err != nil {
+				t.Fatalf("could not read back file: %+v", err)
+			}
+			switch {
+			case tc.torn && !bytes.Equal(raw, orig):
+				t.Fatalf("torn journal was replayed")
+			case !tc.torn && !bytes.Equal(raw, want):
+				t.Fatalf("complete journal was not replayed")
+			}
+			_, err = os.Stat(fname + "-journal")
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("journal kept after replay: %+v", err)
+			}
+		})
+	}
+}
+
+func TestChecksummed(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "data.bin")
+	sname := filepath.Join(tmp, "data.sums")
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	err := os.WriteFile(fname, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	err = os.WriteFile(sname, make([]byte, SumsSize(int64(len(content)))), 0644)
+	if err != nil {
+		t.Fatalf("could not seed checksum file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+	sums, err := OpenFile(sname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap checksum file: %+v", err)
+	}
+	defer sums.Close()
+
+	c, err := NewChecksummed(f, sums)
+	if err != nil {
+		t.Fatalf("could not create checksummed file: %+v", err)
+	}
+	if err := c.Verify(); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("invalid error before rehash: %+v", err)
+	}
+	err = c.Rehash()
+	if err != nil {
+		t.Fatalf("could not rehash: %+v", err)
+	}
+	if err := c.Verify(); err != nil {
+		t.Fatalf("could not verify: %+v", err)
+	}
+
+	_, err = c.WriteAt([]byte("hello"), 4094)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	err = c.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	got := make([]byte, 5)
+	_, err = c.ReadAt(got, 4094)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if !bytes.Equal(got, []byte("hello")) {
+		t.Fatalf("invalid content: %q", got)
+	}
+
+	// Corrupt the file behind the back of the checksummed layer.
+	_, err = f.WriteAt([]byte("X"), 9000)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	_, err = c.ReadAt(got, 8192)
+	if !errors.Is(err, ErrChecksum) {
+		t.Fatalf("invalid error reading corrupted page: %+v", err)
+	}
+	_, err = c.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at intact page: %+v", err)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "records.bin")
+	err := os.WriteFile(fname, []byte("aaaabbbbccccdd"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"flat", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(fname, Read|Write, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			recs := f.Records(4)
+			if got, want := recs.Len(), 3; got != want {
+				t.Fatalf("invalid number of records: got=%d, want=%d", got, want)
+			}
+			if got, want := recs.At(1), []byte("bbbb"); !bytes.Equal(got, want) {
+				t.Fatalf("invalid record: got=%q, want=%q", got, want)
+			}
+
+			err = recs.Set(2, []byte("CCCC"))
+			if err != nil {
+				t.Fatalf("could not set record: %+v", err)
+			}
+			err = recs.Set(0, []byte("A"))
+			if err == nil {
+				t.Fatalf("expected an error setting a short record")
+			}
+
+			var got []string
+			recs.Range(func(i int, rec []byte) bool {
+				got = append(got, string(rec))
+				return i < 1
+			})
+			if want := []string{"aaaa", "bbbb"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+				t.Fatalf("invalid records: got=%q, want=%q", got, want)
+			}
+			if got, want := recs.At(2), []byte("CCCC"); !bytes.Equal(got, want) {
+				t.Fatalf("invalid record: got=%q, want=%q", got, want)
+			}
+			_ = recs.Set(2, []byte("cccc"))
+
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected a panic accessing an out of range record")
+				}
+			}()
+			recs.At(3)
+		})
+	}
+}
+
+func TestReadAtEOF(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "eof.txt")
+	err := os.WriteFile(fname, []byte("hello"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	empty := filepath.Join(tmp, "empty.txt")
+	err = os.WriteFile(empty, nil, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	e, err := Open(empty)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer e.Close()
+
+	for _, tc := range []struct {
+		name string
+		r    io.ReaderAt
+		n    int
+		off  int64
+		want int
+		err  error
+	}{
+		{"exact", f, 5, 0, 5, nil},
+		{"short", f, 5, 2, 3, io.EOF},
+		{"at-end", f, 5, 5, 0, io.EOF},
+		{"past-end", f, 5, 10, 0, io.EOF},
+		{"empty", e, 5, 0, 0, io.EOF},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := tc.r.ReadAt(make([]byte, tc.n), tc.off)
+			if n != tc.want || err != tc.err {
+				t.Fatalf("invalid read-at: got=(%d, %v), want=(%d, %v)", n, err, tc.want, tc.err)
+			}
+		})
+	}
+}
+
+func TestPeekDiscard(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "peek.txt")
+	err := os.WriteFile(fname, []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"flat", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(fname, Read, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			p, err := f.Peek(5)
+			if err != nil || string(p) != "hello" {
+				t.Fatalf("invalid peek: got=(%q, %v)", p, err)
+			}
+			n, err := f.Discard(6)
+			if err != nil || n != 6 {
+				t.Fatalf("invalid discard: got=(%d, %v)", n, err)
+			}
+			p, err = f.Peek(10)
+			if err != io.EOF || string(p) != "world" {
+				t.Fatalf("invalid peek: got=(%q, %v)", p, err)
+			}
+			b, err := f.ReadByte()
+			if err != nil || b != 'w' {
+				t.Fatalf("invalid read-byte: got=(%q, %v)", b, err)
+			}
+			n, err = f.Discard(10)
+			if err != io.EOF || n != 4 {
+				t.Fatalf("invalid discard: got=(%d, %v)", n, err)
+			}
+			p, err = f.Peek(1)
+			if err != io.EOF || len(p) != 0 {
+				t.Fatalf("invalid peek: got=(%q, %v)", p, err)
+			}
+
+			_, err = f.Seek(100, io.SeekStart)
+			if err != nil {
+				t.Fatalf("could not seek past the end: %+v", err)
+			}
+			p, err = f.Peek(5)
+			if err != io.EOF || p != nil {
+				t.Fatalf("invalid peek past the end: got=(%q, %v)", p, err)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	tmp := t.TempDir()
+	fname := filepath.Join(tmp, "cached.txt")
+	err := os.WriteFile(fname, []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	c := NewCache(1, 0)
+	defer c.Close()
+
+	f1, err := c.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open cached file: %+v", err)
+	}
+	f2, err := c.Open(filepath.Join(tmp, ".", "cached.txt"))
+	if err != nil {
+		t.Fatalf("could not open cached file: %+v", err)
+	}
+	if &f1.f.data[0] != &f2.f.data[0] {
+		t.Fatalf("mapping was not shared")
+	}
+
+	// Views have their own cursor.
+	p := make([]byte, 5)
+	_, err = f1.Read(p)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	b, err := f2.ReadByte()
+	if err != nil || b != 'h' {
+		t.Fatalf("invalid read-byte: got=(%q, %v)", b, err)
+	}
+
+	// Views write from the mapping, without moving the shared descriptor.
+	out, err := os.Create(filepath.Join(tmp, "out.txt"))
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+	defer out.Close()
+	nw, err := f2.WriteTo(out)
+	if err != nil || nw != 10 {
+		t.Fatalf("invalid write-to: got=(%d, %v)", nw, err)
+	}
+	if got, _ := os.ReadFile(out.Name()); string(got) != "ello world" {
+		t.Fatalf("invalid write-to contents: %q", got)
+	}
+
+	data := f1.f.data
+	err = f1.Close()
+	if err != nil {
+		t.Fatalf("could not close view: %+v", err)
+	}
+	err = f2.Close()
+	if err != nil {
+		t.Fatalf("could not close view: %+v", err)
+	}
+	_, err = f2.ReadAt(p, 0)
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("invalid error reading closed view: %+v", err)
+	}
+
+	// The unused mapping is kept around, and reused.
+	f3, err := c.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open cached file: %+v", err)
+	}
+	if &f3.f.data[0] != &data[0] {
+		t.Fatalf("idle mapping was not reused")
+	}
+	err = f3.Close()
+	if err != nil {
+		t.Fatalf("could not close view: %+v", err)
+	}
+
+	other := filepath.Join(tmp, "other.txt")
+	err = os.WriteFile(other, []byte("bye"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f4, err := c.Open(other)
+	if err != nil {
+		t.Fatalf("could not open cached file: %+v", err)
+	}
+	err = f4.Close()
+	if err != nil {
+		t.Fatalf("could not close view: %+v", err)
+	}
+	c.mu.Lock()
+	n := len(c.entries)
+	c.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("invalid number of cached entries: got=%d, want=1", n)
+	}
+
+	g, err := OpenCached(fname)
+	if err != nil {
+		t.Fatalf("could not open cached file: %+v", err)
+	}
+	err = g.Close()
+	if err != nil {
+		t.Fatalf("could not close view: %+v", err)
+	}
+	if len(defaultCache.entries) != 0 {
+		t.Fatalf("default cache kept an unused mapping")
+	}
+}
+
+func TestMultiFile(t *testing.T) {
+	tmp := t.TempDir()
+	var names []string
+	for i, content := range []string{"hello", "", " world", "!"} {
+		name := filepath.Join(tmp, "segment-"+string(rune('0'+i)))
+		err := os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("could not seed file: %+v", err)
+		}
+		names = append(names, name)
+	}
+
+	m, err := OpenMulti(names...)
+	if err != nil {
+		t.Fatalf("could not open files: %+v", err)
+	}
+	defer m.Close()
+
+	if got, want := m.Len(), 12; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+	if got, want := m.At(6), byte('w'); got != want {
+		t.Fatalf("invalid byte: got=%q, want=%q", got, want)
+	}
+
+	p := make([]byte, 6)
+	n, err := m.ReadAt(p, 3)
+	if err != nil || string(p[:n]) != "lo wor" {
+		t.Fatalf("invalid read-at: got=(%q, %v)", p[:n], err)
+	}
+	n, err = m.ReadAt(p, 9)
+	if err != io.EOF || string(p[:n]) != "ld!" {
+		t.Fatalf("invalid read-at: got=(%q, %v)", p[:n], err)
+	}
+
+	all, err := io.ReadAll(m)
+	if err != nil {
+		t.Fatalf("could not read all: %+v", err)
+	}
+	if got, want := string(all), "hello world!"; got != want {
+		t.Fatalf("invalid content: got=%q, want=%q", got, want)
+	}
+
+	_, err = m.Seek(-1, io.SeekEnd)
+	if err != nil {
+		t.Fatalf("could not seek: %+v", err)
+	}
+	all, err = io.ReadAll(m)
+	if err != nil || string(all) != "!" {
+		t.Fatalf("invalid content: got=(%q, %v)", all, err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "overlay.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	o := NewOverlay(f)
+	for _, w := range []struct {
+		s   string
+		off int64
+	}{
+		{"HE", 0},
+		{"LLO", 2},
+		{"W", 6},
+		{"BYE", 13},
+		{"ORLD", 7},
+	} {
+		_, err = o.WriteAt([]byte(w.s), w.off)
+		if err != nil {
+			t.Fatalf("could not write-at: %+v", err)
+		}
+	}
+	_, err = o.WriteAt([]byte("...."), int64(f.Len()-2))
+	if err != io.ErrShortWrite {
+		t.Fatalf("invalid error: %+v", err)
+	}
+	if got, want := len(o.patches), 3; got != want {
+		t.Fatalf("invalid number of patches: got=%d, want=%d", got, want)
+	}
+
+	got := make([]byte, f.Len())
+	_, err = o.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if want := []byte("HELLO WORLD!\nBYE..."); !bytes.Equal(got, want[:len(got)]) {
+		t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want[:len(got)])
+	}
+
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if want := []byte("hello world!\nbye.\n"); !bytes.Equal(got, want) {
+		t.Fatalf("file observes staged writes")
+	}
+
+	err = o.Flush()
+	if err != nil {
+		t.Fatalf("could not flush: %+v", err)
+	}
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read-at: %+v", err)
+	}
+	if want := []byte("HELLO WORLD!\nBYE..."); !bytes.Equal(got, want[:len(got)]) {
+		t.Fatalf("invalid content after flush:\ngot= %q\nwant=%q\n", got, want[:len(got)])
+	}
+
+	_, err = o.WriteAt([]byte("x"), 0)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	o.Discard()
+	if got, want := o.Len(), 0; got != want {
+		t.Fatalf("invalid staged length: got=%d, want=%d", got, want)
+	}
+}
+
+func TestUnsafePointer(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "pointer.txt")
+	err := os.WriteFile(fname, []byte("hello"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	p := f.UnsafePointer()
+	if p == nil {
+		t.Fatalf("invalid nil pointer")
+	}
+	if got, want := unsafe.Slice((*byte)(p), f.Len()), []byte("hello"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content: got=%q, want=%q", got, want)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if f.UnsafePointer() != nil {
+		t.Fatalf("invalid pointer to closed file")
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "aligned.bin")
+	err := os.WriteFile(fname, make([]byte, 64), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read, WithAlignment(64))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	if got := uintptr(unsafe.Pointer(&f.Bytes()[0])) % 64; got != 0 {
+		t.Fatalf("invalid alignment of mapping: %d", got)
+	}
+	b, err := f.AlignedSection(16, 16, 16)
+	if err != nil || len(b) != 16 {
+		t.Fatalf("invalid aligned section: got=(%d, %v)", len(b), err)
+	}
+	_, err = f.AlignedSection(4, 8, 8)
+	if err == nil {
+		t.Fatalf("expected an error for a misaligned section")
+	}
+	_, err = f.AlignedSection(0, 8, 3)
+	if err == nil {
+		t.Fatalf("expected an error for an invalid alignment")
+	}
+
+	for _, opts := range [][]Option{
+		{WithAlignment(2 * os.Getpagesize())},
+		{WithAlignment(64), withWindow(1 << 16)},
+	} {
+		_, err = OpenFile(fname, Read, opts...)
+		if err == nil {
+			t.Fatalf("expected an error for a unsatisfiable alignment")
+		}
+	}
+}
+
+func TestOpenZip(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "archive.zip")
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, e := range []struct {
+		name   string
+		method uint16
+	}{
+		{"stored.txt", zip.Store},
+		{"deflated.txt", zip.Deflate},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: e.method})
+		if err != nil {
+			t.Fatalf("could not create zip entry: %+v", err)
+		}
+		_, err = w.Write([]byte("hello " + e.name))
+		if err != nil {
+			t.Fatalf("could not write zip entry: %+v", err)
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		t.Fatalf("could not close zip writer: %+v", err)
+	}
+	err = os.WriteFile(fname, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	z, err := OpenZip(fname)
+	if err != nil {
+		t.Fatalf("could not open zip archive: %+v", err)
+	}
+	defer z.Close()
+
+	if got, want := len(z.File), 2; got != want {
+		t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+	}
+	for _, e := range z.File {
+		rc, err := e.Open()
+		if err != nil {
+			t.Fatalf("could not open zip entry: %+v", err)
+		}
+		got, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil || string(got) != "hello "+e.Name {
+			t.Fatalf("invalid zip entry: got=(%q, %v)", got, err)
+		}
+	}
+
+	sr, err := z.StoredEntry(z.File[0])
+	if err != nil {
+		t.Fatalf("could not open stored entry: %+v", err)
+	}
+	got := make([]byte, 5)
+	_, err = sr.ReadAt(got, 6)
+	if err != nil || string(got) != "store" {
+		t.Fatalf("invalid stored entry: got=(%q, %v)", got, err)
+	}
+	_, err = z.StoredEntry(z.File[1])
+	if err == nil {
+		t.Fatalf("expected an error for a compressed entry")
+	}
+}
+
+func TestSectionReader(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "section.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f.SectionReader(6, 6))
+	if err != nil || string(got) != "world!" {
+		t.Fatalf("invalid section: got=(%q, %v)", got, err)
+	}
+	got, err = io.ReadAll(io.NewSectionReader(f.ReaderAt(), 13, f.Size()))
+	if err != nil || string(got) != "bye.\n" {
+		t.Fatalf("invalid section: got=(%q, %v)", got, err)
+	}
+	b, err := f.ReadByte()
+	if err != nil || b != 'h' {
+		t.Fatalf("invalid read-byte: got=(%q, %v)", b, err)
+	}
+}
+
+func TestStreamReader(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "stream.bin")
+	content := make([]byte, 3*streamSpan+10)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	err := os.WriteFile(fname, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	r := f.StreamReader()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not read stream: %+v", err)
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("could not close stream: %+v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("invalid stream content")
+	}
+	if f.c != 0 {
+		t.Fatalf("stream moved the cursor of the file")
+	}
+}
+
+func TestParallelSync(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "parallel.bin")
+	page := os.Getpagesize()
+	err := os.WriteFile(fname, make([]byte, 10*page+10), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, WithParallelSync(4))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	copy(f.data, "hello")
+	copy(f.data[len(f.data)-3:], "bye")
+
+	var (
+		mu    sync.Mutex
+		total int
+	)
+	err = f.syncParallel(func(b []byte) error {
+		if uintptr(unsafe.Pointer(&b[0]))%uintptr(page) != 0 {
+			t.Errorf("part is not page-aligned")
+		}
+		mu.Lock()
+		total += len(b)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	if total != f.Len() {
+		t.Fatalf("invalid synced length: got=%d, want=%d", total, f.Len())
+	}
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte("hello")) || !bytes.HasSuffix(raw, []byte("bye")) {
+		t.Fatalf("invalid content")
+	}
+}
+
+func TestForkOptions(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "fork.txt")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"dont-fork", []Option{WithDontFork()}},
+		{"wipe-on-fork", []Option{WithWipeOnFork()}},
+		{"wipe-on-fork-window", []Option{WithWipeOnFork(), withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(fname, Read, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+			defer f.Close()
+
+			if got, want := f.At(0), byte('h'); got != want {
+				t.Fatalf("invalid byte: got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
+
+func TestLockRange(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "mlock.bin")
+	page := os.Getpagesize()
+	err := os.WriteFile(fname, make([]byte, 4*page), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	err = f.LockRange(10, int64(page))
+	if err != nil {
+		t.Skipf("could not lock range: %+v", err)
+	}
+	err = f.UnlockRange(10, int64(page))
+	if err != nil {
+		t.Fatalf("could not unlock range: %+v", err)
+	}
+
+	err = f.LockRangeOnFault(0, int64(f.Len()))
+	switch {
+	case err == nil:
+		err = f.UnlockRange(0, int64(f.Len()))
+		if err != nil {
+			t.Fatalf("could not unlock range: %+v", err)
+		}
+	case !errors.Is(err, errUnsupported):
+		t.Logf("could not lock range on fault: %+v", err)
+	}
+
+	err = f.LockRange(int64(f.Len()), 1)
+	if err == nil {
+		t.Fatalf("expected an error locking an invalid range")
+	}
+}
+
+func TestStats(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "stats.bin")
+	err := os.WriteFile(fname, make([]byte, 8192), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	var synced atomic.Int64
+	SetSyncHook(func(d time.Duration) { synced.Add(1) })
+	defer SetSyncHook(nil)
+
+	before := ReadStats()
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	opened := ReadStats()
+	if got, want := opened.Mappings-before.Mappings, int64(1); got != want {
+		t.Fatalf("invalid mappings delta: got=%d, want=%d", got, want)
+	}
+	if got, want := opened.MappedBytes-before.MappedBytes, int64(8192); got != want {
+		t.Fatalf("invalid mapped bytes delta: got=%d, want=%d", got, want)
+	}
+
+	err = f.Remap()
+	if err != nil {
+		t.Fatalf("could not remap file: %+v", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync file: %+v", err)
+	}
+	err = f.SyncContext(context.Background())
+	if err != nil {
+		t.Fatalf("could not sync file with context: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	after := ReadStats()
+	if got, want := after.Remaps-before.Remaps, int64(1); got != want {
+		t.Fatalf("invalid remaps delta: got=%d, want=%d", got, want)
+	}
+	if got := after.Syncs - before.Syncs; got < 2 {
+		t.Fatalf("invalid syncs delta: got=%d, want>=2", got)
+	}
+	if synced.Load() < 2 {
+		t.Fatalf("sync hook was not called")
+	}
+	if got, want := after.MappedBytes, before.MappedBytes; got != want {
+		t.Fatalf("invalid mapped bytes after close: got=%d, want=%d", got, want)
+	}
+}
+
+func TestPopulate(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "populate.bin")
+	want := bytes.Repeat([]byte("populate"), 4096)
+	err := os.WriteFile(fname, want, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read, WithPopulate())
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	got := make([]byte, len(want))
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("invalid populated contents")
+	}
+}
+
+func TestWithSize(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "size.bin")
+	err := os.WriteFile(fname, []byte("hello world!\nbye.\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read, WithSize(5))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.Len(), 5; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := string(got), "hello"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+
+	_, err = OpenFile(fname, Read, WithSize(1<<20))
+	if err == nil {
+		t.Fatalf("expected an error mapping past the end of the file")
+	}
+}
+
+func TestEmptyGrow(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "empty.bin")
+	err := os.WriteFile(fname, nil, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	p := make([]byte, 5)
+	_, err = f.ReadAt(p, 0)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("invalid error reading empty file: %+v", err)
+	}
+
+	err = os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not grow file: %+v", err)
+	}
+
+	_, err = f.ReadAt(p, 0)
+	if err != nil {
+		t.Fatalf("could not read grown file: %+v", err)
+	}
+	if got, want := string(p), "hello"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+	if got, want := f.Len(), 13; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+
+	// Files mapped empty are mapped once by concurrent readers, which
+	// either wait for the mapping, or observe it whole.
+	for _, read := range []func(f *File) error{
+		func(f *File) error {
+			_, err := f.ReadAt(make([]byte, 5), 0)
+			return err
+		},
+		func(f *File) error {
+			if b := f.At(1); b != 'e' {
+				return fmt.Errorf("invalid byte %q", b)
+			}
+			return nil
+		},
+	} {
+		err = os.WriteFile(fname, nil, 0644)
+		if err != nil {
+			t.Fatalf("could not empty file: %+v", err)
+		}
+		g, err := Open(fname)
+		if err != nil {
+			t.Fatalf("could not mmap file: %+v", err)
+		}
+		err = os.WriteFile(fname, []byte("hello world!\n"), 0644)
+		if err != nil {
+			t.Fatalf("could not grow file: %+v", err)
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = read(g)
+			}(i)
+		}
+		wg.Wait()
+		g.Close()
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("could not read grown file concurrently: %+v", err)
+			}
+		}
+	}
+}
+
+func TestOpenFlags(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "created.bin")
+
+	f, err := OpenFile(fname, Read|Write, WithCreate(0600), WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+	_, err = f.WriteAt([]byte("hello"), 0)
+	if err != nil {
+		t.Fatalf("could not write file: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(fname)
+		if err != nil {
+			t.Fatalf("could not stat file: %+v", err)
+		}
+		if got, want := fi.Mode().Perm(), os.FileMode(0600); got != want {
+			t.Fatalf("invalid permissions: got=%v, want=%v", got, want)
+		}
+	}
+
+	_, err = OpenFile(fname, Read|Write, WithExclusive())
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("invalid error opening existing file exclusively: %+v", err)
+	}
+
+	_, err = OpenFile(fname, Read, WithTruncate())
+	if !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("invalid error truncating read-only file: %+v", err)
+	}
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatalf("could not stat file: %+v", err)
+	}
+	if got, want := fi.Size(), int64(5); got != want {
+		t.Fatalf("read-only file truncated: got=%d, want=%d", got, want)
+	}
+
+	f, err = OpenFile(fname, Read|Write, WithTruncate())
+	if err != nil {
+		t.Fatalf("could not truncate file: %+v", err)
+	}
+	if got, want := f.Len(), 0; got != want {
+		t.Fatalf("invalid length after truncation: got=%d, want=%d", got, want)
+	}
+	_ = f.Close()
+
+	link := filepath.Join(dir, "link.bin")
+	err = os.Symlink(fname, link)
+	if err != nil {
+		t.Skipf("could not create symlink: %+v", err)
+	}
+	_, err = OpenFile(link, Read, WithNoFollow())
+	if err == nil {
+		t.Fatalf("expected an error opening a symlink with WithNoFollow")
+	}
+	f, err = OpenFile(link, Read)
+	if err != nil {
+		t.Fatalf("could not open symlink: %+v", err)
+	}
+	_ = f.Close()
+}
+
+func TestOpenFileAt(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "at.bin"), []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		t.Fatalf("could not open directory: %+v", err)
+	}
+	defer d.Close()
+
+	f, err := OpenFileAt(d, "at.bin", Read)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	got := make([]byte, f.Len())
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := string(got), "hello world"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+	_ = f.Close()
+
+	f, err = OpenFileAt(d, "new.bin", Read|Write, WithExclusive(), WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+	_, err = f.WriteAt([]byte("created"), 0)
+	if err != nil {
+		t.Fatalf("could not write file: %+v", err)
+	}
+	_ = f.Close()
+
+	raw, err := os.ReadFile(filepath.Join(dir, "new.bin"))
+	if err != nil {
+		t.Fatalf("could not read created file: %+v", err)
+	}
+	if got, want := string(raw), "created"; got != want {
+		t.Fatalf("invalid created contents: got=%q, want=%q", got, want)
+	}
+
+	_, err = OpenFileAt(d, "missing.bin", Read)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("invalid error opening missing file: %+v", err)
+	}
+	_, err = OpenFileAt(nil, "at.bin", Read)
+	if err == nil {
+		t.Fatalf("expected an error with a nil directory")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "readonly.bin")
+	err := os.WriteFile(fname, []byte("read only"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	r, err := OpenReadOnly(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer r.Close()
+
+	if got, want := r.Len(), 9; got != want {
+		t.Fatalf("invalid length: got=%d, want=%d", got, want)
+	}
+	if got, want := r.At(5), byte('o'); got != want {
+		t.Fatalf("invalid byte: got=%q, want=%q", got, want)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := string(got), "read only"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+	if _, ok := interface{}(r).(io.Writer); ok {
+		t.Fatalf("ReadOnly must not implement io.Writer")
+	}
+}
+
+func TestAtOKRange(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "range.bin")
+	err := os.WriteFile(fname, []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"flat", nil},
+		{"windowed", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(fname, Read, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not mmap file: %+v", err)
+			}
+
+			b, ok := f.AtOK(3)
+			if !ok || b != '3' {
+				t.Fatalf("invalid AtOK(3): got=(%q, %v), want=('3', true)", b, ok)
+			}
+			for _, i := range []int{-1, 10} {
+				if _, ok := f.AtOK(i); ok {
+					t.Fatalf("AtOK(%d) should fail", i)
+				}
+			}
+
+			got, err := f.Range(2, 7)
+			if err != nil {
+				t.Fatalf("could not get range: %+v", err)
+			}
+			if got, want := string(got), "23456"; got != want {
+				t.Fatalf("invalid range: got=%q, want=%q", got, want)
+			}
+			for _, r := range [][2]int{{-1, 2}, {5, 4}, {0, 11}} {
+				_, err = f.Range(r[0], r[1])
+				if err == nil {
+					t.Fatalf("Range(%d, %d) should fail", r[0], r[1])
+				}
+			}
+
+			_ = f.Close()
+			if _, ok := f.AtOK(3); ok {
+				t.Fatalf("AtOK should fail on a closed file")
+			}
+			_, err = f.Range(0, 1)
+			if !errors.Is(err, errClosed) {
+				t.Fatalf("invalid error on a closed file: %+v", err)
+			}
+		})
+	}
+}
+
+func TestCloseEmpty(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "empty.bin")
+	err := os.WriteFile(fname, nil, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if err := f.fd.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("descriptor of empty file was left open: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("closing twice should be a no-op: %+v", err)
+	}
+}
+
+func TestCloseWithSync(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "close-sync.bin")
+	err := os.WriteFile(fname, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	_, err = f.WriteAt([]byte("synced"), 0)
+	if err != nil {
+		t.Fatalf("could not write file: %+v", err)
+	}
+	before := ReadStats().Syncs
+	err = f.CloseWithSync()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if ReadStats().Syncs == before {
+		t.Fatalf("CloseWithSync did not sync the file")
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := string(raw[:6]), "synced"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	if err := joinErrors(nil, nil); err != nil {
+		t.Fatalf("invalid join of nil errors: %+v", err)
+	}
+	if err := joinErrors(nil, errBadFD); err != errBadFD {
+		t.Fatalf("a single error should be returned as is: %+v", err)
+	}
+	pe := &os.PathError{Op: "close", Path: "x", Err: os.ErrClosed}
+	err := joinErrors(errBadFD, pe)
+	if !errors.Is(err, errBadFD) || !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("joined errors should match both errors: %+v", err)
+	}
+	var got *os.PathError
+	if !errors.As(err, &got) || got != pe {
+		t.Fatalf("joined errors should match *os.PathError: %+v", err)
+	}
+	if got, want := err.Error(), errBadFD.Error()+"\n"+pe.Error(); got != want {
+		t.Fatalf("invalid message: got=%q, want=%q", got, want)
+	}
+}
+
+func TestClone(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "clone.bin")
+	err := os.WriteFile(fname, []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	_, err = f.Seek(5, io.SeekStart)
+	if err != nil {
+		t.Fatalf("could not seek: %+v", err)
+	}
+
+	var wg sync.WaitGroup
+	clones := make([]*File, 4)
+	for i := range clones {
+		clones[i] = f.Clone()
+		if clones[i] == nil {
+			t.Fatalf("could not clone file")
+		}
+	}
+	if err := f.Remap(); !errors.Is(err, errShared) {
+		t.Fatalf("invalid error remapping a cloned file: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	errs := make([]error, len(clones))
+	for i, c := range clones {
+		wg.Add(1)
+		go func(i int, c *File) {
+			defer wg.Done()
+			got, err := io.ReadAll(c)
+			if err == nil && string(got) != "0123456789" {
+				err = fmt.Errorf("invalid contents: %q", got)
+			}
+			if e := c.Close(); err == nil {
+				err = e
+			}
+			errs[i] = err
+		}(i, c)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("clone %d: %+v", i, err)
+		}
+	}
+	if err := f.fd.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("descriptor was left open after closing all clones: %+v", err)
+	}
+	if c := f.Clone(); c != nil {
+		t.Fatalf("cloning a closed file should return nil")
+	}
+}
+
+func TestCloneConcurrent(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "clone.bin")
+	err := os.WriteFile(fname, []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+
+	var wg sync.WaitGroup
+	clones := make([]*File, 8)
+	for i := range clones {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clones[i] = f.Clone()
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range clones {
+		if c == nil {
+			t.Fatalf("could not clone file")
+		}
+		err = c.Close()
+		if err != nil {
+			t.Fatalf("could not close clone: %+v", err)
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if err := f.fd.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("descriptor was left open after closing all clones: %+v", err)
+	}
+}
+
+func TestCloneFlag(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "clone.bin")
+	err := os.WriteFile(fname, []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	r, err := f.CloneFlag(Read)
+	if err != nil {
+		t.Fatalf("could not clone file: %+v", err)
+	}
+	defer r.Close()
+	if _, err := r.WriteAt([]byte("a"), 0); err == nil {
+		t.Fatalf("read-only clone should not be writable")
+	}
+	_, err = f.WriteAt([]byte("a"), 0)
+	if err != nil {
+		t.Fatalf("could not write file: %+v", err)
+	}
+	if got, want := r.At(0), byte('a'); got != want {
+		t.Fatalf("write not visible through clone: got=%q, want=%q", got, want)
+	}
+
+	w, err := r.CloneFlag(Read | Write)
+	if !errors.Is(err, os.ErrPermission) || w != nil {
+		t.Fatalf("cloning a read-only clone for writing should fail: %+v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "reopen.bin")
+	err := os.WriteFile(fname, []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	r, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer r.Close()
+	if got, want := r.Name(), fname; got != want {
+		t.Fatalf("invalid name: got=%q, want=%q", got, want)
+	}
+
+	w, err := r.Reopen(Read | Write)
+	if err != nil {
+		t.Fatalf("could not reopen file: %+v", err)
+	}
+	_, err = w.WriteAt([]byte("ab"), 0)
+	if err != nil {
+		t.Fatalf("could not write reopened file: %+v", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("could not close reopened file: %+v", err)
+	}
+
+	if got, want := r.At(0), byte('a'); got != want {
+		t.Fatalf("write through reopened file not visible: got=%q, want=%q", got, want)
+	}
+
+	// Snapshots reopen the file itself, not a snapshot of it.
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatalf("could not snapshot file: %+v", err)
+	}
+	defer snap.Close()
+	w, err = snap.Reopen(Read | Write)
+	if err != nil {
+		t.Fatalf("could not reopen snapshot: %+v", err)
+	}
+	_, err = w.WriteAt([]byte("cd"), 2)
+	if err == nil {
+		err = w.Sync()
+	}
+	if err != nil {
+		t.Fatalf("could not write reopened snapshot: %+v", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("could not close reopened snapshot: %+v", err)
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := string(raw), "abcd456789"; got != want {
+		t.Fatalf("write through reopened snapshot not carried to the file: got=%q, want=%q", got, want)
+	}
+
+	_ = r.Close()
+	_, err = r.Reopen(Read)
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("invalid error reopening a closed file: %+v", err)
+	}
+}
+
+func TestDump(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "dump.bin")
+	raw := append([]byte("hello world, this is a hexdump test\n"), make([]byte, 64)...)
+	raw = append(raw, "tail\x01\x02"...)
+	err := os.WriteFile(fname, raw, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	err = f.Dump(&buf, 0, f.Size())
+	if err != nil {
+		t.Fatalf("could not dump file: %+v", err)
+	}
+	want := `00000000  68 65 6c 6c 6f 20 77 6f  72 6c 64 2c 20 74 68 69  |hello world, thi|
+00000010  73 20 69 73 20 61 20 68  65 78 64 75 6d 70 20 74  |s is a hexdump t|
+00000020  65 73 74 0a 00 00 00 00  00 00 00 00 00 00 00 00  |est.............|
+00000030  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|
+*
+00000060  00 00 00 00 74 61 69 6c  01 02                    |....tail..|
+0000006a
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("invalid dump:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	err = f.Dump(&buf, 100, 10)
+	if err == nil {
+		t.Fatalf("expected an error dumping an invalid range")
+	}
+
+	if got, want := f.String(), fmt.Sprintf("mmap.File(%q, 106 bytes, r, at ", fname); !strings.HasPrefix(got, want) {
+		t.Fatalf("invalid summary: got=%q, want prefix %q", got, want)
+	}
+	_ = f.Close()
+	if got, want := f.String(), fmt.Sprintf("mmap.File(%q, 0 bytes, r, closed)", fname); got != want {
+		t.Fatalf("invalid summary: got=%q, want=%q", got, want)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "faults.bin")
+	err := os.WriteFile(fname, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	errFault := errors.New("injected fault")
+	inject := func(calls ...Syscall) Option {
+		return WithFaults(func(call Syscall) error {
+			for _, c := range calls {
+				if c == call {
+					return errFault
+				}
+			}
+			return nil
+		})
+	}
+
+	_, err = OpenFile(fname, Read, inject(SyscallMmap, SyscallCreateFileMapping))
+	if !errors.Is(err, errFault) {
+		t.Fatalf("invalid error with an injected mmap fault: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, inject(SyscallMsync))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	if err := f.Sync(); !errors.Is(err, errFault) {
+		t.Fatalf("invalid error with an injected msync fault: %+v", err)
+	}
+	if err := f.SyncRange(0, 10); !errors.Is(err, errFault) {
+		t.Fatalf("invalid error with an injected msync fault: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	f, err = OpenFile(fname, Read, inject(SyscallMunmap))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	// The mapping is leaked, as munmap was skipped.
+	if err := f.Close(); !errors.Is(err, errFault) {
+		t.Fatalf("invalid error with an injected munmap fault: %+v", err)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "secret.key")
+	want := []byte("correct horse battery staple")
+	err := os.WriteFile(fname, want, 0600)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	s, err := OpenSecret(fname)
+	if err != nil {
+		t.Skipf("could not open secret: %+v", err)
+	}
+	if got := s.Bytes(); !bytes.Equal(got, want) {
+		t.Fatalf("invalid secret: got=%q, want=%q", got, want)
+	}
+	data := s.f.data
+	wiped := make(chan bool, 1)
+	s.f.cfg.faults = func(call Syscall) error {
+		if call == SyscallMunmap {
+			wiped <- bytes.Equal(data, make([]byte, len(data)))
+		}
+		return nil
+	}
+	err = s.Close()
+	if err != nil {
+		t.Fatalf("could not close secret: %+v", err)
+	}
+	if !<-wiped {
+		t.Fatalf("secret was not wiped before unmapping")
+	}
+	if s.Bytes() != nil {
+		t.Fatalf("closed secret should have no contents")
+	}
+
+	got, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("wiping the secret altered the file: got=%q", got)
+	}
+}
+
+func TestByteOrderView(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "order.bin")
+	err := os.WriteFile(fname, make([]byte, 16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	be := NewByteOrderView(f, binary.BigEndian)
+	le := NewByteOrderView(f, binary.LittleEndian)
+	err = be.PutUint32(0, 0x01020304)
+	if err != nil {
+		t.Fatalf("could not put uint32: %+v", err)
+	}
+	if got, want := f.Bytes()[:4], []byte{1, 2, 3, 4}; !bytes.Equal(got, want) {
+		t.Fatalf("invalid big-endian encoding: got=%x, want=%x", got, want)
+	}
+	if got, err := le.Uint32(0); err != nil || got != 0x04030201 {
+		t.Fatalf("invalid little-endian uint32: %#x, %+v", got, err)
+	}
+	if got, err := be.Uint16(2); err != nil || got != 0x0304 {
+		t.Fatalf("invalid big-endian uint16: %#x, %+v", got, err)
+	}
+
+	err = le.PutFloat64(8, 3.5)
+	if err != nil {
+		t.Fatalf("could not put float64: %+v", err)
+	}
+	if got, err := le.Float64(8); err != nil || got != 3.5 {
+		t.Fatalf("invalid float64: %v, %+v", got, err)
+	}
+	if got, err := be.Uint64(8); err != nil || got == 0 {
+		t.Fatalf("invalid big-endian uint64: %#x, %+v", got, err)
+	}
+
+	_, err = be.Uint64(12)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("invalid error reading past the end: %+v", err)
+	}
+}
+
+func TestLengthPrefixed(t *testing.T) {
+	var buf []byte
+	want := []string{"hello", "", strings.Repeat("x", 300), "bye"}
+	for _, rec := range want {
+		buf = AppendLengthPrefixed(buf, []byte(rec))
+	}
+	fname := filepath.Join(t.TempDir(), "records.bin")
+	err := os.WriteFile(fname, buf, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, opts := range [][]Option{nil, {withWindow(1 << 16)}} {
+		f, err := OpenFile(fname, Read, opts...)
+		if err != nil {
+			t.Fatalf("could not mmap file: %+v", err)
+		}
+
+		if v, n := f.ReadUvarintAt(6); v != 0 || n != 1 {
+			t.Fatalf("invalid uvarint: %d, %d", v, n)
+		}
+		if v, n := f.ReadUvarintAt(7); v != 300 || n != 2 {
+			t.Fatalf("invalid uvarint: %d, %d", v, n)
+		}
+		if _, n := f.ReadUvarintAt(int64(len(buf))); n != 0 {
+			t.Fatalf("invalid uvarint length at end of file: %d", n)
+		}
+
+		var got []string
+		off := int64(0)
+		for {
+			rec, next, err := f.NextLengthPrefixed(off)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("could not read record at %d: %+v", off, err)
+			}
+			got = append(got, string(rec))
+			off = next
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid records: got=%q, want=%q", got, want)
+		}
+		_ = f.Close()
+	}
+
+	fname = filepath.Join(t.TempDir(), "short.bin")
+	err = os.WriteFile(fname, buf[:4], 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+	_, _, err = f.NextLengthPrefixed(0)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("invalid error reading truncated record: %+v", err)
+	}
+}
+
+func TestValidUTF8(t *testing.T) {
+	// Runes of 3 bytes straddle the chunks validated at once.
+	text := []byte(strings.Repeat("€", 400000))
+	fname := filepath.Join(t.TempDir(), "text.txt")
+	err := os.WriteFile(fname, text, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, opts := range [][]Option{nil, {withWindow(1 << 16)}} {
+		f, err := OpenFile(fname, Read|Write, opts...)
+		if err != nil {
+			t.Fatalf("could not mmap file: %+v", err)
+		}
+
+		if !f.ValidUTF8() {
+			t.Fatalf("valid text reported invalid")
+		}
+		if ok, err := f.ValidUTF8Range(3, 3000); err != nil || !ok {
+			t.Fatalf("valid range reported invalid: %+v", err)
+		}
+		if ok, err := f.ValidUTF8Range(1, 3); err != nil || ok {
+			t.Fatalf("range cutting runes reported valid: %+v", err)
+		}
+		if _, err := f.ValidUTF8Range(0, int64(len(text))+1); err == nil {
+			t.Fatalf("expected an error validating past the end of the file")
+		}
+
+		_, err = f.WriteAt([]byte{0xff}, int64(len(text))-2)
+		if err != nil {
+			t.Fatalf("could not corrupt text: %+v", err)
+		}
+		if f.ValidUTF8() {
+			t.Fatalf("invalid text reported valid")
+		}
+		_, err = f.WriteAt([]byte("€"), int64(len(text))-3)
+		if err != nil {
+			t.Fatalf("could not restore text: %+v", err)
+		}
+		_ = f.Close()
+	}
+}
+
+func TestProgress(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "progress.bin")
+	const size = 40 << 20
+	err := os.WriteFile(fname, nil, 0644)
+	if err == nil {
+		err = os.Truncate(fname, size)
+	}
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	var (
+		calls []int64
+		stop  error
+	)
+	f, err := OpenFile(fname, Read|Write, WithProgress(func(done, total int64) error {
+		if total != size {
+			t.Errorf("invalid total: got=%d, want=%d", total, size)
+		}
+		calls = append(calls, done)
+		return stop
+	}))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	if got, want := calls, []int64{0, 16 << 20, 32 << 20, size}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid sync progress: got=%v, want=%v", got, want)
+	}
+
+	calls = nil
+	err = f.Prefetch(0, size)
+	if err != nil {
+		t.Fatalf("could not prefetch: %+v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("invalid prefetch progress: %v", calls)
+	}
+
+	calls = nil
+	err = f.CloneTo(filepath.Join(dir, "clone.bin"))
+	if err != nil {
+		t.Fatalf("could not clone: %+v", err)
+	}
+	if len(calls) == 0 || calls[len(calls)-1] != size {
+		t.Fatalf("invalid clone progress: %v", calls)
+	}
+
+	calls = nil
+	stop = errors.New("canceled")
+	err = f.SyncContext(context.Background())
+	if !errors.Is(err, stop) {
+		t.Fatalf("invalid error of canceled sync: %+v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("canceled sync went on: %v", calls)
+	}
+}
+
+func TestPushPopPos(t *testing.T) {
+	f, err := Open("mmap_test.go")
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	p := make([]byte, 5)
+	_, err = io.ReadFull(f, p)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	f.PushPos()
+	f.PushPos()
+	_, err = io.ReadFull(f, p)
+	if err != nil {
+		t.Fatalf("could not read ahead: %+v", err)
+	}
+	if got, want := f.Pos(), int64(10); got != want {
+		t.Fatalf("invalid position: got=%d, want=%d", got, want)
+	}
+	for i := 0; i < 2; i++ {
+		err = f.PopPos()
+		if err != nil {
+			t.Fatalf("could not pop position: %+v", err)
+		}
+		if got, want := f.Pos(), int64(5); got != want {
+			t.Fatalf("invalid restored position: got=%d, want=%d", got, want)
+		}
+		_, _ = f.Seek(0, io.SeekStart)
+	}
+	err = f.PopPos()
+	if err == nil {
+		t.Fatalf("expected an error popping an empty stack")
+	}
+}
+
+func TestDelta(t *testing.T) {
+	dir := t.TempDir()
+	page := os.Getpagesize()
+	orig := bytes.Repeat([]byte("0123456789abcdef"), 4*page/16)
+	cur := append([]byte(nil), orig...)
+	cur[10] = 'x'
+	cur[2*page+5] = 'y'
+	cur[3*page] = 'z'
+	cur = append(cur, "tail"...)
+
+	oname := filepath.Join(dir, "old.bin")
+	nname := filepath.Join(dir, "new.bin")
+	for name, data := range map[string][]byte{oname: orig, nname: cur} {
+		err := os.WriteFile(name, data, 0644)
+		if err != nil {
+			t.Fatalf("could not seed file: %+v", err)
+		}
+	}
+
+	old, err := OpenFile(oname, Read|Write, WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not mmap old file: %+v", err)
+	}
+	defer old.Close()
+	f, err := OpenFile(nname, Read, withWindow(1<<16))
+	if err != nil {
+		t.Fatalf("could not mmap new file: %+v", err)
+	}
+	defer f.Close()
+
+	patches, err := f.DeltaTo(old)
+	if err != nil {
+		t.Fatalf("could not compute delta: %+v", err)
+	}
+	if got, want := len(patches), 2; got != want {
+		t.Fatalf("invalid number of patches: got=%d, want=%d", got, want)
+	}
+	if got, want := patches[1].Off, int64(2*page); got != want {
+		t.Fatalf("invalid offset of merged patch: got=%d, want=%d", got, want)
+	}
+
+	n, err := old.ApplyPatches(patches)
+	if err != nil {
+		t.Fatalf("could not apply patches: %+v", err)
+	}
+	if got, want := n, int64(3*page+4); got != want {
+		t.Fatalf("invalid number of patched bytes: got=%d, want=%d", got, want)
+	}
+	if !bytes.Equal(old.Bytes(), cur) {
+		t.Fatalf("invalid patched contents")
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "patched.bin")
+	orig := bytes.Repeat([]byte("."), 3*os.Getpagesize())
+	err := os.WriteFile(fname, orig, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	for _, ps := range [][]Patch{
+		{{Off: 0, Data: []byte("abc")}, {Off: 2, Data: []byte("x")}},
+		{{Off: 0, Data: []byte("abc")}, {Off: -1, Data: []byte("x")}},
+		{{Off: 0, Data: []byte("abc")}, {Off: int64(len(orig)) - 1, Data: []byte("xy")}},
+	} {
+		_, err = f.ApplyPatches(ps)
+		if err == nil {
+			t.Fatalf("expected an error applying %v", ps)
+		}
+		if !bytes.Equal(f.Bytes(), orig) {
+			t.Fatalf("invalid patches were partially applied")
+		}
+	}
+
+	ps := []Patch{
+		{Off: int64(len(orig)) - 3, Data: []byte("end")},
+		{Off: 1, Data: []byte("bc")},
+		{Off: 0, Data: []byte("a")},
+	}
+	n, err := f.ApplyPatches(ps)
+	if err != nil || n != 6 {
+		t.Fatalf("could not apply patches: %d, %+v", n, err)
+	}
+	err = f.SyncPatches(ps)
+	if err != nil {
+		t.Fatalf("could not sync patches: %+v", err)
+	}
+	got, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if !bytes.HasPrefix(got, []byte("abc.")) || !bytes.HasSuffix(got, []byte(".end")) {
+		t.Fatalf("invalid patched contents")
+	}
+
+	// Closed files are neither patched nor grown.
+	g, err := OpenFile(fname, Read|Write, WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	err = g.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	_, err = g.ApplyPatches([]Patch{{Off: int64(len(orig)), Data: []byte("x")}, {Off: -1}})
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("invalid error patching a closed file: %+v", err)
+	}
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatalf("could not stat file: %+v", err)
+	}
+	if fi.Size() != int64(len(orig)) {
+		t.Fatalf("closed file was grown to %d bytes", fi.Size())
+	}
+}
+
+func TestFallback(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "fallback.bin")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	fail := func(call Syscall) error {
+		if call == SyscallMmap || call == SyscallCreateFileMapping {
+			return errors.New("no mapping")
+		}
+		return nil
+	}
+
+	_, err = OpenFile(fname, Read|Write, WithFaults(fail))
+	if err == nil {
+		t.Fatalf("expected an error opening without fallback")
+	}
+
+	f, err := OpenFile(fname, Read|Write, WithFaults(fail), WithFallback())
+	if err != nil {
+		t.Fatalf("could not open file with fallback: %+v", err)
+	}
+	defer f.Close()
+
+	if f.Mapped() {
+		t.Fatalf("file reported as mapped")
+	}
+	if f.Bytes() != nil {
+		t.Fatalf("unmapped file has bytes")
+	}
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = f.Zero(6, 5)
+	if err != nil {
+		t.Fatalf("could not zero: %+v", err)
+	}
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	if want := "HELLO \x00\x00\x00\x00\x00!\n"; string(got) != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil || !bytes.Equal(raw, got) {
+		t.Fatalf("invalid file contents: %q, %+v", raw, err)
+	}
+}
+
+func TestRemotePolicy(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "remote.bin")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	fd, err := os.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	fstype := remoteFS(fd)
+	fd.Close()
+	if fstype != "" {
+		t.Skipf("temporary directory is on a %s filesystem", fstype)
+	}
+
+	for _, policy := range []RemotePolicy{RemoteAllow, RemoteWarn, RemoteFallback} {
+		var warned bool
+		warn := func(filename, fstype string) { warned = true }
+		f, err := OpenFile(fname, Read, WithRemotePolicy(policy, warn))
+		if err != nil {
+			t.Fatalf("could not open file with policy %d: %+v", policy, err)
+		}
+		if warned {
+			t.Fatalf("local file reported as remote")
+		}
+		if !f.Mapped() {
+			t.Fatalf("local file not mapped with policy %d", policy)
+		}
+		f.Close()
+	}
+
+	// Files found on a remote filesystem are read through their descriptor.
+	forced := func(o *options) { o.direct = true }
+	f, err := OpenFile(fname, Read|Write, forced)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+	if f.Mapped() {
+		t.Fatalf("file reported as mapped")
+	}
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	got := make([]byte, 5)
+	_, err = f.ReadAt(got, 0)
+	if err != nil || string(got) != "HELLO" {
+		t.Fatalf("invalid contents: got=%q, err=%+v", got, err)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "changed.txt")
+	err := os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	changed, err := f.Changed()
+	if err != nil || changed {
+		t.Fatalf("fresh file reported as changed: %v, %+v", changed, err)
+	}
+
+	err = os.WriteFile(fname, []byte("hello world, again!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not modify file: %+v", err)
+	}
+	fi, err := f.StatLive()
+	if err != nil {
+		t.Fatalf("could not stat file: %+v", err)
+	}
+	if got, want := fi.Size(), int64(20); got != want {
+		t.Fatalf("invalid live size: got=%d, want=%d", got, want)
+	}
+	if fi, _ := f.Stat(); fi.Size() != 13 {
+		t.Fatalf("invalid size at open: got=%d, want=13", fi.Size())
+	}
+	changed, err = f.Changed()
+	if err != nil || !changed {
+		t.Fatalf("modified file not reported as changed: %v, %+v", changed, err)
+	}
+
+	err = f.Remap()
+	if err != nil {
+		t.Fatalf("could not remap: %+v", err)
+	}
+	changed, err = f.Changed()
+	if err != nil || changed {
+		t.Fatalf("remapped file reported as changed: %v, %+v", changed, err)
+	}
+
+	// Replace the file by renaming another one over it.
+	tmp := filepath.Join(dir, "changed.tmp")
+	err = os.WriteFile(tmp, []byte("hello world, again!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed replacement: %+v", err)
+	}
+	err = os.Chtimes(tmp, f.fi.ModTime(), f.fi.ModTime())
+	if err != nil {
+		t.Fatalf("could not set times: %+v", err)
+	}
+	err = os.Rename(tmp, fname)
+	if err != nil {
+		t.Skipf("could not replace open file: %+v", err)
+	}
+	changed, err = f.Changed()
+	if err != nil || !changed {
+		t.Fatalf("replaced file not reported as changed: %v, %+v", changed, err)
+	}
+}
+
+func TestPathError(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "path-error.bin")
+
+	op := func(err error) string {
+		t.Helper()
+		var pe *fs.PathError
+		if !errors.As(err, &pe) {
+			t.Fatalf("error is not a *fs.PathError: %+v", err)
+		}
+		if pe.Path != fname {
+			t.Fatalf("invalid path: got=%q, want=%q", pe.Path, fname)
+		}
+		return pe.Op
+	}
+
+	_, err := Open(fname)
+	if got, want := op(err), "mmap.open"; got != want {
+		t.Fatalf("invalid op: got=%q, want=%q", got, want)
+	}
+	if !os.IsNotExist(err) {
+		t.Fatalf("os.IsNotExist does not see through the error: %+v", err)
+	}
+
+	err = os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	failing := make(map[Syscall]bool)
+	errFault := errors.New("fault")
+	faults := WithFaults(func(call Syscall) error {
+		if failing[call] {
+			return errFault
+		}
+		return nil
+	})
+
+	failing[SyscallMmap] = true
+	_, err = OpenFile(fname, Read|Write, faults)
+	if got, want := op(err), "mmap.map"; got != want {
+		t.Fatalf("invalid op: got=%q, want=%q", got, want)
+	}
+	if !errors.Is(err, errFault) {
+		t.Fatalf("error does not wrap the fault: %+v", err)
+	}
+	failing[SyscallMmap] = false
+
+	f, err := OpenFile(fname, Read|Write, faults)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	failing[SyscallMsync] = true
+	err = f.Sync()
+	if got, want := op(err), "mmap.sync"; got != want {
+		t.Fatalf("invalid op: got=%q, want=%q", got, want)
+	}
+	failing[SyscallMsync] = false
+
+	failing[SyscallMunmap] = true
+	err = f.Close()
+	if got, want := op(err), "mmap.close"; got != want {
+		t.Fatalf("invalid op: got=%q, want=%q", got, want)
+	}
+
+	_, err = OpenFile(fname, Read, WithLock(Shared|Exclusive))
+	if got, want := op(err), "mmap.lock"; got != want {
+		t.Fatalf("invalid op: got=%q, want=%q", got, want)
+	}
+	if !errors.Is(err, errLockMode) {
+		t.Fatalf("error does not wrap the invalid lock mode: %+v", err)
+	}
+}
+
+func TestReadFastPath(t *testing.T) {
+	f, err := Open("mmap_test.go")
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	want, err := os.ReadFile("mmap_test.go")
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+
+	var got []byte
+	for i := 0; i < 8; i++ {
+		b, err := f.ReadByte()
+		if err != nil {
+			t.Fatalf("could not read byte: %+v", err)
+		}
+		got = append(got, b)
+	}
+	if !f.fast {
+		t.Fatalf("fast path not enabled after a first read")
+	}
+	rest, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	if got := append(got, rest...); !bytes.Equal(got, want) {
+		t.Fatalf("invalid contents")
+	}
+	if _, err := f.ReadByte(); err != io.EOF {
+		t.Fatalf("invalid error at end of file: %+v", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if _, err := f.ReadByte(); err == nil {
+		t.Fatalf("expected an error reading a closed file")
+	}
+	if _, err := f.Read(make([]byte, 4)); err == nil {
+		t.Fatalf("expected an error reading a closed file")
+	}
+}
+
+// benchmarkData returns a file holding n bytes of data.
+func benchmarkData(b *testing.B, n int) string {
+	b.Helper()
+	fname := filepath.Join(b.TempDir(), "bench.bin")
+	err := os.WriteFile(fname, bytes.Repeat([]byte("0123456789abcdef"), n/16), 0644)
+	if err != nil {
+		b.Fatalf("could not seed file: %+v", err)
+	}
+	return fname
+}
+
+// BenchmarkReadByte consumes a file byte by byte, as parsers do.
+func BenchmarkReadByte(b *testing.B) {
+	const size = 1 << 20
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"mapped", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			f, err := OpenFile(benchmarkData(b, size), Read, bc.opts...)
+			if err != nil {
+				b.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			b.SetBytes(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = f.Seek(0, io.SeekStart)
+				for {
+					_, err := f.ReadByte()
+					if err != nil {
+						break
+					}
+				}
+			}
+		})
+	}
+
+	b.Run("bytes.Reader", func(b *testing.B) {
+		raw, err := os.ReadFile(benchmarkData(b, size))
+		if err != nil {
+			b.Fatalf("could not read file: %+v", err)
+		}
+		r := bytes.NewReader(raw)
+
+		b.SetBytes(size)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = r.Seek(0, io.SeekStart)
+			for {
+				_, err := r.ReadByte()
+				if err != nil {
+					break
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkReadSmall consumes a file in small reads of a few bytes.
+func BenchmarkReadSmall(b *testing.B) {
+	const size = 1 << 20
+	for _, n := range []int{4, 16, 64} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			f, err := Open(benchmarkData(b, size))
+			if err != nil {
+				b.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			p := make([]byte, n)
+			b.SetBytes(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = f.Seek(0, io.SeekStart)
+				for {
+					_, err := f.Read(p)
+					if err != nil {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadAtBuf(t *testing.T) {
+	f, err := Open("mmap_test.go")
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+	want, err := os.ReadFile("mmap_test.go")
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+
+	for _, n := range []int64{0, 1, 100, 4096, 5000} {
+		p, release, err := f.ReadAtBuf(10, n)
+		if err != nil {
+			t.Fatalf("could not read %d bytes: %+v", n, err)
+		}
+		if !bytes.Equal(p, want[10:10+n]) {
+			t.Fatalf("invalid contents for %d bytes", n)
+		}
+		release()
+		release() // releasing twice is harmless.
+	}
+
+	// Stale releases do not hand out the buffer of its next user.
+	_, release1, err := f.ReadAtBuf(0, 100)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	release1()
+	p2, release2, err := f.ReadAtBuf(0, 100)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	release1()
+	p3, release3, err := f.ReadAtBuf(0, 100)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	if &p2[0] == &p3[0] {
+		t.Fatalf("buffer in use handed out again by a stale release")
+	}
+	release2()
+	release3()
+
+	size := int64(len(want))
+	p, release, err := f.ReadAtBuf(size-4, 16)
+	if err != io.EOF {
+		t.Fatalf("invalid error reading past the end: %+v", err)
+	}
+	if !bytes.Equal(p, want[size-4:]) {
+		t.Fatalf("invalid contents at the end: %q", p)
+	}
+	release()
+
+	// Huge reads only take a buffer for what is left of the file.
+	p, release, err = f.ReadAtBuf(0, 1<<40)
+	if err != io.EOF || !bytes.Equal(p, want) {
+		t.Fatalf("invalid huge read: err=%+v", err)
+	}
+	if cap(p) > 2*len(want) {
+		t.Fatalf("huge read took a buffer of %d bytes", cap(p))
+	}
+	release()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, release, err := f.ReadAtBuf(0, 1024)
+		if err != nil {
+			t.Fatalf("could not read: %+v", err)
+		}
+		release()
+	})
+	if allocs > 1 {
+		// Only the release func is allocated.
+		t.Fatalf("ReadAtBuf allocates: %v allocs per run", allocs)
+	}
+}
+
+func TestOpenAll(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("seg-%02d", i))
+		err := os.WriteFile(name, []byte(name), 0644)
+		if err != nil {
+			t.Fatalf("could not seed file: %+v", err)
+		}
+		names = append(names, name)
+	}
+
+	for _, workers := range []int{0, 4} {
+		files, err := OpenAll(names, Read, WithOpenWorkers(workers))
+		if err != nil {
+			t.Fatalf("could not open files with %d workers: %+v", workers, err)
+		}
+		for i, f := range files {
+			if got := string(f.Bytes()); got != names[i] {
+				t.Fatalf("invalid file %d: %q", i, got)
+			}
+			_ = f.Close()
+		}
+
+		missing := filepath.Join(dir, "missing")
+		bad := append(append([]string(nil), names[:10]...), missing)
+		bad = append(bad, names[10:]...)
+		before := ReadStats().Mappings
+		files, err = OpenAll(bad, Read, WithOpenWorkers(workers))
+		if files != nil || !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("invalid result with a missing file: %v, %+v", files, err)
+		}
+		var pe *fs.PathError
+		if !errors.As(err, &pe) || pe.Path != missing {
+			t.Fatalf("error does not name the missing file: %+v", err)
+		}
+		if got := ReadStats().Mappings; got > before {
+			t.Fatalf("files left mapped after a failure: %d, want %d", got, before)
+		}
+	}
+}
+
+func TestMapper(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 4; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("file-%d", i))
+		err := os.WriteFile(name, make([]byte, 100), 0644)
+		if err != nil {
+			t.Fatalf("could not seed file: %+v", err)
+		}
+		names = append(names, name)
+	}
+
+	m := NewMapper(300)
+	var files []*File
+	for _, name := range names[:3] {
+		f, err := m.OpenFile(name, Read|Write)
+		if err != nil {
+			t.Fatalf("could not open %q: %+v", name, err)
+		}
+		files = append(files, f)
+	}
+	if got, want := m.Stats(), (MapperStats{Files: 3, MappedBytes: 300}); got != want {
+		t.Fatalf("invalid stats: got=%+v, want=%+v", got, want)
+	}
+	_, err := m.Open(names[3])
+	if !errors.Is(err, ErrMappedLimit) {
+		t.Fatalf("invalid error over the limit: %+v", err)
+	}
+
+	err = files[0].Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if got, want := m.Stats(), (MapperStats{Files: 2, MappedBytes: 200}); got != want {
+		t.Fatalf("invalid stats after close: got=%+v, want=%+v", got, want)
+	}
+	f, err := m.Open(names[3])
+	if err != nil {
+		t.Fatalf("could not open file under the limit: %+v", err)
+	}
+	files = append(files, f)
+
+	_, err = files[1].WriteAt([]byte("synced"), 0)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = m.SyncAll()
+	if err != nil {
+		t.Fatalf("could not sync all: %+v", err)
+	}
+	raw, err := os.ReadFile(names[1])
+	if err != nil || string(raw[:6]) != "synced" {
+		t.Fatalf("file not synced: %q, %+v", raw[:6], err)
+	}
+
+	err = m.CloseAll()
+	if err != nil {
+		t.Fatalf("could not close all: %+v", err)
+	}
+	if got := m.Stats(); got.Files != 0 {
+		t.Fatalf("files left open: %+v", got)
+	}
+	for _, f := range files {
+		if !f.closed() {
+			t.Fatalf("file %q left open", f.Name())
+		}
+	}
+
+	// Stats may be polled while the files grow.
+	g, err := m.OpenFile(names[0], Read|Write, WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer g.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = m.Stats()
+		}
+	}()
+	for i := int64(1); i <= 100; i++ {
+		_, err = g.WriteAt([]byte("x"), 100*i)
+		if err != nil {
+			t.Fatalf("could not extend file: %+v", err)
+		}
+	}
+	<-done
+	if got, want := m.Stats(), (MapperStats{Files: 1, MappedBytes: g.Size()}); got != want {
+		t.Fatalf("invalid stats after growing: got=%+v, want=%+v", got, want)
+	}
+}
+
+func TestMaxMappedBytes(t *testing.T) {
+	const size = 128 << 10
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 4; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("file-%d", i))
+		err := os.WriteFile(name, bytes.Repeat([]byte{byte('a' + i)}, size), 0644)
+		if err != nil {
+			t.Fatalf("could not seed file: %+v", err)
+		}
+		names = append(names, name)
+	}
+
+	base := ReadStats().MappedBytes
+	prev := SetMaxMappedBytes(base + 2*size)
+	defer SetMaxMappedBytes(prev)
+	if got, want := MaxMappedBytes(), base+2*size; got != want {
+		t.Fatalf("invalid bound: got=%d, want=%d", got, want)
+	}
+
+	f0, err := Open(names[0])
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	f1, err := Open(names[1])
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	_, err = Open(names[2])
+	var berr *BudgetError
+	if !errors.Is(err, ErrMappedLimit) || !errors.As(err, &berr) {
+		t.Fatalf("invalid error over the bound: %+v", err)
+	}
+	if berr.Size != size || berr.Max != base+2*size {
+		t.Fatalf("invalid budget error: %+v", berr)
+	}
+
+	// Closing a file makes room for another one.
+	err = f0.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	f2, err := Open(names[2])
+	if err != nil {
+		t.Fatalf("could not open file under the bound: %+v", err)
+	}
+	_ = f2.Close()
+
+	// Mapping a file evicts the unused views of windowed files.
+	w, err := OpenFile(names[0], Read, withWindow(1<<16))
+	if err != nil {
+		t.Fatalf("could not open windowed file: %+v", err)
+	}
+	defer w.Close()
+	buf := make([]byte, 1)
+	_, err = w.ReadAt(buf, 0)
+	if err != nil {
+		t.Fatalf("could not read windowed file: %+v", err)
+	}
+	f2, err = Open(names[2])
+	if err != nil {
+		t.Fatalf("could not open file evicting a view: %+v", err)
+	}
+	if w.w.data != nil {
+		t.Fatalf("view was not evicted")
+	}
+	_, err = w.ReadAt(buf, size-1)
+	if !errors.Is(err, ErrMappedLimit) {
+		t.Fatalf("invalid error mapping a view over the bound: %+v", err)
+	}
+
+	// Waiting files are mapped once others are closed.
+	done := make(chan error, 1)
+	go func() {
+		f, err := OpenFile(names[3], Read, WithBudgetWait())
+		if err == nil {
+			err = f.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("file was mapped over the bound: %+v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	err = f2.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	err = <-done
+	if err != nil {
+		t.Fatalf("could not open waiting file: %+v", err)
+	}
+	_ = f1.Close()
+
+	// Files larger than the bound are mapped through a window.
+	SetMaxMappedBytes(base + size/2)
+	f, err := Open(names[3])
+	if err != nil {
+		t.Fatalf("could not open file larger than the bound: %+v", err)
+	}
+	defer f.Close()
+	if f.Bytes() != nil {
+		t.Fatalf("file larger than the bound was mapped as a whole")
+	}
+	got := make([]byte, size)
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read file larger than the bound: %+v", err)
+	}
+	if !bytes.Equal(got, bytes.Repeat([]byte{'d'}, size)) {
+		t.Fatalf("invalid contents of file larger than the bound")
+	}
+}
+
+func TestMaxMappedBytesEarlyViews(t *testing.T) {
+	const size = 128 << 10
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "file")
+	err := os.WriteFile(fname, make([]byte, size), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	// Views mapped before the mappings are bounded are evicted as well.
+	w, err := OpenFile(fname, Read, withWindow(1<<16))
+	if err != nil {
+		t.Fatalf("could not open windowed file: %+v", err)
+	}
+	defer w.Close()
+	_, err = w.ReadAt(make([]byte, 1), 0)
+	if err != nil {
+		t.Fatalf("could not read windowed file: %+v", err)
+	}
+	view := int64(len(w.w.data))
+
+	prev := SetMaxMappedBytes(ReadStats().MappedBytes - view + size)
+	defer SetMaxMappedBytes(prev)
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file evicting a view: %+v", err)
+	}
+	defer f.Close()
+	if w.w.data != nil {
+		t.Fatalf("view was not evicted")
+	}
+}
+
+func TestSyncFull(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"full", []Option{WithFullSync()}},
+		{"window", []Option{WithFullSync(), withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(name, Read|Write, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+			_, err = f.WriteAt([]byte(tc.name), 0)
+			if err != nil {
+				t.Fatalf("could not write: %+v", err)
+			}
+			err = f.SyncFull()
+			if err != nil {
+				t.Fatalf("could not sync full: %+v", err)
+			}
+			err = f.Sync()
+			if err != nil {
+				t.Fatalf("could not sync: %+v", err)
+			}
+			err = f.SyncContext(context.Background())
+			if err != nil {
+				t.Fatalf("could not sync with context: %+v", err)
+			}
+			raw, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+			if !bytes.HasPrefix(raw, []byte(tc.name)) {
+				t.Fatalf("invalid contents: %q", raw[:8])
+			}
+		})
+	}
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer r.Close()
+	if err := r.SyncFull(); err != nil {
+		t.Fatalf("could not sync read-only file: %+v", err)
+	}
+
+	rg, err := OpenRegion(name, Read|Write, 0, 4096, WithFullSync())
+	if err != nil {
+		t.Fatalf("could not open region: %+v", err)
+	}
+	defer rg.Close()
+	_, err = rg.WriteAt([]byte("region"), 0)
+	if err != nil {
+		t.Fatalf("could not write region: %+v", err)
+	}
+	if err := rg.Sync(); err != nil {
+		t.Fatalf("could not sync region: %+v", err)
+	}
+}
+
+func TestNoSpace(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 8), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := OpenFile(name, Read|Write)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.Remaining(), int64(8); got != want {
+		t.Fatalf("invalid remaining: got=%d, want=%d", got, want)
+	}
+	n, err := f.Write([]byte("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("could not write: n=%d, err=%+v", n, err)
+	}
+	if got, want := f.Remaining(), int64(3); got != want {
+		t.Fatalf("invalid remaining: got=%d, want=%d", got, want)
+	}
+	n, err = f.Write([]byte("world"))
+	if !errors.Is(err, ErrNoSpace) || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("invalid error: %+v", err)
+	}
+	if n != 3 || f.Pos() != 8 || f.Remaining() != 0 {
+		t.Fatalf("invalid partial write: n=%d, pos=%d, remaining=%d", n, f.Pos(), f.Remaining())
+	}
+	if err := f.WriteByte('!'); !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("invalid error: %+v", err)
+	}
+	n, err = f.WriteAt([]byte("bye"), 6)
+	if !errors.Is(err, ErrNoSpace) || n != 2 {
+		t.Fatalf("invalid partial write-at: n=%d, err=%+v", n, err)
+	}
+	if got, want := string(f.Bytes()), "hellowby"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+
+	_, err = f.Seek(16, io.SeekStart)
+	if err != nil {
+		t.Fatalf("could not seek: %+v", err)
+	}
+	if got := f.Remaining(); got != 0 {
+		t.Fatalf("invalid remaining past the end: %d", got)
+	}
+}
+
+func TestReadWriteFullAt(t *testing.T) {
+	const size = 3 << 16
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, size), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"mapped", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(name, Read|Write, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			// Span the views of windowed files.
+			want := bytes.Repeat([]byte(tc.name), 1<<15)[:2<<16]
+			err = f.WriteFullAt(want, 1<<15)
+			if err != nil {
+				t.Fatalf("could not write full: %+v", err)
+			}
+			got := make([]byte, len(want))
+			err = f.ReadFullAt(got, 1<<15)
+			if err != nil {
+				t.Fatalf("could not read full: %+v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("invalid contents")
+			}
+
+			err = f.ReadFullAt(got, size-10)
+			if err != io.ErrUnexpectedEOF {
+				t.Fatalf("invalid error reading past the end: %+v", err)
+			}
+			err = f.ReadFullAt(got, size)
+			if err != io.EOF {
+				t.Fatalf("invalid error reading at the end: %+v", err)
+			}
+			err = f.WriteFullAt(want, size-10)
+			if !errors.Is(err, ErrNoSpace) {
+				t.Fatalf("invalid error writing past the end: %+v", err)
+			}
+		})
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := OpenFile(name, Read|Write)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	b := NewBatchWriter(f)
+	for _, w := range []struct {
+		s   string
+		off int64
+	}{
+		{"hello", 2},
+		{"world", 8},
+		{"J", 2},
+	} {
+		p := []byte(w.s)
+		_, err = b.WriteAt(p, w.off)
+		if err != nil {
+			t.Fatalf("could not write %q: %+v", w.s, err)
+		}
+		p[0] = '?' // the batch must hold a copy.
+	}
+	_, err = b.WriteAt([]byte("past"), 14)
+	if !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("invalid error writing past the end: %+v", err)
+	}
+	if got, want := b.Len(), 3; got != want {
+		t.Fatalf("invalid number of writes: got=%d, want=%d", got, want)
+	}
+	if got, want := b.Buffered(), 11; got != want {
+		t.Fatalf("invalid buffered bytes: got=%d, want=%d", got, want)
+	}
+	if !bytes.Equal(f.Bytes(), make([]byte, 16)) {
+		t.Fatalf("writes are visible before commit: %q", f.Bytes())
+	}
+
+	err = b.Commit(true)
+	if err != nil {
+		t.Fatalf("could not commit: %+v", err)
+	}
+	if got, want := string(f.Bytes()), "\x00\x00Jello\x00world\x00\x00\x00"; got != want {
+		t.Fatalf("invalid contents:\ngot= %q\nwant=%q", got, want)
+	}
+	if b.Len() != 0 || b.Buffered() != 0 {
+		t.Fatalf("batch was not emptied")
+	}
+	err = b.Commit(false)
+	if err != nil {
+		t.Fatalf("could not commit empty batch: %+v", err)
+	}
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer r.Close()
+	if _, err := NewBatchWriter(r).WriteAt([]byte("x"), 0); err != errBadFD {
+		t.Fatalf("invalid error writing to read-only file: %+v", err)
+	}
+
+	_, err = b.WriteAt([]byte("closed"), 0)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if err := b.Commit(false); !errors.Is(err, errClosed) {
+		t.Fatalf("invalid error committing to a closed file: %+v", err)
+	}
+}
+
+func TestOpenRegion(t *testing.T) {
+	gran := allocGranularity()
+	size := 4 * gran
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		off, n int64
+	}{
+		{0, 10},
+		{1, gran},
+		{gran, gran},
+		{gran + 123, 2*gran - 7},
+		{3*gran + 1, gran - 1},
+	} {
+		t.Run(fmt.Sprintf("%d+%d", tc.off, tc.n), func(t *testing.T) {
+			r, err := OpenRegion(name, Read|Write, tc.off, tc.n)
+			if err != nil {
+				t.Fatalf("could not open region: %+v", err)
+			}
+			defer r.Close()
+
+			if got, want := r.BaseOffset(), tc.off-tc.off%gran; got != want {
+				t.Fatalf("invalid base offset: got=%d, want=%d", got, want)
+			}
+			if r.Offset() != tc.off || int64(r.Len()) != tc.n {
+				t.Fatalf("invalid region: off=%d, len=%d", r.Offset(), r.Len())
+			}
+			if !bytes.Equal(r.Bytes(), content[tc.off:tc.off+tc.n]) {
+				t.Fatalf("invalid contents")
+			}
+			if got, want := r.FileOffset(5), tc.off+5; got != want {
+				t.Fatalf("invalid file offset: got=%d, want=%d", got, want)
+			}
+			if i, ok := r.RegionOffset(tc.off + 3); !ok || i != 3 {
+				t.Fatalf("invalid region offset: %d, %v", i, ok)
+			}
+			if _, ok := r.RegionOffset(tc.off - 1); ok {
+				t.Fatalf("region holds the byte before it")
+			}
+			if _, ok := r.RegionOffset(tc.off + tc.n); ok {
+				t.Fatalf("region holds the byte after it")
+			}
+
+			got := make([]byte, 4)
+			_, err = r.ReadAt(got, 2)
+			if err != nil {
+				t.Fatalf("could not read: %+v", err)
+			}
+			if !bytes.Equal(got, content[tc.off+2:tc.off+6]) {
+				t.Fatalf("invalid read: %v", got)
+			}
+			_, err = r.WriteAt([]byte("HELLO"), tc.n-3)
+			if !errors.Is(err, ErrNoSpace) {
+				t.Fatalf("invalid error writing past the end: %+v", err)
+			}
+			_, err = r.WriteAt([]byte("hi"), 0)
+			if err != nil {
+				t.Fatalf("could not write: %+v", err)
+			}
+			err = r.Sync()
+			if err != nil {
+				t.Fatalf("could not sync: %+v", err)
+			}
+			err = r.Close()
+			if err != nil {
+				t.Fatalf("could not close: %+v", err)
+			}
+
+			raw, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read file: %+v", err)
+			}
+			if !bytes.Equal(raw[tc.off:tc.off+2], []byte("hi")) ||
+				!bytes.Equal(raw[tc.off+tc.n-3:tc.off+tc.n], []byte("HEL")) {
+				t.Fatalf("writes were not carried to the file")
+			}
+			if tc.off+tc.n < size && raw[tc.off+tc.n] != content[tc.off+tc.n] {
+				t.Fatalf("write past the region reached the file")
+			}
+			copy(content[tc.off:], "hi")
+			copy(content[tc.off+tc.n-3:], "HEL")
+		})
+	}
+
+	for _, tc := range []struct {
+		off, n int64
+	}{
+		{-1, 10},
+		{0, 0},
+		{size - 1, 2},
+	} {
+		_, err := OpenRegion(name, Read, tc.off, tc.n)
+		if err == nil {
+			t.Fatalf("expected an error opening region %d+%d", tc.off, tc.n)
+		}
+	}
+}
+
+func TestAddr(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 3<<16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(name)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	if got, want := f.Addr(), uintptr(f.UnsafePointer()); got != want || got == 0 {
+		t.Fatalf("invalid address: got=%#x, want=%#x", got, want)
+	}
+	if got, want := f.MappedLen(), 3<<16; got != want {
+		t.Fatalf("invalid mapped length: got=%d, want=%d", got, want)
+	}
+	_ = f.Close()
+	if f.Addr() != 0 || f.MappedLen() != 0 {
+		t.Fatalf("closed file is still mapped: %#x, %d", f.Addr(), f.MappedLen())
+	}
+
+	w, err := OpenFile(name, Read, withWindow(1<<16))
+	if err != nil {
+		t.Fatalf("could not open windowed file: %+v", err)
+	}
+	defer w.Close()
+	if w.Addr() != 0 || w.MappedLen() != 0 {
+		t.Fatalf("windowed file is mapped before use: %#x, %d", w.Addr(), w.MappedLen())
+	}
+	_, err = w.ReadAt(make([]byte, 1), 2<<16)
+	if err != nil {
+		t.Fatalf("could not read: %+v", err)
+	}
+	if w.Addr() == 0 || w.MappedLen() != 1<<16 {
+		t.Fatalf("invalid view: %#x, %d", w.Addr(), w.MappedLen())
+	}
+
+	r, err := OpenRegion(name, Read, allocGranularity()+1, 10)
+	if err != nil {
+		t.Fatalf("could not open region: %+v", err)
+	}
+	defer r.Close()
+	if got, want := r.Addr()+1, uintptr(unsafe.Pointer(&r.Bytes()[0])); got != want {
+		t.Fatalf("invalid region address: got=%#x, want=%#x", got, want)
+	}
+	if got, want := r.MappedLen(), 11; got != want {
+		t.Fatalf("invalid region mapped length: got=%d, want=%d", got, want)
+	}
+}
+
+func TestOpenFiles(t *testing.T) {
+	TrackOpenFiles(true)
+	defer TrackOpenFiles(false)
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	err := os.WriteFile(name, make([]byte, 16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := OpenFile(name, Read|Write, WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+	r, err := Open(name)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+
+	infos := OpenFiles()
+	if len(infos) != 2 {
+		t.Fatalf("invalid open files: %+v", infos)
+	}
+	if got := infos[0]; got.Path != name || got.Size != 16 || got.Flag != Read|Write || got.Opened.After(infos[1].Opened) {
+		t.Fatalf("invalid open file: %+v", got)
+	}
+	if got := infos[1]; got.Flag != Read {
+		t.Fatalf("invalid open file: %+v", got)
+	}
+
+	_, err = f.WriteAt([]byte("grow"), 30)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	infos = OpenFiles()
+	if len(infos) != 1 || infos[0].Size != 34 {
+		t.Fatalf("invalid open files after grow and close: %+v", infos)
+	}
+
+	TrackOpenFiles(false)
+	if infos := OpenFiles(); len(infos) != 0 {
+		t.Fatalf("disabled registry reports files: %+v", infos)
+	}
+}
+
+func TestFinalizer(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 16), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	t.Run("none", func(t *testing.T) {
+		TrackOpenFiles(true)
+		defer TrackOpenFiles(false)
+
+		func() {
+			_, err := OpenFile(name, Read, WithFinalizer(FinalizerNone))
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+		}()
+		for i := 0; i < 3; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+		if infos := OpenFiles(); len(infos) != 1 {
+			t.Fatalf("unreachable file was closed: %+v", infos)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		f, err := OpenFile(name, Read, WithFinalizer(FinalizerPanic))
+		if err != nil {
+			t.Fatalf("could not open file: %+v", err)
+		}
+		defer f.Close()
+		if len(f.stack) == 0 {
+			t.Fatalf("opening stack was not recorded")
+		}
+
+		// Call the finalizer directly, as the panic of a finalizer run by
+		// the garbage collector would crash the test.
+		defer func() {
+			msg, _ := recover().(string)
+			if !strings.Contains(msg, name) || !strings.Contains(msg, "TestFinalizer") {
+				t.Fatalf("invalid panic: %q", msg)
+			}
+		}()
+		f.leaked()
+	})
+}
+
+func TestPersist(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, make([]byte, 8192), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(name, Read|Write)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+	_, err = f.WriteAt([]byte("persisted"), 4090)
+	if err != nil {
+		t.Fatalf("could not write: %+v", err)
+	}
+	err = f.Persist(4090, 9)
+	if err != nil {
+		t.Fatalf("could not persist: %+v", err)
+	}
+	err = f.Persist(8000, 1000)
+	if err == nil {
+		t.Fatalf("expected an error persisting past the end of the file")
+	}
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := string(raw[4090:4099]), "persisted"; got != want {
+		t.Fatalf("invalid contents: got=%q, want=%q", got, want)
+	}
+
+	// Flushing cache lines works on any memory.
+	buf := make([]byte, 1000)
+	if flushCache(f.data[4090:4099]) != flushCache(buf[3:]) {
+		t.Fatalf("inconsistent cache flushes")
+	}
+	if flushCache(buf[3:]) && runtime.GOARCH != "amd64" {
+		t.Fatalf("unexpected cache flush on %s", runtime.GOARCH)
+	}
+	if !flushCache(nil) && runtime.GOARCH == "amd64" {
+		t.Fatalf("could not flush empty range")
+	}
+}
+
+func TestRegisterBuffer(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, []byte("hello world!"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(name, Read|Write, WithAutoExtend())
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	var order []int
+	errUnregister := errors.New("unregister failed")
+	buf, err := f.RegisterBuffer(func() error {
+		order = append(order, 1)
+		return errUnregister
+	})
+	if err != nil {
+		t.Fatalf("could not register buffer: %+v", err)
+	}
+	if got, want := string(buf), "hello world!"; got != want {
+		t.Fatalf("invalid buffer: got=%q, want=%q", got, want)
+	}
+	_, err = f.RegisterBuffer(func() error {
+		order = append(order, 2)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not register buffer again: %+v", err)
+	}
+
+	err = f.Remap()
+	if !errors.Is(err, errPinned) {
+		t.Fatalf("invalid error remapping pinned file: %+v", err)
+	}
+	_, err = f.WriteAt([]byte("bye"), 12)
+	if !errors.Is(err, errPinned) {
+		t.Fatalf("invalid error growing pinned file: %+v", err)
+	}
+	_, err = f.WriteAt([]byte("bye"), 0)
+	if err != nil {
+		t.Fatalf("could not write within pinned file: %+v", err)
+	}
+	if got, want := string(buf[:3]), "bye"; got != want {
+		t.Fatalf("invalid buffer after write: got=%q, want=%q", got, want)
+	}
+
+	err = f.Close()
+	if !errors.Is(err, errUnregister) {
+		t.Fatalf("invalid error closing file: %+v", err)
+	}
+	if got, want := order, []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid unregistration order: got=%v, want=%v", got, want)
+	}
+	_, err = f.RegisterBuffer(nil)
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("invalid error registering closed file: %+v", err)
+	}
+
+	w, err := OpenFile(name, Read, withWindow(1<<16))
+	if err != nil {
+		t.Fatalf("could not open windowed file: %+v", err)
+	}
+	defer w.Close()
+	_, err = w.RegisterBuffer(nil)
+	if !errors.Is(err, errUnsupported) {
+		t.Fatalf("invalid error registering windowed file: %+v", err)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	content := bytes.Repeat([]byte("hello world!\n"), 10000)
+	err := os.WriteFile(name, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	defer ln.Close()
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		dst  func(t *testing.T) (io.Writer, func() []byte)
+	}{
+		{
+			name: "buffer",
+			dst: func(t *testing.T) (io.Writer, func() []byte) {
+				buf := new(bytes.Buffer)
+				return buf, buf.Bytes
+			},
+		},
+		{
+			name: "window",
+			opts: []Option{withWindow(1 << 16)},
+			dst: func(t *testing.T) (io.Writer, func() []byte) {
+				buf := new(bytes.Buffer)
+				return buf, buf.Bytes
+			},
+		},
+		{
+			name: "file",
+			dst: func(t *testing.T) (io.Writer, func() []byte) {
+				out, err := os.Create(filepath.Join(dir, "out"))
+				if err != nil {
+					t.Fatalf("could not create output: %+v", err)
+				}
+				t.Cleanup(func() { out.Close() })
+				return out, func() []byte {
+					raw, err := os.ReadFile(out.Name())
+					if err != nil {
+						t.Fatalf("could not read output: %+v", err)
+					}
+					return raw
+				}
+			},
+		},
+		{
+			name: "tcp",
+			dst: func(t *testing.T) (io.Writer, func() []byte) {
+				done := make(chan []byte)
+				go func() {
+					c, err := ln.Accept()
+					if err != nil {
+						done <- nil
+						return
+					}
+					defer c.Close()
+					raw, _ := io.ReadAll(c)
+					done <- raw
+				}()
+				c, err := net.Dial("tcp", ln.Addr().String())
+				if err != nil {
+					t.Fatalf("could not dial: %+v", err)
+				}
+				return c, func() []byte {
+					c.Close()
+					return <-done
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(name, Read, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+			_, err = f.Seek(6, io.SeekStart)
+			if err != nil {
+				t.Fatalf("could not seek: %+v", err)
+			}
+
+			w, got := tc.dst(t)
+			_, send := f.sendable(w)
+			if want := tc.name == "tcp" || tc.name == "file" && fileCopyOffload; send != want {
+				t.Fatalf("invalid kernel copy: got=%v, want=%v", send, want)
+			}
+			n, err := f.WriteTo(w)
+			if err != nil {
+				t.Fatalf("could not write to: %+v", err)
+			}
+			if want := int64(len(content) - 6); n != want {
+				t.Fatalf("invalid count: got=%d, want=%d", n, want)
+			}
+			if !bytes.Equal(got(), content[6:]) {
+				t.Fatalf("invalid contents")
+			}
+
+			n, err = f.WriteTo(w)
+			if n != 0 || err != nil {
+				t.Fatalf("invalid write to at EOF: n=%d, err=%+v", n, err)
+			}
+			_, err = f.Read(make([]byte, 1))
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("invalid error reading after write to: %+v", err)
+			}
+		})
+	}
+}
+
+func TestWriteToDirty(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data")
+	content := bytes.Repeat([]byte("hello world!\n"), 1000)
+	err := os.WriteFile(name, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := OpenFile(name, Read|Write, WithDirtyTracking())
+	if errors.Is(err, errUnsupported) {
+		t.Skipf("could not mmap file: %+v", err)
+	}
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	// The write is not synced: WriteTo must send it all the same.
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	if err != nil {
+		t.Fatalf("could not write-at: %+v", err)
+	}
+	want := append([]byte("HELLO"), content[5:]...)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+	defer ln.Close()
+	done := make(chan []byte)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer c.Close()
+		raw, _ := io.ReadAll(c)
+		done <- raw
+	}()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %+v", err)
+	}
+	if _, send := f.sendable(c); send == dirtyCopied {
+		t.Fatalf("invalid kernel copy: got=%v, want=%v", send, !dirtyCopied)
+	}
+	_, err = f.WriteTo(c)
+	c.Close()
+	if err != nil {
+		t.Fatalf("could not write to: %+v", err)
+	}
+	if got := <-done; !bytes.Equal(got, want) {
+		t.Fatalf("invalid contents")
+	}
+}
+
+func TestBytesReader(t *testing.T) {
+	content := []byte("héllo, wörld!\n")
+	name := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(name, content, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"mapped", nil},
+		{"window", []Option{withWindow(1 << 16)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := OpenFile(name, Read, tc.opts...)
+			if err != nil {
+				t.Fatalf("could not open file: %+v", err)
+			}
+			defer f.Close()
+
+			// Both readers go through the same calls, and must agree.
+			got, want := NewBytesReader(f), bytes.NewReader(content)
+			check := func(op string, g, w interface{}) {
+				t.Helper()
+				if !reflect.DeepEqual(g, w) {
+					t.Fatalf("%s: got=%v, want=%v", op, g, w)
+				}
+			}
+			check("size", got.Size(), want.Size())
+
+			gr, gn, gerr := got.ReadRune()
+			wr, wn, werr := want.ReadRune()
+			check("read rune", []interface{}{gr, gn, gerr}, []interface{}{wr, wn, werr})
+			gr, gn, gerr = got.ReadRune()
+			wr, wn, werr = want.ReadRune()
+			check("read multi-byte rune", []interface{}{gr, gn, gerr}, []interface{}{wr, wn, werr})
+			check("unread rune", got.UnreadRune() == nil, want.UnreadRune() == nil)
+			check("unread rune twice", got.UnreadRune() == nil, want.UnreadRune() == nil)
+			check("len", got.Len(), want.Len())
+
+			gb, gerr := got.ReadByte()
+			wb, werr := want.ReadByte()
+			check("read byte", []interface{}{gb, gerr}, []interface{}{wb, werr})
+			check("unread byte", got.UnreadByte() == nil, want.UnreadByte() == nil)
+
+			gp, wp := make([]byte, 5), make([]byte, 5)
+			gn, gerr = got.Read(gp)
+			wn, werr = want.Read(wp)
+			check("read", []interface{}{gp[:gn], gerr}, []interface{}{wp[:wn], werr})
+			gn, gerr = got.ReadAt(gp, 12)
+			wn, werr = want.ReadAt(wp, 12)
+			check("read at", []interface{}{gp[:gn], gerr}, []interface{}{wp[:wn], werr})
+
+			goff, gerr := got.Seek(-3, io.SeekEnd)
+			woff, werr := want.Seek(-3, io.SeekEnd)
+			check("seek end", []interface{}{goff, gerr}, []interface{}{woff, werr})
+			_, gerr = got.Seek(-100, io.SeekCurrent)
+			_, werr = want.Seek(-100, io.SeekCurrent)
+			check("seek negative", gerr != nil, werr != nil)
+
+			gbuf, wbuf := new(bytes.Buffer), new(bytes.Buffer)
+			gn64, gerr := got.WriteTo(gbuf)
+			wn64, werr := want.WriteTo(wbuf)
+			check("write to", []interface{}{gn64, gerr, gbuf.String()}, []interface{}{wn64, werr, wbuf.String()})
+			check("len at end", got.Len(), want.Len())
+
+			gn, gerr = got.Read(gp)
+			wn, werr = want.Read(wp)
+			check("read at end", []interface{}{gn, gerr}, []interface{}{wn, werr})
+			_, _, gerr = got.ReadRune()
+			_, _, werr = want.ReadRune()
+			check("read rune at end", gerr, werr)
+			_, gerr = got.ReadByte()
+			_, werr = want.ReadByte()
+			check("read byte at end", gerr, werr)
+
+			got.Reset(f)
+			want.Reset(content)
+			all, err := io.ReadAll(got)
+			if err != nil {
+				t.Fatalf("could not read all: %+v", err)
+			}
+			check("read all", all, content)
+			check("file cursor", f.Pos(), int64(0))
+		})
+	}
+}
@@ -8,71 +8,781 @@
 package mmap
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"os"
-	"runtime"
+	"path/filepath"
+	"time"
+	"unsafe"
 
 	syscall "golang.org/x/sys/unix"
 )
 
-func openFile(filename string, fl Flag) (*File, error) {
-	f, err := os.OpenFile(filename, fl.flag(), 0666)
+func openFile(filename string, fl Flag, cfg options) (*File, error) {
+	var (
+		f   *os.File
+		err error
+	)
+	if cfg.dir != nil {
+		f, err = openAt(cfg.dir, filename, fl.flag()|cfg.openFlags(), cfg.perm)
+	} else {
+		f, err = os.OpenFile(filename, fl.flag()|cfg.openFlags(), cfg.perm)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("mmap: could not open %q: %w", filename, err)
+		return nil, pathError("mmap.open", filename, err)
+	}
+	if cfg.inherit {
+		_, err = syscall.FcntlInt(f.Fd(), syscall.F_SETFD, 0)
+		if err != nil {
+			_ = f.Close()
+			return nil, pathError("mmap.open", filename, err)
+		}
+	}
+
+	if cfg.lock != 0 {
+		_, err = lockFile(f, cfg.lock, true)
+		if err != nil {
+			_ = f.Close()
+			return nil, pathError("mmap.lock", filename, err)
+		}
 	}
 
-	fi, err := f.Stat()
+	r := &File{
+		fd:   f,
+		name: filename,
+		flag: fl,
+		cfg:  cfg,
+	}
+	r.checkRemote()
+	err = r.mapFile()
+	if err == nil {
+		err = r.checkAlign()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("mmap: could not stat %q: %w", filename, err)
+		_ = r.unmapFile()
+		_ = f.Close()
+		return nil, err
+	}
+
+	if cfg.watch {
+		err = r.startWatch()
+		if err != nil {
+			_ = r.unmapFile()
+			_ = f.Close()
+			return nil, pathError("mmap.watch", filename, err)
+		}
 	}
 
-	size := fi.Size()
+	r.opened()
+	return r, nil
+}
+
+// mapFile maps the file in memory, according to its current size.
+func (f *File) mapFile() error {
+	filename := f.fd.Name()
+	fi, err := f.fd.Stat()
+	if err != nil {
+		return fmt.Errorf("mmap: could not stat %q: %w", filename, err)
+	}
+	f.fi = fi
+
+	size, err := f.mapSize(fi)
+	if err != nil {
+		return err
+	}
 	if size == 0 {
-		return &File{fd: f, flag: fl, fi: fi}, nil
+		if !f.wflag() && fi.Mode().IsRegular() && pseudoFile(f.fd) {
+			f.empty.Store(false)
+			return f.mapContents()
+		}
+		f.empty.Store(true)
+		return nil
 	}
+	// Files mapped empty are only reported as mapped once their mapping is
+	// published, for readers not to observe them half-mapped.
+	defer f.empty.Store(false)
 	if size < 0 {
-		return nil, fmt.Errorf("mmap: file %q has negative size", filename)
+		return fmt.Errorf("mmap: file %q has negative size", filename)
+	}
+	if f.cfg.direct {
+		f.w = &window{size: size, direct: true}
+		return nil
+	}
+	if f.cfg.mapSync && (mapSync == 0 || f.cfg.private) {
+		return fmt.Errorf("mmap: file %q can not be mapped synchronously: %w", filename, errUnsupported)
+	}
+	if size > maxView && f.cfg.addr != 0 {
+		return fmt.Errorf("mmap: file %q is too large to be mapped at a fixed address", filename)
+	}
+	if size > maxView || f.cfg.window > 0 {
+		if f.cfg.private {
+			return fmt.Errorf("mmap: file %q is too large to be snapshotted", filename)
+		}
+		if f.cfg.dirty {
+			return fmt.Errorf("mmap: file %q is too large to track dirty pages", filename)
+		}
+		f.w = newWindow(size, f.cfg.window)
+		return nil
+	}
+	if overBudget(size) && f.cfg.addr == 0 && !f.cfg.private && !f.cfg.dirty {
+		f.w = newWindow(size, budgetSpan())
+		return nil
+	}
+	reserved, err := f.reserve(nil, size)
+	if err != nil {
+		return err
+	}
+	defer unreserve(reserved)
+
+	prot := f.flag.prot()
+	if f.cfg.private {
+		// Copy-on-write mappings can be written whatever the access to
+		// the file.
+		prot |= syscall.PROT_WRITE
+	}
+
+	base := f.shareFlags()
+	if f.cfg.private {
+		base = syscall.MAP_PRIVATE
+	}
+	if f.cfg.populate {
+		base |= mapPopulate
+	}
+	if f.cfg.noReserve {
+		base |= mapNoReserve
+	}
+	flags := base
+	if f.cfg.largePages {
+		flags |= mapLargePages
+	}
+
+	fd := int(f.fd.Fd())
+	data, err := f.mmap(fd, 0, f.cfg.addr, int(size), prot, flags)
+	if err != nil && flags != base && !errors.Is(err, ErrAddrNotAvailable) {
+		data, err = f.mmap(fd, 0, f.cfg.addr, int(size), prot, base)
+	}
+	if err != nil {
+		return f.fallback(size, pathError("mmap.map", filename, err))
+	}
+
+	if f.cfg.populate && mapPopulate == 0 {
+		_ = syscall.Madvise(data, syscall.MADV_WILLNEED)
+	}
+	if f.cfg.advice != adviceNormal {
+		err = syscall.Madvise(data, f.cfg.advice.madvise())
+		if err != nil {
+			_ = munmap(data)
+			return fmt.Errorf("mmap: could not madvise %q: %w", filename, err)
+		}
+	}
+	if f.cfg.numa {
+		err = bindNode(data, f.cfg.node)
+		if err != nil {
+			_ = munmap(data)
+			return fmt.Errorf("mmap: could not bind %q to NUMA node %d: %w", filename, f.cfg.node, err)
+		}
+	}
+	err = f.cfg.fork.apply(data)
+	if err != nil {
+		_ = munmap(data)
+		return fmt.Errorf("mmap: could not set fork inheritance of %q: %w", filename, err)
+	}
+	if f.cfg.dirty {
+		err = trackDirty(data)
+		if err != nil {
+			_ = munmap(data)
+			return fmt.Errorf("mmap: could not track dirty pages of %q: %w", filename, err)
+		}
+	}
+
+	statMap(data)
+	f.data = data
+	return nil
+}
+
+// mapContents maps a copy of the contents of a pseudo file, such as the
+// ones of procfs or sysfs, which report no size and can not be mapped.
+// The copy is read-only, and Remap takes a new one.
+func (f *File) mapContents() error {
+	filename := f.fd.Name()
+	buf, err := io.ReadAll(io.NewSectionReader(f.fd, 0, math.MaxInt64))
+	if err != nil {
+		return fmt.Errorf("mmap: could not read %q: %w", filename, err)
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+
+	data, err := f.mmap(-1, 0, 0, len(buf), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return pathError("mmap.map", filename, err)
 	}
-	if size != int64(int(size)) {
-		return nil, fmt.Errorf("mmap: file %q is too large", filename)
+	copy(data, buf)
+	err = syscall.Mprotect(data, syscall.PROT_READ)
+	if err != nil {
+		_ = munmap(data)
+		return fmt.Errorf("mmap: could not mprotect %q: %w", filename, err)
 	}
 
+	statMap(data)
+	f.data = data
+	return nil
+}
+
+// unmapFile releases the memory mapping of the file.
+func (f *File) unmapFile() error {
+	if f.w != nil {
+		w := f.w
+		f.w = nil
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.unmap(f)
+	}
+	if f.data == nil {
+		return nil
+	}
+	data := f.data
+	f.data = nil
+	if f.cfg.dirty {
+		untrackDirty(data)
+	}
+	statUnmap(data)
+	return f.munmap(data)
+}
+
+// openAt opens the named file relative to the directory dir.
+func openAt(dir *os.File, filename string, flag int, perm fs.FileMode) (*os.File, error) {
+	var (
+		fd  int
+		err error
+	)
+	for {
+		fd, err = syscall.Openat(int(dir.Fd()), filename, flag|syscall.O_CLOEXEC, uint32(perm.Perm()))
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: filename, Err: err}
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(dir.Name(), filename)), nil
+}
+
+// openFlags returns the flags to open the file with, besides its access
+// mode.
+func (cfg options) openFlags() int {
+	var flag int
+	if cfg.create {
+		flag |= os.O_CREATE
+	}
+	if cfg.excl {
+		flag |= os.O_EXCL
+	}
+	if cfg.trunc {
+		flag |= os.O_TRUNC
+	}
+	if cfg.noFollow {
+		flag |= syscall.O_NOFOLLOW
+	}
+	return flag
+}
+
+func (fl Flag) prot() int {
 	prot := syscall.PROT_READ
 	if fl&Write != 0 {
 		prot |= syscall.PROT_WRITE
 	}
+	return prot
+}
+
+func (adv advice) madvise() int {
+	switch adv {
+	case adviceSequential:
+		return syscall.MADV_SEQUENTIAL
+	case adviceRandom:
+		return syscall.MADV_RANDOM
+	}
+	return syscall.MADV_NORMAL
+}
+
+// apply sets what child processes inherit of data.
+func (mode forkMode) apply(data []byte) error {
+	var adv int
+	switch mode {
+	case forkNone:
+		adv = madvDontFork
+	case forkWipe:
+		adv = madvWipeOnFork
+	}
+	if adv == 0 {
+		return nil
+	}
+	err := syscall.Madvise(data, adv)
+	if err == syscall.EINVAL && mode == forkWipe {
+		// Only private anonymous mappings can be wiped.
+		err = syscall.Madvise(data, madvDontFork)
+	}
+	return err
+}
 
-	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), prot, syscall.MAP_SHARED)
+func lockFile(fd *os.File, mode LockMode, block bool) (bool, error) {
+	var how int
+	switch mode {
+	case Shared:
+		how = syscall.LOCK_SH
+	case Exclusive:
+		how = syscall.LOCK_EX
+	default:
+		return false, errLockMode
+	}
+	if !block {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		err := syscall.Flock(int(fd.Fd()), how)
+		switch {
+		case err == syscall.EINTR:
+			continue
+		case err == syscall.EWOULDBLOCK && !block:
+			return false, nil
+		case err != nil:
+			return false, pathError("mmap.lock", fd.Name(), err)
+		}
+		return true, nil
+	}
+}
+
+func unlockFile(fd *os.File) error {
+	err := syscall.Flock(int(fd.Fd()), syscall.LOCK_UN)
 	if err != nil {
-		return nil, fmt.Errorf("mmap: could not mmap %q: %w", filename, err)
+		return pathError("mmap.unlock", fd.Name(), err)
 	}
-	r := &File{
-		data: data,
-		fd:   f,
-		flag: fl,
-		fi:   fi,
+	return nil
+}
+
+// Prefetch asks the OS to read the [off, off+n) range of the file into
+// memory ahead of access.
+// Prefetch returns without waiting for the data to be read.
+func (f *File) Prefetch(off, n int64) error {
+	if f.reports() {
+		return f.chunked(off, n, f.prefetch)
 	}
-	runtime.SetFinalizer(r, (*File).Close)
-	return r, nil
+	return f.prefetch(off, n)
 }
 
-// Sync commits the current contents of the file to stable storage.
-func (f *File) Sync() error {
-	if !f.wflag() {
-		return errBadFD
+// prefetch asks the OS to read the [off, off+n) range of the file into
+// memory.
+func (f *File) prefetch(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	err = syscall.Madvise(b, syscall.MADV_WILLNEED)
+	if err != nil {
+		return fmt.Errorf("mmap: could not prefetch: %w", err)
 	}
-	return syscall.Msync(f.data, syscall.MS_SYNC)
+	return nil
+}
+
+// Release tells the OS that the [off, off+n) range of the file is not
+// needed anymore, so the pages backing it may be reclaimed.
+// Whole pages overlapping the range are released.
+// Released pages are transparently read back from the file on next access.
+// Release fails for snapshots, whose pages written to would be lost.
+func (f *File) Release(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil {
+		return err
+	}
+	if f.cfg.private {
+		return fmt.Errorf("mmap: could not release private mapping of %q: %w", f.fd.Name(), errUnsupported)
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	err = syscall.Madvise(b, syscall.MADV_DONTNEED)
+	if err != nil {
+		return fmt.Errorf("mmap: could not release: %w", err)
+	}
+	return nil
+}
+
+// advise applies the access pattern hint adv to the whole mapping.
+func (f *File) advise(adv advice) error {
+	if len(f.data) == 0 {
+		return nil
+	}
+	return syscall.Madvise(f.data, adv.madvise())
+}
+
+// AdviseHugePages advises the OS to back the mapping with transparent huge
+// pages if enable is true, or to stop doing so otherwise.
+// Huge pages speed up scanning large mappings, but may hurt the latency of
+// random writes.
+//
+// Unlike WithLargePages, AdviseHugePages applies to regular filesystems.
+// It is only honored on Linux, for files mapped as a whole: it is a no-op
+// otherwise.
+func (f *File) AdviseHugePages(enable bool) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if f.closed() {
+		return errClosed
+	}
+	adv := madvNoHugePage
+	if enable {
+		adv = madvHugePage
+	}
+	if adv == 0 || len(f.data) == 0 {
+		return nil
+	}
+	err := syscall.Madvise(f.data, adv)
+	if err != nil {
+		return fmt.Errorf("mmap: could not advise huge pages: %w", err)
+	}
+	return nil
+}
+
+// dontDump excludes the mapping of the file from core dumps, where
+// supported.
+func (f *File) dontDump() error {
+	if madvDontDump == 0 || len(f.data) == 0 {
+		return nil
+	}
+	return syscall.Madvise(f.data, madvDontDump)
+}
+
+// LockRange locks the [off, off+n) range of the mapping in memory, reading
+// it in first, so that accessing it never waits for storage.
+// Whole pages overlapping the range are locked, until UnlockRange is
+// called or the file is closed.
+// The amount of memory a process may lock is limited by the OS.
+func (f *File) LockRange(off, n int64) error {
+	b, err := f.lockRegion(off, n)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	err = syscall.Mlock(b)
+	if err != nil {
+		return fmt.Errorf("mmap: could not lock range: %w", err)
+	}
+	return nil
+}
+
+// LockRangeOnFault locks the pages of the [off, off+n) range of the
+// mapping in memory as they are accessed, rather than reading them in
+// first, so that only the touched subset of a large range counts against
+// the limit of locked memory of the process.
+// It is only supported on Linux.
+func (f *File) LockRangeOnFault(off, n int64) error {
+	b, err := f.lockRegion(off, n)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	err = mlockOnFault(b)
+	if err != nil {
+		return fmt.Errorf("mmap: could not lock range: %w", err)
+	}
+	return nil
+}
+
+// UnlockRange unlocks the [off, off+n) range of the mapping, which may then
+// be paged out again.
+func (f *File) UnlockRange(off, n int64) error {
+	b, err := f.lockRegion(off, n)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	err = syscall.Munlock(b)
+	if err != nil {
+		return fmt.Errorf("mmap: could not unlock range: %w", err)
+	}
+	return nil
+}
+
+// Snapshot returns a private, copy-on-write mapping of the file.
+// Writes to the snapshot are never carried to the file, and Sync is a no-op
+// on snapshots.
+//
+// The OS only copies the pages of the snapshot that are written to:
+// the others may still reflect later changes to the file.
+// Callers needing a point-in-time copy of a range must write to it (or
+// read it) from the snapshot before the file is modified.
+func (f *File) Snapshot() (*File, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+
+	fd, err := syscall.Dup(int(f.fd.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not duplicate file descriptor: %w", err)
+	}
+	syscall.CloseOnExec(fd)
+
+	s := &File{
+		fd:   os.NewFile(uintptr(fd), f.fd.Name()),
+		name: f.name,
+		flag: Read | Write,
+		cfg:  f.cfg.snapshot(),
+	}
+	s.origin = f.reopenOptions()
+	err = s.mapFile()
+	if err != nil {
+		_ = s.fd.Close()
+		return nil, err
+	}
+	s.opened()
+	return s, nil
+}
+
+// sync commits the current contents of the file to stable storage.
+func (f *File) sync() error {
+	if f.w != nil {
+		f.w.mu.Lock()
+		defer f.w.mu.Unlock()
+		if f.w.data != nil {
+			err := f.msync(f.w.data)
+			if err != nil {
+				return err
+			}
+		}
+		return pathError("mmap.sync", f.fd.Name(), f.fd.Sync())
+	}
+	if f.cfg.dirty {
+		return f.syncDirty()
+	}
+	if f.parallelSync() {
+		return f.syncParallel(func(b []byte) error {
+			return f.msync(b)
+		})
+	}
+	return f.msync(f.data)
+}
+
+// shareFlags returns the mmap flags of the shared mappings of the file.
+func (f *File) shareFlags() int {
+	if f.cfg.mapSync {
+		return mapSync
+	}
+	return syscall.MAP_SHARED
+}
+
+// syncFD commits the buffers of the descriptor to stable storage.
+func (f *File) syncFD() error {
+	if f.w != nil && fsyncFull {
+		// Syncing windowed files already syncs their descriptor.
+		return nil
+	}
+	return pathError("mmap.sync", f.fd.Name(), fullFsync(f.fd))
+}
+
+// syncDirty commits the pages written to since the last sync to stable
+// storage, and resets their tracking.
+// The tracking is reset before the pages are committed, so that writes
+// racing with the sync are reported by the next one: once committing them
+// failed, the pages left are no longer reported, and the next sync commits
+// the whole mapping instead.
+func (f *File) syncDirty() error {
+	offs, err := f.dirtyPages(true)
+	if err != nil {
+		return err
+	}
+	if f.resync {
+		err = f.msync(f.data)
+		if err == nil {
+			f.resync = false
+		}
+		return err
+	}
+	page := int64(os.Getpagesize())
+	for i := 0; i < len(offs); {
+		// Coalesce runs of contiguous pages.
+		beg := offs[i]
+		end := beg + page
+		for i++; i < len(offs) && offs[i] == end; i++ {
+			end += page
+		}
+		if end > int64(len(f.data)) {
+			end = int64(len(f.data))
+		}
+		err = f.msync(f.data[beg:end])
+		if err != nil {
+			f.resync = true
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncRange commits the [off, off+n) range of the file to stable storage.
+func (f *File) SyncRange(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil || !f.wflag() || f.cfg.private || f.cfg.noSync {
+		return err
+	}
+	if f.w != nil {
+		return f.Sync()
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return f.msync(b)
+}
+
+// dirtyCopied reports whether files mapped WithDirtyTracking hold their
+// writes in a copy of the file until synced. On unix, they are mapped from
+// the page cache, as other files.
+const dirtyCopied = false
+
+// cloneSeesWrites reports whether clones of the descriptor of a file hold
+// the writes to its mapping not synced yet. On unix, the pages of the
+// mapping are the ones of the page cache.
+const cloneSeesWrites = true
+
+// flushRange commits the [off, off+n) range of the mapping to stable
+// storage, as SyncRange does.
+func (f *File) flushRange(off, n int64) error {
+	return f.SyncRange(off, n)
+}
+
+// flushFile commits the buffers of the file to stable storage once its
+// mapping was synced, which msync already did.
+func (f *File) flushFile() error {
+	return nil
 }
 
 // Close closes the memory-mapped file.
+// The errors of unmapping the file and of closing its descriptor are both
+// reported.
 func (f *File) Close() error {
-	if f.data == nil {
+	if f.closed() {
 		return nil
 	}
-	defer f.Close()
+	f.closing()
+	f.stopWatch()
+	f.mapper.forget(f)
+	unpinErr := f.unpin()
+	if f.release() {
+		return unpinErr
+	}
 
-	data := f.data
-	f.data = nil
-	runtime.SetFinalizer(f, nil)
-	return syscall.Munmap(data)
+	beg, size := time.Now(), f.size()
+	name := f.fd.Name()
+	err := joinErrors(unpinErr, f.syncOnClose(), pathError("mmap.close", name, f.unmapFile()), pathError("mmap.close", name, f.fd.Close()))
+	f.isClosed = true
+	f.logEvent("close", size, beg, err)
+	return err
+}
+
+func (f *File) mapView(off int64, n int) ([]byte, error) {
+	data, err := f.mmap(int(f.fd.Fd()), off, 0, n, f.flag.prot(), f.shareFlags())
+	if err != nil {
+		return nil, pathError("mmap.map", f.fd.Name(), err)
+	}
+	if f.cfg.numa {
+		err = bindNode(data, f.cfg.node)
+		if err != nil {
+			_ = munmap(data)
+			return nil, fmt.Errorf("mmap: could not bind view at offset %d to NUMA node %d: %w", off, f.cfg.node, err)
+		}
+	}
+	err = f.cfg.fork.apply(data)
+	if err != nil {
+		_ = munmap(data)
+		return nil, fmt.Errorf("mmap: could not set fork inheritance of view at offset %d: %w", off, err)
+	}
+	return data, nil
+}
+
+func (f *File) unmapView(data []byte) error {
+	return f.munmap(data)
+}
+
+// syncView commits the view data to stable storage.
+func (f *File) syncView(data []byte) error {
+	return f.msync(data)
+}
+
+// allocGranularity returns the alignment of the offsets files are mapped
+// from: the page size.
+func allocGranularity() int64 {
+	return int64(os.Getpagesize())
+}
+
+// mmap calls mmap, unless a fault is injected.
+func (f *File) mmap(fd int, off int64, addr uintptr, n, prot, flags int) ([]byte, error) {
+	if err := f.fault(SyscallMmap); err != nil {
+		return nil, err
+	}
+	return mmap(fd, off, addr, n, prot, flags)
+}
+
+// munmap calls munmap, unless a fault is injected.
+func (f *File) munmap(data []byte) error {
+	if err := f.fault(SyscallMunmap); err != nil {
+		return err
+	}
+	return munmap(data)
+}
+
+// msync calls msync, unless a fault is injected.
+func (f *File) msync(b []byte) error {
+	if err := f.fault(SyscallMsync); err != nil {
+		return pathError("mmap.sync", f.fd.Name(), err)
+	}
+	return pathError("mmap.sync", f.fd.Name(), syscall.Msync(b, syscall.MS_SYNC))
+}
+
+// mmap maps n bytes of the file fd, starting at off, at the address addr.
+// If addr is zero, the OS chooses the address of the mapping.
+func mmap(fd int, off int64, addr uintptr, n, prot, flags int) ([]byte, error) {
+	if addr != 0 {
+		flags |= mapFixed
+	}
+	// addr is not a Go pointer: convert it without going through a uintptr
+	// expression, which is what the unsafe.Pointer rules disallow.
+	hint := *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+	ptr, err := syscall.MmapPtr(fd, off, hint, uintptr(n), prot, flags)
+	if err != nil {
+		if addr != 0 && err == errAddrInUse {
+			return nil, ErrAddrNotAvailable
+		}
+		return nil, err
+	}
+	data := unsafe.Slice((*byte)(ptr), n)
+	if addr != 0 && uintptr(ptr) != addr {
+		// The OS only took addr as a hint.
+		_ = munmap(data)
+		return nil, ErrAddrNotAvailable
+	}
+	return data, nil
+}
+
+func munmap(data []byte) error {
+	return syscall.MunmapPtr(unsafe.Pointer(&data[0]), uintptr(len(data)))
+}
+
+// syncDir commits the entries of the directory dir to stable storage.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if e := d.Close(); err == nil {
+		err = e
+	}
+	return err
 }
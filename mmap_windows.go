@@ -7,97 +7,993 @@ package mmap
 import (
 	"fmt"
 	"os"
-	"runtime"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 	"unsafe"
 
 	syscall "golang.org/x/sys/windows"
 )
 
-func openFile(filename string, fl Flag) (*File, error) {
-	f, err := os.OpenFile(filename, fl.flag(), 0666)
+var (
+	modkernel32 = syscall.NewLazySystemDLL("kernel32.dll")
+
+	procGetDiskFreeSpaceW     = modkernel32.NewProc("GetDiskFreeSpaceW")
+	procMapViewOfFileEx       = modkernel32.NewProc("MapViewOfFileEx")
+	procPrefetchVirtualMemory = modkernel32.NewProc("PrefetchVirtualMemory")
+	procGetSystemInfo         = modkernel32.NewProc("GetSystemInfo")
+	procGetWriteWatch         = modkernel32.NewProc("GetWriteWatch")
+	procResetWriteWatch       = modkernel32.NewProc("ResetWriteWatch")
+)
+
+func openFile(filename string, fl Flag, cfg options) (*File, error) {
+	f, err := open(filename, fl, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	fi, err := f.Stat()
+	if cfg.lock != 0 {
+		_, err = lockFile(f, cfg.lock, true)
+		if err != nil {
+			_ = f.Close()
+			return nil, pathError("mmap.lock", filename, err)
+		}
+	}
+
+	fd := &File{
+		fd:   f,
+		name: filename,
+		flag: fl,
+		cfg:  cfg,
+	}
+	fd.checkRemote()
+	err = fd.mapFile()
+	if err == nil {
+		err = fd.checkAlign()
+	}
 	if err != nil {
+		_ = fd.unmapFile()
+		_ = f.Close()
 		return nil, err
 	}
 
-	size := fi.Size()
+	if cfg.watch {
+		err = fd.startWatch()
+		if err != nil {
+			_ = fd.unmapFile()
+			_ = f.Close()
+			return nil, pathError("mmap.watch", filename, err)
+		}
+	}
+
+	fd.opened()
+	return fd, nil
+}
+
+// mapFile maps the file in memory, according to its current size.
+func (f *File) mapFile() error {
+	filename := f.fd.Name()
+	fi, err := f.fd.Stat()
+	if err != nil {
+		return err
+	}
+	f.fi = fi
+
+	size, err := f.mapSize(fi)
+	if err != nil {
+		return err
+	}
 	if size == 0 {
-		return &File{fd: f, flag: fl, fi: fi}, nil
+		f.empty.Store(true)
+		return nil
 	}
+	// Files mapped empty are only reported as mapped once their mapping is
+	// published, for readers not to observe them half-mapped.
+	defer f.empty.Store(false)
 	if size < 0 {
-		return nil, fmt.Errorf("mmap: file %q has negative size", filename)
+		return fmt.Errorf("mmap: file %q has negative size", filename)
 	}
-	if size != int64(int(size)) {
-		return nil, fmt.Errorf("mmap: file %q is too large", filename)
+	if f.cfg.direct {
+		f.w = &window{size: size, direct: true}
+		return nil
 	}
+	if f.cfg.mapSync {
+		return fmt.Errorf("mmap: file %q can not be mapped synchronously: %w", filename, errUnsupported)
+	}
+
+	prot, view := f.access()
 
-	prot := uint32(syscall.PAGE_READONLY)
-	view := uint32(syscall.FILE_MAP_READ)
-	if fl&Write != 0 {
-		prot = syscall.PAGE_READWRITE
-		view = syscall.FILE_MAP_WRITE
+	if tooLarge(size) && f.cfg.addr != 0 {
+		return fmt.Errorf("mmap: file %q is too large to be mapped at a fixed address", filename)
+	}
+	if tooLarge(size) || f.cfg.window > 0 {
+		if f.cfg.private {
+			return fmt.Errorf("mmap: file %q is too large to be snapshotted", filename)
+		}
+		if f.cfg.dirty {
+			return fmt.Errorf("mmap: file %q is too large to track dirty pages", filename)
+		}
+		low, high := uint32(size), uint32(size>>32)
+		fmap, err := f.createFileMapping(prot, high, low)
+		if err != nil {
+			return f.fallback(size, pathError("mmap.map", filename, err))
+		}
+		f.w = newWindow(size, f.cfg.window)
+		f.w.fmap = uintptr(fmap)
+		return nil
 	}
+	if overBudget(size) && f.cfg.addr == 0 && !f.cfg.private && !f.cfg.dirty {
+		low, high := uint32(size), uint32(size>>32)
+		fmap, err := f.createFileMapping(prot, high, low)
+		if err != nil {
+			return f.fallback(size, pathError("mmap.map", filename, err))
+		}
+		f.w = newWindow(size, budgetSpan())
+		f.w.fmap = uintptr(fmap)
+		return nil
+	}
+	reserved, err := f.reserve(nil, size)
+	if err != nil {
+		return err
+	}
+	defer unreserve(reserved)
 
+	if f.cfg.dirty {
+		return f.mapWatched(size)
+	}
+
+	// WithLargePages is not honored: SEC_LARGE_PAGES only applies to
+	// sections backed by the paging file, and never to the ones of files.
 	low, high := uint32(size), uint32(size>>32)
-	fmap, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, prot, high, low, nil)
+	fmap, err := f.createFileMapping(prot, high, low)
 	if err != nil {
-		return nil, err
+		return f.fallback(size, pathError("mmap.map", filename, err))
 	}
 	defer syscall.CloseHandle(fmap)
-	ptr, err := syscall.MapViewOfFile(fmap, view, 0, 0, uintptr(size))
+	ptr, err := f.mapViewAt(fmap, view, 0, uintptr(size), f.cfg.addr)
+	if err != nil {
+		return f.fallback(size, pathError("mmap.map", filename, err))
+	}
+	f.data = sliceAt(ptr, int(size))
+	statMap(f.data)
+	if f.cfg.populate {
+		_ = f.prefetch(0, size)
+	}
+	return nil
+}
+
+// unmapFile releases the memory mapping of the file.
+func (f *File) unmapFile() error {
+	if f.w != nil {
+		w := f.w
+		f.w = nil
+		if w.fmap != 0 {
+			defer syscall.CloseHandle(syscall.Handle(w.fmap))
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.unmap(f)
+	}
+	if f.data == nil {
+		return nil
+	}
+	if f.cfg.dirty {
+		return f.unmapWatched()
+	}
+	if err := f.fault(SyscallMunmap); err != nil {
+		return err
+	}
+	statUnmap(f.data)
+	addr := f.addr()
+	f.data = nil
+	return syscall.UnmapViewOfFile(addr)
+}
+
+// tooLarge reports whether size bytes are too large to be mapped as a
+// whole, and must be mapped through a sliding window: mappings are bounded
+// to maxBytes bytes.
+func tooLarge(size int64) bool {
+	return size > maxView || size > maxBytes
+}
+
+func (f *File) access() (prot, view uint32) {
+	if f.cfg.private {
+		return syscall.PAGE_WRITECOPY, syscall.FILE_MAP_COPY
+	}
+	if f.flag&Write != 0 {
+		return syscall.PAGE_READWRITE, syscall.FILE_MAP_WRITE
+	}
+	return syscall.PAGE_READONLY, syscall.FILE_MAP_READ
+}
+
+// mapViewAt maps n bytes of fmap, starting at off, at the address addr.
+// If addr is zero, the OS chooses the address of the view.
+func mapViewAt(fmap syscall.Handle, view uint32, off int64, n, addr uintptr) (uintptr, error) {
+	low, high := uint32(off), uint32(off>>32)
+	if addr == 0 {
+		return syscall.MapViewOfFile(fmap, view, high, low, n)
+	}
+
+	r1, _, e1 := procMapViewOfFileEx.Call(
+		uintptr(fmap), uintptr(view), uintptr(high), uintptr(low), n, addr,
+	)
+	if r1 == 0 {
+		if e1 == syscall.ERROR_INVALID_ADDRESS {
+			return 0, ErrAddrNotAvailable
+		}
+		return 0, e1
+	}
+	return r1, nil
+}
+
+// open opens the named file with CreateFile, as access hints such as
+// FILE_FLAG_SEQUENTIAL_SCAN and share modes can only be given when the
+// handle is created.
+func open(filename string, fl Flag, cfg options) (*os.File, error) {
+	var access uint32
+	switch fl {
+	case Write:
+		access = syscall.GENERIC_WRITE
+	case Read | Write:
+		access = syscall.GENERIC_READ | syscall.GENERIC_WRITE
+	default:
+		access = syscall.GENERIC_READ
+	}
+
+	var share uint32
+	if cfg.share&ShareRead != 0 {
+		share |= syscall.FILE_SHARE_READ
+	}
+	if cfg.share&ShareWrite != 0 {
+		share |= syscall.FILE_SHARE_WRITE
+	}
+	if cfg.share&ShareDelete != 0 {
+		share |= syscall.FILE_SHARE_DELETE
+	}
+
+	var mode uint32
+	switch {
+	case cfg.excl:
+		mode = syscall.CREATE_NEW
+	case cfg.create && cfg.trunc:
+		mode = syscall.CREATE_ALWAYS
+	case cfg.create:
+		mode = syscall.OPEN_ALWAYS
+	case cfg.trunc:
+		mode = syscall.TRUNCATE_EXISTING
+	default:
+		mode = syscall.OPEN_EXISTING
+	}
+
+	var sa *syscall.SecurityAttributes
+	if cfg.inherit {
+		sa = &syscall.SecurityAttributes{InheritHandle: 1}
+		sa.Length = uint32(unsafe.Sizeof(*sa))
+	}
+
+	attrs := uint32(syscall.FILE_ATTRIBUTE_NORMAL)
+	if cfg.create && cfg.perm&0200 == 0 {
+		attrs = syscall.FILE_ATTRIBUTE_READONLY
+	}
+	if cfg.noFollow {
+		attrs |= syscall.FILE_FLAG_OPEN_REPARSE_POINT
+	}
+	switch cfg.advice {
+	case adviceSequential:
+		attrs |= syscall.FILE_FLAG_SEQUENTIAL_SCAN
+	case adviceRandom:
+		attrs |= syscall.FILE_FLAG_RANDOM_ACCESS
+	}
+
+	var (
+		h   syscall.Handle
+		err error
+	)
+	if cfg.dir != nil {
+		h, err = openAt(cfg.dir, filename, access, share, mode, attrs, cfg.inherit)
+		if err != nil {
+			return nil, &os.PathError{Op: "mmap.open", Path: filename, Err: err}
+		}
+	} else {
+		var name *uint16
+		name, err = syscall.UTF16PtrFromString(longPath(filename))
+		if err == nil {
+			h, err = syscall.CreateFile(name, access, share, sa, mode, attrs, 0)
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "mmap.open", Path: filename, Err: err}
+		}
+	}
+	if cfg.noFollow {
+		var info syscall.ByHandleFileInformation
+		err = syscall.GetFileInformationByHandle(h, &info)
+		if err == nil && info.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+			err = syscall.ERROR_CANT_RESOLVE_FILENAME
+		}
+		if err != nil {
+			_ = syscall.CloseHandle(h)
+			return nil, &os.PathError{Op: "mmap.open", Path: filename, Err: err}
+		}
+	}
+	if cfg.dir != nil {
+		filename = filepath.Join(cfg.dir.Name(), filename)
+	}
+	return os.NewFile(uintptr(h), filename), nil
+}
+
+// openAt opens the named file relative to the directory dir with
+// NtCreateFile, translating the parameters of CreateFile.
+func openAt(dir *os.File, filename string, access, share, mode, attrs uint32, inherit bool) (syscall.Handle, error) {
+	name, err := syscall.NewNTUnicodeString(strings.ReplaceAll(filename, "/", `\`))
 	if err != nil {
+		return 0, err
+	}
+	oa := syscall.OBJECT_ATTRIBUTES{
+		RootDirectory: syscall.Handle(dir.Fd()),
+		ObjectName:    name,
+		Attributes:    syscall.OBJ_CASE_INSENSITIVE,
+	}
+	oa.Length = uint32(unsafe.Sizeof(oa))
+	if inherit {
+		oa.Attributes |= syscall.OBJ_INHERIT
+	}
+
+	var disposition uint32
+	switch mode {
+	case syscall.CREATE_NEW:
+		disposition = syscall.FILE_CREATE
+	case syscall.CREATE_ALWAYS:
+		disposition = syscall.FILE_OVERWRITE_IF
+	case syscall.OPEN_ALWAYS:
+		disposition = syscall.FILE_OPEN_IF
+	case syscall.TRUNCATE_EXISTING:
+		disposition = syscall.FILE_OVERWRITE
+	default:
+		disposition = syscall.FILE_OPEN
+	}
+
+	options := uint32(syscall.FILE_NON_DIRECTORY_FILE | syscall.FILE_SYNCHRONOUS_IO_NONALERT)
+	if attrs&syscall.FILE_FLAG_SEQUENTIAL_SCAN != 0 {
+		options |= syscall.FILE_SEQUENTIAL_ONLY
+	}
+	if attrs&syscall.FILE_FLAG_RANDOM_ACCESS != 0 {
+		options |= syscall.FILE_RANDOM_ACCESS
+	}
+	if attrs&syscall.FILE_FLAG_OPEN_REPARSE_POINT != 0 {
+		options |= syscall.FILE_OPEN_REPARSE_POINT
+	}
+
+	var (
+		h    syscall.Handle
+		iosb syscall.IO_STATUS_BLOCK
+	)
+	access |= syscall.SYNCHRONIZE | syscall.FILE_READ_ATTRIBUTES
+	err = syscall.NtCreateFile(&h, access, &oa, &iosb, nil, attrs&0xffff, share, disposition, options, 0, 0)
+	if st, ok := err.(syscall.NTStatus); ok {
+		return 0, st.Errno()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return h, nil
+}
+
+// Prefetch asks the OS to read the [off, off+n) range of the file into
+// memory ahead of access.
+// Prefetch is a no-op on Windows versions without PrefetchVirtualMemory.
+// It may block while the read requests are issued: callers wanting to
+// fully overlap I/O with computation should use PrefetchAsync.
+func (f *File) Prefetch(off, n int64) error {
+	if f.reports() {
+		return f.chunked(off, n, f.prefetch)
+	}
+	return f.prefetch(off, n)
+}
+
+// prefetch asks the OS to read the [off, off+n) range of the file into
+// memory.
+func (f *File) prefetch(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 || procPrefetchVirtualMemory.Find() != nil {
+		return nil
+	}
+
+	// WIN32_MEMORY_RANGE_ENTRY
+	entry := struct {
+		addr uintptr
+		size uintptr
+	}{
+		addr: uintptr(unsafe.Pointer(&b[0])),
+		size: uintptr(len(b)),
+	}
+	r1, _, e1 := procPrefetchVirtualMemory.Call(
+		uintptr(syscall.CurrentProcess()), 1, uintptr(unsafe.Pointer(&entry)), 0,
+	)
+	if r1 == 0 {
+		return fmt.Errorf("mmap: could not prefetch: %w", e1)
+	}
+	return nil
+}
+
+// Release tells the OS that the [off, off+n) range of the file is not
+// needed anymore, so the pages backing it may be reclaimed.
+// Whole pages overlapping the range are released.
+// Released pages are transparently read back from the file on next access.
+// Release fails for snapshots, whose pages written to would be lost.
+func (f *File) Release(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil {
+		return err
+	}
+	if f.cfg.private {
+		return fmt.Errorf("mmap: could not release private mapping of %q: %w", f.fd.Name(), errUnsupported)
+	}
+	if len(b) == 0 {
+		return nil
+	}
+
+	// OfferVirtualMemory only applies to private memory.
+	// Unlocking pages that are not locked removes them from the working set
+	// of the process instead, which is what we want for file-backed views.
+	err = syscall.VirtualUnlock(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)))
+	if err != nil && err != syscall.ERROR_NOT_LOCKED {
+		return fmt.Errorf("mmap: could not release: %w", err)
+	}
+	return nil
+}
+
+// dontDump excludes the mapping of the file from core dumps, where
+// supported.
+// Windows offers no way to do so.
+func (f *File) dontDump() error {
+	return nil
+}
+
+// LockRange locks the [off, off+n) range of the mapping in memory, reading
+// it in first, so that accessing it never waits for storage.
+// Whole pages overlapping the range are locked, until UnlockRange is
+// called or the file is closed.
+// The amount of memory a process may lock is limited by the OS.
+func (f *File) LockRange(off, n int64) error {
+	b, err := f.lockRegion(off, n)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	err = syscall.VirtualLock(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)))
+	if err != nil {
+		return fmt.Errorf("mmap: could not lock range: %w", err)
+	}
+	return nil
+}
+
+// LockRangeOnFault locks the pages of the [off, off+n) range of the
+// mapping in memory as they are accessed, rather than reading them in
+// first, so that only the touched subset of a large range counts against
+// the limit of locked memory of the process.
+// It is only supported on Linux.
+func (f *File) LockRangeOnFault(off, n int64) error {
+	_, err := f.lockRegion(off, n)
+	if err != nil {
+		return err
+	}
+	return errUnsupported
+}
+
+// UnlockRange unlocks the [off, off+n) range of the mapping, which may then
+// be paged out again.
+func (f *File) UnlockRange(off, n int64) error {
+	b, err := f.lockRegion(off, n)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	err = syscall.VirtualUnlock(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)))
+	if err != nil && err != syscall.ERROR_NOT_LOCKED {
+		return fmt.Errorf("mmap: could not unlock range: %w", err)
+	}
+	return nil
+}
+
+// advise applies the access pattern hint adv to the whole mapping.
+// Windows only takes hints when files are opened.
+func (f *File) advise(adv advice) error {
+	return nil
+}
+
+// AdviseHugePages advises the OS to back the mapping with transparent huge
+// pages if enable is true, or to stop doing so otherwise.
+// Huge pages speed up scanning large mappings, but may hurt the latency of
+// random writes.
+//
+// Unlike WithLargePages, AdviseHugePages applies to regular filesystems.
+// It is only honored on Linux, for files mapped as a whole: it is a no-op
+// otherwise.
+func (f *File) AdviseHugePages(enable bool) error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	if f.closed() {
+		return errClosed
+	}
+	return nil
+}
+
+func lockFile(fd *os.File, mode LockMode, block bool) (bool, error) {
+	var flags uint32
+	switch mode {
+	case Shared:
+	case Exclusive:
+		flags = syscall.LOCKFILE_EXCLUSIVE_LOCK
+	default:
+		return false, errLockMode
+	}
+	if !block {
+		flags |= syscall.LOCKFILE_FAIL_IMMEDIATELY
+	}
+
+	ol := new(syscall.Overlapped)
+	err := syscall.LockFileEx(syscall.Handle(fd.Fd()), flags, 0, ^uint32(0), ^uint32(0), ol)
+	switch {
+	case err == syscall.ERROR_LOCK_VIOLATION && !block:
+		return false, nil
+	case err != nil:
+		return false, pathError("mmap.lock", fd.Name(), err)
+	}
+	return true, nil
+}
+
+func unlockFile(fd *os.File) error {
+	ol := new(syscall.Overlapped)
+	err := syscall.UnlockFileEx(syscall.Handle(fd.Fd()), 0, ^uint32(0), ^uint32(0), ol)
+	if err != nil {
+		return pathError("mmap.unlock", fd.Name(), err)
+	}
+	return nil
+}
+
+// duplicateExtentsData is the DUPLICATE_EXTENTS_DATA structure.
+type duplicateExtentsData struct {
+	FileHandle       syscall.Handle
+	_                [8 - unsafe.Sizeof(syscall.Handle(0))]byte
+	SourceFileOffset int64
+	TargetFileOffset int64
+	ByteCount        int64
+}
+
+// cloneFile creates a file at path sharing the data blocks of src.
+// It reports whether the file was created.
+func cloneFile(src *os.File, path string, perm os.FileMode) (bool, error) {
+	fi, err := src.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// Block cloning works on whole clusters of the volume.
+	cluster, err := clusterSize(path)
+	if err != nil {
+		return false, nil
+	}
+
+	dst, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return false, err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			_ = dst.Close()
+			_ = os.Remove(path)
+		}
+	}()
+
+	size := fi.Size()
+	err = dst.Truncate(size)
+	if err != nil {
+		return false, err
+	}
+
+	req := duplicateExtentsData{
+		FileHandle: syscall.Handle(src.Fd()),
+		ByteCount:  (size + cluster - 1) / cluster * cluster,
+	}
+	var n uint32
+	err = syscall.DeviceIoControl(
+		syscall.Handle(dst.Fd()), syscall.FSCTL_DUPLICATE_EXTENTS_TO_FILE,
+		(*byte)(unsafe.Pointer(&req)), uint32(unsafe.Sizeof(req)), nil, 0, &n, nil,
+	)
+	if err != nil {
+		// Only ReFS supports block cloning.
+		return false, nil
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return false, err
+	}
+	ok = true
+	return true, nil
+}
+
+// clusterSize returns the size of the clusters of the volume holding path.
+func clusterSize(path string) (int64, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	root, err := syscall.UTF16PtrFromString(filepath.VolumeName(abs) + `\`)
+	if err != nil {
+		return 0, err
+	}
+
+	var sectors, bytes, free, total uint32
+	r1, _, e1 := procGetDiskFreeSpaceW.Call(
+		uintptr(unsafe.Pointer(root)),
+		uintptr(unsafe.Pointer(&sectors)),
+		uintptr(unsafe.Pointer(&bytes)),
+		uintptr(unsafe.Pointer(&free)),
+		uintptr(unsafe.Pointer(&total)),
+	)
+	if r1 == 0 {
+		return 0, e1
+	}
+	return int64(sectors) * int64(bytes), nil
+}
+
+// longPath returns the extended-length form of path when it is too long
+// to be handled by the regular Windows API.
+func longPath(path string) string {
+	// CreateFile is limited to MAX_PATH, but CreateDirectory's limit of
+	// 248 characters is used, as os.OpenFile does.
+	const maxPath = 248
+	if len(path) < maxPath {
+		return path
+	}
+	if strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return path
+	}
+
+	// Extended-length paths are not normalized by the OS: they must be
+	// absolute and clean.
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// Snapshot returns a private, copy-on-write mapping of the file.
+// Writes to the snapshot are never carried to the file, and Sync is a no-op
+// on snapshots.
+//
+// The OS only copies the pages of the snapshot that are written to:
+// the others may still reflect later changes to the file.
+// Callers needing a point-in-time copy of a range must write to it (or
+// read it) from the snapshot before the file is modified.
+func (f *File) Snapshot() (*File, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+
+	var h syscall.Handle
+	proc := syscall.CurrentProcess()
+	err := syscall.DuplicateHandle(
+		proc, syscall.Handle(f.fd.Fd()), proc, &h,
+		0, false, syscall.DUPLICATE_SAME_ACCESS,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not duplicate file handle: %w", err)
+	}
+
+	s := &File{
+		fd:   os.NewFile(uintptr(h), f.fd.Name()),
+		name: f.name,
+		flag: Read | Write,
+		cfg:  f.cfg.snapshot(),
+	}
+	s.origin = f.reopenOptions()
+	err = s.mapFile()
+	if err != nil {
+		_ = s.fd.Close()
 		return nil, err
 	}
-	data := (*[maxBytes]byte)(unsafe.Pointer(ptr))[:size]
+	s.opened()
+	return s, nil
+}
 
-	fd := &File{
-		data: data,
-		fd:   f,
-		fi:   fi,
-		flag: fl,
+// sync commits the current contents of the file to stable storage.
+func (f *File) sync() error {
+	if f.cfg.dirty {
+		return f.syncWatched()
 	}
-	runtime.SetFinalizer(fd, (*File).Close)
-	return fd, nil
 
+	if f.w != nil {
+		f.w.mu.Lock()
+		defer f.w.mu.Unlock()
+		if f.w.data == nil {
+			return f.flushFile()
+		}
+		return f.flush(uintptr(unsafe.Pointer(&f.w.data[0])), len(f.w.data))
+	}
+	if f.parallelSync() {
+		err := f.syncParallel(func(b []byte) error {
+			err := f.fault(SyscallMsync)
+			if err == nil {
+				err = syscall.FlushViewOfFile(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)))
+			}
+			if err != nil {
+				return pathError("mmap.sync", f.fd.Name(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return f.flushFile()
+	}
+	return f.flush(f.addr(), len(f.data))
 }
 
-// Sync commits the current contents of the file to stable storage.
-func (f *File) Sync() error {
-	if !f.wflag() {
-		return errBadFD
+// SyncRange commits the [off, off+n) range of the file to stable storage.
+// Only the corresponding part of the view is flushed.
+func (f *File) SyncRange(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil || !f.wflag() || f.cfg.private || f.cfg.noSync {
+		return err
+	}
+	if f.w != nil || f.cfg.dirty {
+		return f.Sync()
 	}
+	if len(b) == 0 {
+		return nil
+	}
+	return f.flush(uintptr(unsafe.Pointer(&b[0])), len(b))
+}
 
-	err := syscall.FlushViewOfFile(f.addr(), uintptr(len(f.data)))
+// flushRange writes the [off, off+n) range of the view to the file, without
+// flushing the file buffers: chunked syncs flush them once, at the end.
+func (f *File) flushRange(off, n int64) error {
+	b, err := f.region(off, n)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	return f.flushView(uintptr(unsafe.Pointer(&b[0])), len(b))
+}
+
+// flush writes the n bytes of the view starting at addr to the file, and
+// then the file buffers to stable storage.
+func (f *File) flush(addr uintptr, n int) error {
+	err := f.flushView(addr, n)
+	if err != nil {
+		return err
+	}
+	return f.flushFile()
+}
+
+// flushView writes the n bytes of the view starting at addr to the file.
+func (f *File) flushView(addr uintptr, n int) error {
+	err := f.fault(SyscallMsync)
+	if err == nil {
+		err = syscall.FlushViewOfFile(addr, uintptr(n))
+	}
 	if err != nil {
-		return fmt.Errorf("mmap: could not sync view: %w", err)
+		return pathError("mmap.sync", f.fd.Name(), err)
 	}
+	return nil
+}
+
+// fileCopyOffload reports whether copies between files are offloaded to
+// the kernel, or to the filesystem, by (*os.File).ReadFrom. On Windows, it
+// copies them through a buffer.
+const fileCopyOffload = false
+
+// dirtyCopied reports whether files mapped WithDirtyTracking hold their
+// writes in a copy of the file until synced. On Windows, they do.
+const dirtyCopied = true
 
-	err = syscall.FlushFileBuffers(syscall.Handle(f.fd.Fd()))
+// cloneSeesWrites reports whether clones of the descriptor of a file hold
+// the writes to its mapping not synced yet. On Windows, block cloning
+// duplicates the extents of the file on disk, without them.
+const cloneSeesWrites = false
+
+// syncFD commits the buffers of the descriptor to stable storage, which
+// syncing the file already does.
+func (f *File) syncFD() error {
+	return nil
+}
+
+func (f *File) flushFile() error {
+	err := syscall.FlushFileBuffers(syscall.Handle(f.fd.Fd()))
 	if err != nil {
-		return fmt.Errorf("mmap: could not sync file buffers: %w", err)
+		return pathError("mmap.sync", f.fd.Name(), err)
 	}
 
 	return nil
 }
 
-// Close closes the reader.
+// Close closes the memory-mapped file.
+// The errors of unmapping the file and of closing its descriptor are both
+// reported.
 func (f *File) Close() error {
-	if f.data == nil {
+	if f.closed() {
 		return nil
 	}
-	defer f.fd.Close()
+	f.closing()
+	f.stopWatch()
+	f.mapper.forget(f)
+	unpinErr := f.unpin()
+	if f.release() {
+		return unpinErr
+	}
 
-	addr := f.addr()
-	f.data = nil
-	runtime.SetFinalizer(f, nil)
-	return syscall.UnmapViewOfFile(addr)
+	beg, size := time.Now(), f.size()
+	name := f.fd.Name()
+	err := joinErrors(unpinErr, f.syncOnClose(), pathError("mmap.close", name, f.unmapFile()), pathError("mmap.close", name, f.fd.Close()))
+	f.isClosed = true
+	f.logEvent("close", size, beg, err)
+	return err
+}
+
+// sliceAt returns the n bytes of memory mapped at addr by the system.
+func sliceAt(addr uintptr, n int) []byte {
+	// addr is not a Go pointer: convert it without going through a uintptr
+	// expression, which is what the unsafe.Pointer rules disallow.
+	p := *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+	return unsafe.Slice((*byte)(p), n)
 }
 
 func (f *File) addr() uintptr {
 	data := f.data
 	return uintptr(unsafe.Pointer(&data[0]))
 }
+
+func (f *File) mapView(off int64, n int) ([]byte, error) {
+	_, view := f.access()
+	ptr, err := f.mapViewAt(syscall.Handle(f.w.fmap), view, off, uintptr(n), 0)
+	if err != nil {
+		return nil, pathError("mmap.map", f.fd.Name(), err)
+	}
+	return sliceAt(ptr, n), nil
+}
+
+func (f *File) unmapView(data []byte) error {
+	if err := f.fault(SyscallMunmap); err != nil {
+		return err
+	}
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&data[0])))
+}
+
+// syncView commits the view data to stable storage.
+func (f *File) syncView(data []byte) error {
+	return f.flush(uintptr(unsafe.Pointer(&data[0])), len(data))
+}
+
+// systemInfo is the SYSTEM_INFO structure filled by GetSystemInfo.
+type systemInfo struct {
+	processorArchitecture     uint16
+	reserved                  uint16
+	pageSize                  uint32
+	minimumApplicationAddress uintptr
+	maximumApplicationAddress uintptr
+	activeProcessorMask       uintptr
+	numberOfProcessors        uint32
+	processorType             uint32
+	allocationGranularity     uint32
+	processorLevel            uint16
+	processorRevision         uint16
+}
+
+var granularity struct {
+	once sync.Once
+	n    int64
+}
+
+// allocGranularity returns the alignment of the offsets files are mapped
+// from, as reported by GetSystemInfo: 64 KiB on all current versions.
+func allocGranularity() int64 {
+	granularity.once.Do(func() {
+		granularity.n = 64 << 10
+		var info systemInfo
+		if procGetSystemInfo.Find() == nil {
+			procGetSystemInfo.Call(uintptr(unsafe.Pointer(&info)))
+			if info.allocationGranularity != 0 {
+				granularity.n = int64(info.allocationGranularity)
+			}
+		}
+	})
+	return granularity.n
+}
+
+// createFileMapping creates a mapping object for the file, unless a fault
+// is injected.
+func (f *File) createFileMapping(prot, high, low uint32) (syscall.Handle, error) {
+	if err := f.fault(SyscallCreateFileMapping); err != nil {
+		return 0, err
+	}
+	return syscall.CreateFileMapping(syscall.Handle(f.fd.Fd()), nil, prot, high, low, nil)
+}
+
+// mapViewAt calls mapViewAt, unless a fault is injected.
+func (f *File) mapViewAt(fmap syscall.Handle, view uint32, off int64, n, addr uintptr) (uintptr, error) {
+	if err := f.fault(SyscallMmap); err != nil {
+		return 0, err
+	}
+	return mapViewAt(fmap, view, off, n, addr)
+}
+
+// deviceSize returns the size of the block device fd.
+// Windows can not map volumes nor disks: file mappings need a file.
+func deviceSize(fd *os.File) (int64, error) {
+	return 0, errUnsupported
+}
+
+// punchHole deallocates the [off, off+n) range of fd, which then reads back
+// as zeros.
+func punchHole(fd *os.File, off, n int64) error {
+	return errUnsupported
+}
+
+// syncDir commits the entries of the directory dir to stable storage.
+// Windows commits renames with the file system metadata: there is nothing
+// to do.
+func syncDir(dir string) error {
+	return nil
+}
+
+// fileRemoteProtocolInfo is the FILE_REMOTE_PROTOCOL_INFO structure.
+type fileRemoteProtocolInfo struct {
+	StructureVersion uint16
+	StructureSize    uint16
+	Protocol         uint32
+	Major            uint16
+	Minor            uint16
+	Revision         uint16
+	Reserved         uint16
+	Flags            uint32
+	GenericReserved  [8]uint32
+	ProtocolSpecific [16]uint32
+}
+
+// Network providers of the remote protocols.
+const (
+	wnncNetSMB   = 0x00020000
+	wnncNetDAV   = 0x002e0000
+	wnncNetMSNFS = 0x00420000
+)
+
+// remoteFS returns the type of the network or FUSE filesystem fd lives on,
+// or "" if it is local.
+// Files on network shares are told apart by the redirector answering for
+// their remote protocol, and WinFsp FUSE volumes by their filesystem name.
+func remoteFS(fd *os.File) string {
+	h := syscall.Handle(fd.Fd())
+	var info fileRemoteProtocolInfo
+	err := syscall.GetFileInformationByHandleEx(h, syscall.FileRemoteProtocolInfo, (*byte)(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+	if err == nil {
+		switch info.Protocol {
+		case wnncNetSMB:
+			return "smb"
+		case wnncNetDAV:
+			return "webdav"
+		case wnncNetMSNFS:
+			return "nfs"
+		}
+		return "remote"
+	}
+
+	var name [syscall.MAX_PATH + 1]uint16
+	err = syscall.GetVolumeInformationByHandle(h, nil, 0, nil, nil, nil, &name[0], uint32(len(name)))
+	if err == nil && strings.HasPrefix(syscall.UTF16ToString(name[:]), "FUSE") {
+		return "fuse"
+	}
+	return ""
+}
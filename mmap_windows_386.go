@@ -4,4 +4,6 @@
 
 package mmap
 
+// maxBytes is the largest size of the mappings.
+// It bounds mappings to the 2 GiB of user address space of 32-bit processes.
 const maxBytes = 1<<31 - 1
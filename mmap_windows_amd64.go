@@ -4,4 +4,7 @@
 
 package mmap
 
+// maxBytes is the largest size of the mappings.
+// It bounds mappings to 1 PiB, beyond the user address space of 64-bit
+// processes.
 const maxBytes = 1<<50 - 1
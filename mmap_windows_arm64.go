@@ -0,0 +1,10 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+// maxBytes is the largest size of the mappings.
+// It bounds mappings to 1 PiB, beyond the user address space of 64-bit
+// processes, as on amd64.
+const maxBytes = 1<<50 - 1
@@ -0,0 +1,179 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestLongPath(t *testing.T) {
+	long := strings.Repeat("d", 100) + `\` + strings.Repeat("e", 100) + `\` + strings.Repeat("f", 100)
+	abs, err := filepath.Abs(long)
+	if err != nil {
+		t.Fatalf("could not get absolute path: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{`C:\short\path.txt`, `C:\short\path.txt`},
+		{`\\?\C:\` + long, `\\?\C:\` + long},
+		{`\\server\share\` + long, `\\?\UNC\server\share\` + long},
+		{`C:\` + long, `\\?\C:\` + long},
+		{`C:\` + strings.ReplaceAll(long, `\`, `/`), `\\?\C:\` + long},
+		{long, `\\?\` + abs},
+	} {
+		if got := longPath(tc.path); got != tc.want {
+			t.Errorf("invalid long path for %q:\ngot= %q\nwant=%q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestOpenLongPath(t *testing.T) {
+	dir := t.TempDir()
+	for len(dir) < 300 {
+		dir = filepath.Join(dir, strings.Repeat("d", 50))
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatalf("could not create directory: %+v", err)
+	}
+
+	fname := filepath.Join(dir, "file.txt")
+	err = os.WriteFile(fname, []byte("hello world!\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	got := make([]byte, f.Len())
+	_, err = f.ReadAt(got, 0)
+	if err != nil {
+		t.Fatalf("could not read file: %+v", err)
+	}
+	if got, want := got, []byte("hello world!\n"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid content:\ngot= %q\nwant=%q\n", got, want)
+	}
+}
+
+func TestDirtyPages(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "dirty.bin")
+	page := os.Getpagesize()
+	err := os.WriteFile(fname, make([]byte, 4*page), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, WithDirtyTracking())
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	f.Bytes()[page+1] = 'x'
+	f.Bytes()[3*page] = 'y'
+
+	offs, err := f.GetDirtyPages()
+	if err != nil {
+		t.Fatalf("could not get dirty pages: %+v", err)
+	}
+	if len(offs) != 2 || offs[0] != int64(page) || offs[1] != int64(3*page) {
+		t.Fatalf("invalid dirty pages: %v", offs)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	offs, err = f.GetDirtyPages()
+	if err != nil || len(offs) != 0 {
+		t.Fatalf("invalid dirty pages after sync: got=(%v, %v)", offs, err)
+	}
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read back file: %+v", err)
+	}
+	if raw[page+1] != 'x' || raw[3*page] != 'y' {
+		t.Fatalf("dirty pages were not written back")
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	if uint64(maxBytes) > uint64(math.MaxInt) {
+		t.Fatalf("maxBytes %d overflows int", uint64(maxBytes))
+	}
+	// The fake array must fit in the address space.
+	if got := unsafe.Sizeof([maxBytes]byte{}); uint64(got) != uint64(maxBytes) {
+		t.Fatalf("invalid size of fake array: %d", got)
+	}
+
+	whole := int64(maxView)
+	if whole > maxBytes {
+		whole = maxBytes
+	}
+	for _, tc := range []struct {
+		size int64
+		want bool
+	}{
+		{1, false},
+		{whole - 1, false},
+		{whole, false},
+		{whole + 1, true},
+		{maxBytes + 1, true},
+		{math.MaxInt64, true},
+	} {
+		if got := tooLarge(tc.size); got != tc.want {
+			t.Fatalf("invalid tooLarge(%d): got=%v, want=%v", tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestReopenShareMode(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "reopen.bin")
+	err := os.WriteFile(fname, []byte("0123456789"), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := OpenFile(fname, Read|Write, WithShareMode(ShareRead|ShareWrite|ShareDelete))
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+	snap, err := f.Snapshot()
+	if err != nil {
+		t.Fatalf("could not snapshot file: %+v", err)
+	}
+	defer snap.Close()
+
+	// The reopened files share the file with the ones still open, which
+	// would otherwise fail with a sharing violation.
+	for _, g := range []*File{f, snap} {
+		r, err := g.Reopen(Read | Write)
+		if err != nil {
+			t.Fatalf("could not reopen file: %+v", err)
+		}
+		if got, want := r.cfg.share, ShareRead|ShareWrite|ShareDelete; got != want {
+			t.Fatalf("invalid share mode: got=%v, want=%v", got, want)
+		}
+		err = r.Close()
+		if err != nil {
+			t.Fatalf("could not close reopened file: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mmapelf parses ELF binaries from memory-mapped files.
+package mmapelf
+
+import (
+	"debug/elf"
+	"fmt"
+
+	"github.com/go-mmap/mmap"
+)
+
+// File is a ELF binary read from a memory-mapped file.
+type File struct {
+	*elf.File
+
+	f *mmap.File
+}
+
+// Open memory-maps the named ELF binary for reading, and parses it.
+func Open(filename string) (*File, error) {
+	f, err := mmap.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	bin, err := elf.NewFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("mmapelf: could not parse %q: %w", filename, err)
+	}
+	return &File{File: bin, f: f}, nil
+}
+
+// Close closes the binary and unmaps it.
+func (f *File) Close() error {
+	err := f.File.Close()
+	if e := f.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
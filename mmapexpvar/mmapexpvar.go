@@ -0,0 +1,71 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mmapexpvar publishes the statistics and the open files of the
+// mmap package through expvar, under the "mmap" name, so that operators
+// can see what a process has mapped at runtime, from /debug/vars.
+//
+// The package is imported for its side effects:
+//
+//	import _ "github.com/go-mmap/mmap/mmapexpvar"
+//
+// which enables the registry of open files, with mmap.TrackOpenFiles.
+package mmapexpvar
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/go-mmap/mmap"
+)
+
+func init() {
+	mmap.TrackOpenFiles(true)
+	expvar.Publish("mmap", expvar.Func(value))
+}
+
+// vars is the value published under the "mmap" name.
+type vars struct {
+	Stats mmap.Stats `json:"stats"`
+	Files []file     `json:"files"`
+}
+
+// file describes an open file.
+type file struct {
+	Path   string    `json:"path"`
+	Size   int64     `json:"size"`
+	Mode   string    `json:"mode"`
+	Opened time.Time `json:"opened"`
+}
+
+func value() any {
+	infos := mmap.OpenFiles()
+	v := vars{
+		Stats: mmap.ReadStats(),
+		Files: make([]file, len(infos)),
+	}
+	for i, info := range infos {
+		v.Files[i] = file{
+			Path:   info.Path,
+			Size:   info.Size,
+			Mode:   mode(info.Flag),
+			Opened: info.Opened,
+		}
+	}
+	return v
+}
+
+// mode returns a short description of the access granted by flag.
+func mode(flag mmap.Flag) string {
+	switch flag & (mmap.Read | mmap.Write) {
+	case mmap.Read:
+		return "r"
+	case mmap.Write:
+		return "w"
+	case mmap.Read | mmap.Write:
+		return "rw"
+	default:
+		return "-"
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmapexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestPublish(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "vars.bin")
+	err := os.WriteFile(fname, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := mmap.OpenFile(fname, mmap.Read|mmap.Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+
+	load := func() vars {
+		t.Helper()
+		v := expvar.Get("mmap")
+		if v == nil {
+			t.Fatalf("mmap var is not published")
+		}
+		var got vars
+		err := json.Unmarshal([]byte(v.String()), &got)
+		if err != nil {
+			t.Fatalf("could not decode mmap var: %+v", err)
+		}
+		return got
+	}
+
+	got := load()
+	if len(got.Files) != 1 {
+		t.Fatalf("invalid files: %+v", got.Files)
+	}
+	if fi := got.Files[0]; fi.Path != fname || fi.Size != 4096 || fi.Mode != "rw" || fi.Opened.IsZero() {
+		t.Fatalf("invalid file: %+v", fi)
+	}
+	if got.Stats.Mappings < 1 || got.Stats.MappedBytes < 4096 {
+		t.Fatalf("invalid stats: %+v", got.Stats)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	if got := load(); len(got.Files) != 0 {
+		t.Fatalf("closed file is still published: %+v", got.Files)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mmapmacho parses Mach-O binaries from memory-mapped files.
+package mmapmacho
+
+import (
+	"debug/macho"
+	"fmt"
+
+	"github.com/go-mmap/mmap"
+)
+
+// File is a Mach-O binary read from a memory-mapped file.
+type File struct {
+	*macho.File
+
+	f *mmap.File
+}
+
+// Open memory-maps the named Mach-O binary for reading, and parses it.
+func Open(filename string) (*File, error) {
+	f, err := mmap.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	bin, err := macho.NewFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("mmapmacho: could not parse %q: %w", filename, err)
+	}
+	return &File{File: bin, f: f}, nil
+}
+
+// Close closes the binary and unmaps it.
+func (f *File) Close() error {
+	err := f.File.Close()
+	if e := f.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmapmacho
+
+import (
+	"debug/macho"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	src := filepath.Join(runtime.GOROOT(), "src", "debug", "macho", "testdata", "gcc-amd64-darwin-exec.base64")
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		t.Skipf("missing test binary: %+v", err)
+	}
+	bin, err := base64.StdEncoding.DecodeString(string(raw))
+	if err != nil {
+		t.Fatalf("could not decode test binary: %+v", err)
+	}
+	fname := filepath.Join(t.TempDir(), "exec")
+	err = os.WriteFile(fname, bin, 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open binary: %+v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.Cpu, macho.CpuAmd64; got != want {
+		t.Fatalf("invalid cpu: got=%v, want=%v", got, want)
+	}
+	if f.Section("__text") == nil {
+		t.Fatalf("missing __text section")
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close binary: %+v", err)
+	}
+}
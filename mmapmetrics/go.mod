@@ -0,0 +1,16 @@
+module github.com/go-mmap/mmap/mmapmetrics
+
+go 1.21
+
+require github.com/go-mmap/mmap v0.0.0-20261014121645-171ff1ba0b38
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/prometheus/client_golang v1.19.1
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
@@ -0,0 +1,80 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mmapmetrics exposes the statistics of the mmap package as
+// Prometheus metrics.
+//
+// mmapmetrics lives in its own module, so that users of the mmap package do
+// not depend on the Prometheus client.
+package mmapmetrics
+
+import (
+	"time"
+
+	"github.com/go-mmap/mmap"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exporting the statistics of the
+// mmap package:
+//
+//   - mmap_mappings: number of live memory mappings,
+//   - mmap_mapped_bytes: total size of the live memory mappings,
+//   - mmap_remaps_total: number of calls to Remap,
+//   - mmap_sync_duration_seconds: histogram of the durations of Sync.
+type Collector struct {
+	mappings *prometheus.Desc
+	bytes    *prometheus.Desc
+	remaps   *prometheus.Desc
+	syncs    prometheus.Histogram
+}
+
+// NewCollector returns a new Collector.
+// It registers the sync hook of the mmap package, replacing any previous
+// one, so only one Collector should be in use at a time.
+func NewCollector() *Collector {
+	c := &Collector{
+		mappings: prometheus.NewDesc(
+			"mmap_mappings",
+			"Number of live memory mappings.",
+			nil, nil,
+		),
+		bytes: prometheus.NewDesc(
+			"mmap_mapped_bytes",
+			"Total size of the live memory mappings.",
+			nil, nil,
+		),
+		remaps: prometheus.NewDesc(
+			"mmap_remaps_total",
+			"Number of remappings of files.",
+			nil, nil,
+		),
+		syncs: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "mmap_sync_duration_seconds",
+			Help:    "Durations of the commits of mapped files to stable storage.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+	}
+	mmap.SetSyncHook(func(d time.Duration) {
+		c.syncs.Observe(d.Seconds())
+	})
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.mappings
+	ch <- c.bytes
+	ch <- c.remaps
+	c.syncs.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := mmap.ReadStats()
+	ch <- prometheus.MustNewConstMetric(c.mappings, prometheus.GaugeValue, float64(stats.Mappings))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.MappedBytes))
+	ch <- prometheus.MustNewConstMetric(c.remaps, prometheus.CounterValue, float64(stats.Remaps))
+	c.syncs.Collect(ch)
+}
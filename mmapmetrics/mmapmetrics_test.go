@@ -0,0 +1,74 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmapmetrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(NewCollector())
+	if err != nil {
+		t.Fatalf("could not register collector: %+v", err)
+	}
+	defer mmap.SetSyncHook(nil)
+
+	fname := filepath.Join(t.TempDir(), "metrics.bin")
+	err = os.WriteFile(fname, make([]byte, 4096), 0644)
+	if err != nil {
+		t.Fatalf("could not seed file: %+v", err)
+	}
+	f, err := mmap.OpenFile(fname, mmap.Read|mmap.Write)
+	if err != nil {
+		t.Fatalf("could not mmap file: %+v", err)
+	}
+	defer f.Close()
+
+	err = f.Sync()
+	if err != nil {
+		t.Fatalf("could not sync file: %+v", err)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics: %+v", err)
+	}
+	got := make(map[string]float64)
+	for _, mf := range mfs {
+		m := mf.GetMetric()[0]
+		switch {
+		case m.Gauge != nil:
+			got[mf.GetName()] = m.Gauge.GetValue()
+		case m.Counter != nil:
+			got[mf.GetName()] = m.Counter.GetValue()
+		case m.Histogram != nil:
+			got[mf.GetName()] = float64(m.Histogram.GetSampleCount())
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		min  float64
+	}{
+		{"mmap_mappings", 1},
+		{"mmap_mapped_bytes", 4096},
+		{"mmap_remaps_total", 0},
+		{"mmap_sync_duration_seconds", 1},
+	} {
+		v, ok := got[tc.name]
+		if !ok {
+			t.Errorf("missing metric %q", tc.name)
+			continue
+		}
+		if v < tc.min {
+			t.Errorf("invalid value for %q: got=%v, want>=%v", tc.name, v, tc.min)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmappe
+
+import (
+	"debug/pe"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	fname := filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", "gcc-386-mingw-exec")
+	if _, err := os.Stat(fname); err != nil {
+		t.Skipf("missing test binary: %+v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open binary: %+v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.Machine, uint16(pe.IMAGE_FILE_MACHINE_I386); got != want {
+		t.Fatalf("invalid machine: got=%#x, want=%#x", got, want)
+	}
+	if f.Section(".text") == nil {
+		t.Fatalf("missing .text section")
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close binary: %+v", err)
+	}
+}
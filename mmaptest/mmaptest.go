@@ -0,0 +1,172 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mmaptest provides helpers to test code built on memory-mapped
+// files.
+package mmaptest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+// Pattern returns n bytes repeating pattern.
+// A nil or empty pattern yields the sequence 0, 1, ..., 255, 0, 1, ...,
+// whose bytes differ from their neighbors, which catches off-by-one errors.
+func Pattern(n int, pattern []byte) []byte {
+	p := make([]byte, n)
+	if len(pattern) == 0 {
+		for i := range p {
+			p[i] = byte(i)
+		}
+		return p
+	}
+	for i := 0; i < n; i += len(pattern) {
+		copy(p[i:], pattern)
+	}
+	return p
+}
+
+// WriteFile creates a file of size bytes repeating pattern, as returned by
+// Pattern, in a temporary directory removed at the end of the test.
+// It returns the name of the file.
+func WriteFile(tb testing.TB, size int, pattern []byte) string {
+	tb.Helper()
+	fname := filepath.Join(tb.TempDir(), "mmaptest.bin")
+	err := os.WriteFile(fname, Pattern(size, pattern), 0644)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not create file: %+v", err)
+	}
+	return fname
+}
+
+// Open memory-maps a file of size bytes repeating pattern, as created by
+// WriteFile, for reading/writing depending on the flag value.
+// The file is closed at the end of the test, if it is still open.
+func Open(tb testing.TB, size int, pattern []byte, flag mmap.Flag, opts ...mmap.Option) *mmap.File {
+	tb.Helper()
+	fname := WriteFile(tb, size, pattern)
+	f, err := mmap.OpenFile(fname, flag, opts...)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not mmap file: %+v", err)
+	}
+	tb.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+// Corrupt flips all the bits of the [off, off+n) range of the named file,
+// writing through the file system rather than through a mapping, as a
+// faulty disk or another process would.
+func Corrupt(tb testing.TB, fname string, off int64, n int) {
+	tb.Helper()
+	f, err := os.OpenFile(fname, os.O_RDWR, 0)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not open file to corrupt: %+v", err)
+	}
+	defer f.Close()
+
+	p := make([]byte, n)
+	_, err = f.ReadAt(p, off)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not read range to corrupt: %+v", err)
+	}
+	for i := range p {
+		p[i] ^= 0xff
+	}
+	_, err = f.WriteAt(p, off)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not corrupt file: %+v", err)
+	}
+}
+
+// Truncate changes the size of the named file, behind the back of the
+// mappings of the file.
+// Accessing the mapping of a file past its new end raises SIGBUS on most
+// platforms; Windows refuses to truncate files that are mapped.
+func Truncate(tb testing.TB, fname string, size int64) {
+	tb.Helper()
+	err := os.Truncate(fname, size)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not truncate file: %+v", err)
+	}
+}
+
+// AssertContents checks that the contents of f are want, through Len, Size,
+// At and ReadAt.
+func AssertContents(tb testing.TB, f *mmap.File, want []byte) {
+	tb.Helper()
+	if got, want := f.Size(), int64(len(want)); got != want {
+		tb.Fatalf("mmaptest: invalid size: got=%d, want=%d", got, want)
+	}
+	if got, want := f.Len(), len(want); got != want {
+		tb.Fatalf("mmaptest: invalid length: got=%d, want=%d", got, want)
+	}
+	got := make([]byte, len(want))
+	n, err := f.ReadAt(got, 0)
+	if err != nil && !(err == io.EOF && n == len(want)) {
+		tb.Fatalf("mmaptest: could not read contents: %+v", err)
+	}
+	if i := mismatch(got, want); i >= 0 {
+		tb.Fatalf("mmaptest: contents differ at offset %d: got=%#02x, want=%#02x", i, got[i], want[i])
+	}
+	for _, i := range []int{0, len(want) / 2, len(want) - 1} {
+		if i < 0 || len(want) <= i {
+			continue
+		}
+		if got, want := f.At(i), want[i]; got != want {
+			tb.Fatalf("mmaptest: invalid byte at %d: got=%#02x, want=%#02x", i, got, want)
+		}
+	}
+}
+
+// AssertInvariants checks the invariants of an open file:
+// Len and Size agree, the cursor lies within the file, reads past the end
+// of the file fail with io.EOF, and the size of the mapping matches the
+// size of the file on disk.
+// The file is looked up on disk by name, so it must not have been opened
+// with OpenFileAt.
+func AssertInvariants(tb testing.TB, f *mmap.File) {
+	tb.Helper()
+	size := f.Size()
+	if got, want := int64(f.Len()), size; got != want {
+		tb.Fatalf("mmaptest: Len and Size disagree: len=%d, size=%d", got, want)
+	}
+	cur, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		tb.Fatalf("mmaptest: could not get cursor: %+v", err)
+	}
+	if cur < 0 || size < cur {
+		tb.Fatalf("mmaptest: cursor %d out of the [0, %d] range", cur, size)
+	}
+	var b [1]byte
+	if _, err := f.ReadAt(b[:], size); err != io.EOF {
+		tb.Fatalf("mmaptest: reading past the end: got err=%v, want io.EOF", err)
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		tb.Fatalf("mmaptest: could not stat file: %+v", err)
+	}
+	if got, want := fi.Size(), size; got != want {
+		tb.Fatalf("mmaptest: size on disk differs from mapping: disk=%d, mapping=%d", got, want)
+	}
+}
+
+// mismatch returns the index of the first byte differing between a and b,
+// which have the same length, or -1.
+func mismatch(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return -1
+}
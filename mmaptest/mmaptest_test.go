@@ -0,0 +1,48 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmaptest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestPattern(t *testing.T) {
+	if got, want := Pattern(5, []byte("ab")), []byte("ababa"); !bytes.Equal(got, want) {
+		t.Fatalf("invalid pattern: got=%q, want=%q", got, want)
+	}
+	p := Pattern(300, nil)
+	if p[0] != 0 || p[255] != 255 || p[256] != 0 || p[299] != 43 {
+		t.Fatalf("invalid sequence: %v", p)
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	f := Open(t, 4096, []byte("mmap"), mmap.Read)
+	AssertContents(t, f, Pattern(4096, []byte("mmap")))
+	AssertInvariants(t, f)
+
+	Corrupt(t, f.Name(), 10, 2)
+	want := Pattern(4096, []byte("mmap"))
+	want[10] ^= 0xff
+	want[11] ^= 0xff
+	AssertContents(t, f, want)
+
+	err := f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+	Truncate(t, f.Name(), 100)
+
+	g, err := mmap.Open(f.Name())
+	if err != nil {
+		t.Fatalf("could not mmap truncated file: %+v", err)
+	}
+	defer g.Close()
+	AssertContents(t, g, want[:100])
+	AssertInvariants(t, g)
+}
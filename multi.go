@@ -0,0 +1,158 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// MultiFile is the logical concatenation of several memory-mapped files,
+// read through offsets spanning all of them, such as the segments of a log.
+type MultiFile struct {
+	files []*File
+	offs  []int64 // offs[i] is the offset of the first byte of files[i].
+	size  int64
+	c     int64
+}
+
+// OpenMulti memory-maps the named files for reading, and returns their
+// concatenation in order.
+func OpenMulti(filenames ...string) (*MultiFile, error) {
+	files := make([]*File, 0, len(filenames))
+	for _, name := range filenames {
+		f, err := Open(name)
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return NewMultiFile(files...), nil
+}
+
+// NewMultiFile returns the concatenation of the given memory-mapped files,
+// in order.
+// Closing the MultiFile closes the files.
+// The sizes of the files must not change while the MultiFile is used.
+func NewMultiFile(files ...*File) *MultiFile {
+	m := &MultiFile{
+		files: files,
+		offs:  make([]int64, len(files)),
+	}
+	for i, f := range files {
+		m.offs[i] = m.size
+		m.size += f.Size()
+	}
+	return m
+}
+
+// Len returns the total length of the files.
+// Use Size for files whose length may not fit in an int.
+func (m *MultiFile) Len() int {
+	return int(m.size)
+}
+
+// Size returns the total length of the files.
+func (m *MultiFile) Size() int64 {
+	return m.size
+}
+
+// At returns the byte at index i.
+func (m *MultiFile) At(i int) byte {
+	if int64(i) < 0 || m.size <= int64(i) {
+		panic("index out of range")
+	}
+	var b [1]byte
+	if _, err := m.ReadAt(b[:], int64(i)); err != nil {
+		panic(err)
+	}
+	return b[0]
+}
+
+// Read implements the io.Reader interface.
+func (m *MultiFile) Read(p []byte) (int, error) {
+	if m.c >= m.size {
+		return 0, io.EOF
+	}
+	n, err := m.ReadAt(p, m.c)
+	m.c += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (m *MultiFile) ReadAt(p []byte, off int64) (int, error) {
+	if m == nil {
+		return 0, os.ErrInvalid
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("mmap: invalid ReadAt offset %d", off)
+	}
+
+	// Find the last file starting at or before off.
+	i := sort.Search(len(m.offs), func(i int) bool { return m.offs[i] > off }) - 1
+	n := 0
+	for ; i >= 0 && i < len(m.files) && n < len(p); i++ {
+		f := m.files[i]
+		foff := off + int64(n) - m.offs[i]
+		if foff >= f.Size() {
+			continue
+		}
+		c, err := f.ReadAt(p[n:], foff)
+		n += c
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek implements the io.Seeker interface.
+func (m *MultiFile) Seek(offset int64, whence int) (int64, error) {
+	var c int64
+	switch whence {
+	case io.SeekStart:
+		c = offset
+	case io.SeekCurrent:
+		c = m.c + offset
+	case io.SeekEnd:
+		c = m.size + offset
+	default:
+		return 0, fmt.Errorf("mmap: invalid whence")
+	}
+	if c < 0 {
+		return 0, fmt.Errorf("mmap: negative position")
+	}
+	m.c = c
+	return c, nil
+}
+
+// Close closes all the files.
+func (m *MultiFile) Close() error {
+	var err error
+	for _, f := range m.files {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+var (
+	_ io.Reader   = (*MultiFile)(nil)
+	_ io.ReaderAt = (*MultiFile)(nil)
+	_ io.Seeker   = (*MultiFile)(nil)
+	_ io.Closer   = (*MultiFile)(nil)
+)
@@ -0,0 +1,190 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OpenFileAt memory-maps the file named name, relative to the directory
+// dir, for reading/writing, depending on the flag value.
+//
+// Resolving the name from an open directory, rather than from a path,
+// guards against the directory being renamed or replaced between the time
+// it is checked and the time the file is opened.
+// On Windows, name can not hold ".." elements; elsewhere, callers
+// confining accesses to dir must reject them, and should use WithNoFollow.
+func OpenFileAt(dir *os.File, name string, flag Flag, opts ...Option) (*File, error) {
+	if dir == nil {
+		return nil, os.ErrInvalid
+	}
+	cfg := newOptions(opts)
+	cfg.dir = dir
+	return openLogged(name, flag, cfg)
+}
+
+// WithCreate makes opening the file create it if it does not exist, with
+// the given permissions (before umask).
+// A created file is empty: combine WithCreate with WithAutoExtend, or
+// truncate the file, to give it contents.
+// On Windows, only the write permission bit is honored: a file created
+// without it is read-only.
+func WithCreate(perm fs.FileMode) Option {
+	return func(o *options) {
+		o.create = true
+		o.perm = perm
+	}
+}
+
+// WithExclusive makes opening the file create it, and fail with an error
+// wrapping fs.ErrExist if it already exists.
+// The file is created with the permissions given to WithCreate, if any, or
+// 0666 (before umask).
+func WithExclusive() Option {
+	return func(o *options) {
+		o.create = true
+		o.excl = true
+	}
+}
+
+// WithTruncate makes opening the file truncate it to zero bytes.
+// The file must be opened for writing: opening it for reading only fails
+// with an error wrapping os.ErrInvalid.
+func WithTruncate() Option {
+	return func(o *options) {
+		o.trunc = true
+	}
+}
+
+// WithNoFollow makes opening the file fail if its name refers to a
+// symbolic link, to protect against symlink attacks.
+// On Windows, any reparse point is refused, including junctions.
+func WithNoFollow() Option {
+	return func(o *options) {
+		o.noFollow = true
+	}
+}
+
+// WithInheritable makes the file descriptor of the file inherited by the
+// child processes started while the file is open.
+// By default, file descriptors are closed on exec (O_CLOEXEC), as everywhere
+// in Go.
+func WithInheritable() Option {
+	return func(o *options) {
+		o.inherit = true
+	}
+}
+
+// Name returns the name of the file, as given when opening it.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Reopen memory-maps the file again, under the name it was opened with, for
+// reading/writing depending on the flag value, so that a reader can be
+// turned into a writer, or conversely.
+// The new file is opened with the same options as the file, except those
+// creating or truncating it. Snapshots are reopened with the options of the
+// file they were taken of, and map the file itself. Files opened with
+// OpenFileAt are opened again relative to the same directory, which must
+// still be open.
+// The file itself is left open.
+func (f *File) Reopen(flag Flag) (*File, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	return openLogged(f.name, flag, *f.reopenOptions())
+}
+
+// reopenOptions returns the options Reopen opens the file with: the ones
+// it was opened with, or the ones of the file a snapshot was taken of, but
+// for those creating or truncating it.
+func (f *File) reopenOptions() *options {
+	cfg := f.cfg
+	if f.origin != nil {
+		cfg = *f.origin
+	}
+	cfg.create = false
+	cfg.excl = false
+	cfg.trunc = false
+	return &cfg
+}
+
+// OpenAll memory-maps the named files, with the same flag and options, and
+// returns them in the order of their names.
+//
+// Opening is all or nothing: if a file fails to open, the files opened so
+// far are closed, no more files are opened, and OpenAll returns a nil slice
+// with the errors of the files that failed.
+// The files are opened one after the other, unless WithOpenWorkers is
+// given: the failure of a file then stops the other workers once they are
+// done with the files they were opening.
+func OpenAll(names []string, flag Flag, opts ...Option) ([]*File, error) {
+	cfg := newOptions(opts)
+	files := make([]*File, len(names))
+	errs := make([]error, len(names))
+
+	workers := cfg.openWorkers
+	if workers > len(names) {
+		workers = len(names)
+	}
+	if workers <= 1 {
+		for i, name := range names {
+			files[i], errs[i] = openLogged(name, flag, cfg)
+			if errs[i] != nil {
+				break
+			}
+		}
+	} else {
+		var (
+			wg     sync.WaitGroup
+			next   atomic.Int64
+			failed atomic.Bool
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for !failed.Load() {
+					i := int(next.Add(1) - 1)
+					if i >= len(names) {
+						return
+					}
+					files[i], errs[i] = openLogged(names[i], flag, cfg)
+					if errs[i] != nil {
+						failed.Store(true)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	err := joinErrors(errs...)
+	if err == nil {
+		return files, nil
+	}
+	for _, f := range files {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+	return nil, err
+}
+
+// WithOpenWorkers makes OpenAll open up to workers files at once, each from
+// its own goroutine, which speeds up opening many files on storage serving
+// several requests concurrently.
+func WithOpenWorkers(workers int) Option {
+	return func(o *options) {
+		o.openWorkers = workers
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Overlay stages writes to a memory-mapped file in memory, on top of its
+// unmodified contents.
+// Reads through the overlay observe the staged writes, which are only
+// carried to the file by Flush, or dropped by Discard.
+//
+// Only the written ranges are held in memory, so large files can be
+// edited without a private copy of the whole file.
+// An Overlay must not be used from several goroutines at once.
+type Overlay struct {
+	base    *File
+	patches []patch // sorted, non-overlapping and non-adjacent.
+}
+
+// patch is a staged write of data at off.
+type patch struct {
+	off  int64
+	data []byte
+}
+
+func (p patch) end() int64 { return p.off + int64(len(p.data)) }
+
+// NewOverlay returns an overlay on top of base, which may be opened
+// read-only unless the overlay is flushed.
+func NewOverlay(base *File) *Overlay {
+	return &Overlay{base: base}
+}
+
+// Size returns the length of the underlying file.
+func (o *Overlay) Size() int64 {
+	return o.base.Size()
+}
+
+// Len returns the number of bytes staged in the overlay.
+func (o *Overlay) Len() int {
+	n := 0
+	for _, p := range o.patches {
+		n += len(p.data)
+	}
+	return n
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (o *Overlay) ReadAt(p []byte, off int64) (int, error) {
+	n, err := o.base.ReadAt(p, off)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+	end := off + int64(n)
+	for i := o.search(off); i < len(o.patches) && o.patches[i].off < end; i++ {
+		q := o.patches[i]
+		beg := q.off
+		if beg < off {
+			beg = off
+		}
+		copy(p[beg-off:n], q.data[beg-q.off:])
+	}
+	return n, err
+}
+
+// WriteAt implements the io.WriterAt interface.
+// Writes are staged in the overlay: the file is left untouched.
+func (o *Overlay) WriteAt(p []byte, off int64) (int, error) {
+	size := o.base.Size()
+	if off < 0 || size < off {
+		return 0, fmt.Errorf("mmap: invalid WriteAt offset %d", off)
+	}
+	n := len(p)
+	if int64(n) > size-off {
+		n = int(size - off)
+	}
+	if n > 0 {
+		o.stage(p[:n], off)
+	}
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Flush writes the staged writes to the file, and empties the overlay.
+// The file must be opened for writing.
+func (o *Overlay) Flush() error {
+	if o.base == nil {
+		return os.ErrInvalid
+	}
+	if !o.base.wflag() {
+		return errBadFD
+	}
+	for i, p := range o.patches {
+		_, err := o.base.WriteAt(p.data, p.off)
+		if err != nil {
+			o.patches = o.patches[i:]
+			return err
+		}
+	}
+	o.patches = nil
+	return nil
+}
+
+// Discard drops the staged writes.
+func (o *Overlay) Discard() {
+	o.patches = nil
+}
+
+// search returns the index of the first patch ending after off.
+func (o *Overlay) search(off int64) int {
+	return sort.Search(len(o.patches), func(i int) bool {
+		return o.patches[i].end() > off
+	})
+}
+
+// stage records the write of p at off, merging it with the patches it
+// overlaps or touches.
+func (o *Overlay) stage(p []byte, off int64) {
+	end := off + int64(len(p))
+	i := sort.Search(len(o.patches), func(i int) bool {
+		return o.patches[i].end() >= off
+	})
+	j := i
+	for j < len(o.patches) && o.patches[j].off <= end {
+		j++
+	}
+
+	beg, last := off, end
+	if i < j {
+		if o.patches[i].off < beg {
+			beg = o.patches[i].off
+		}
+		if e := o.patches[j-1].end(); e > last {
+			last = e
+		}
+	}
+	buf := make([]byte, last-beg)
+	for _, q := range o.patches[i:j] {
+		copy(buf[q.off-beg:], q.data)
+	}
+	copy(buf[off-beg:], p)
+
+	merged := patch{off: beg, data: buf}
+	o.patches = append(o.patches[:i], append([]patch{merged}, o.patches[j:]...)...)
+}
+
+var (
+	_ io.ReaderAt = (*Overlay)(nil)
+	_ io.WriterAt = (*Overlay)(nil)
+)
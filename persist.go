@@ -0,0 +1,44 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+// WithMapSync maps the file synchronously, with MAP_SHARED_VALIDATE and
+// MAP_SYNC, for files on filesystems mapping persistent memory directly
+// (DAX), such as ext4 or xfs mounted with -o dax.
+// The pages of such mappings are the persistent memory itself, and the
+// kernel keeps the metadata of the file in sync with them: stores to the
+// mapping are durable as soon as Persist flushes them from the CPU caches,
+// without any system call.
+//
+// Opening a file fails if its filesystem does not support it, which
+// callers may check for with errors.Is and syscall.EOPNOTSUPP, and on
+// platforms other than Linux.
+func WithMapSync() Option {
+	return func(o *options) {
+		o.mapSync = true
+	}
+}
+
+// Persist commits the [off, off+n) range of the file to stable storage.
+//
+// For files mapped with WithMapSync, Persist flushes the cache lines of the
+// range from the CPU caches, with CLWB, CLFLUSHOPT or CLFLUSH on amd64, and
+// waits for the flushes to complete: it is then the building block of
+// programs storing their data structures in persistent memory, which
+// persist each of their updates as they make them.
+// Elsewhere, and on other architectures, Persist is the same as SyncRange.
+func (f *File) Persist(off, n int64) error {
+	if !f.cfg.mapSync || f.w != nil {
+		return f.SyncRange(off, n)
+	}
+	_, err := f.region(off, n)
+	if err != nil || !f.wflag() || f.cfg.noSync || n == 0 {
+		return err
+	}
+	if flushCache(f.data[off : off+n]) {
+		return nil
+	}
+	return f.SyncRange(off, n)
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "unsafe"
+
+// cacheFlush is the instruction flushing cache lines on the CPU, and
+// cacheLine the size of the lines.
+var cacheFlush, cacheLine = detectCacheFlush()
+
+// detectCacheFlush returns the most efficient instruction flushing cache
+// lines supported by the CPU, and the size of its cache lines.
+// CLWB writes lines back while keeping them cached; CLFLUSHOPT and CLFLUSH
+// evict them, the latter serializing the flushes.
+func detectCacheFlush() (func(p unsafe.Pointer, n, line uintptr), uintptr) {
+	flush, line := clflush, uintptr(64)
+	if _, ebx := cpuid(1, 0); ebx>>8&0xff != 0 {
+		line = uintptr(ebx>>8&0xff) * 8
+	}
+	if max, _ := cpuid(0, 0); max < 7 {
+		return flush, line
+	}
+	_, ebx := cpuid(7, 0)
+	switch {
+	case ebx&(1<<24) != 0:
+		flush = clwb
+	case ebx&(1<<23) != 0:
+		flush = clflushopt
+	}
+	return flush, line
+}
+
+// flushCache flushes the cache lines holding b, and waits for the flushes
+// to complete. It reports whether it did.
+func flushCache(b []byte) bool {
+	if len(b) > 0 {
+		cacheFlush(unsafe.Pointer(&b[0]), uintptr(len(b)), cacheLine)
+	}
+	return true
+}
+
+// cpuid returns the EAX and EBX registers of the CPUID instruction for the
+// given leaf and sub-leaf.
+func cpuid(leaf, sub uint32) (eax, ebx uint32)
+
+// clwb, clflushopt and clflush flush the cache lines, of line bytes, that
+// hold the n bytes from p, then issue SFENCE.
+//
+//go:noescape
+func clwb(p unsafe.Pointer, n, line uintptr)
+
+//go:noescape
+func clflushopt(p unsafe.Pointer, n, line uintptr)
+
+//go:noescape
+func clflush(p unsafe.Pointer, n, line uintptr)
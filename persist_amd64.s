@@ -0,0 +1,68 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// func cpuid(leaf, sub uint32) (eax, ebx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-16
+	MOVL leaf+0(FP), AX
+	MOVL sub+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	RET
+
+// The flushes round p down to the start of its cache line, and flush the
+// lines up to p+n.
+
+// func clwb(p unsafe.Pointer, n, line uintptr)
+TEXT ·clwb(SB), NOSPLIT, $0-24
+	MOVQ p+0(FP), SI
+	MOVQ n+8(FP), DI
+	MOVQ line+16(FP), DX
+	ADDQ SI, DI
+	MOVQ DX, AX
+	NEGQ AX
+	ANDQ AX, SI
+loop:
+	CLWB (SI)
+	ADDQ DX, SI
+	CMPQ SI, DI
+	JB   loop
+	SFENCE
+	RET
+
+// func clflushopt(p unsafe.Pointer, n, line uintptr)
+TEXT ·clflushopt(SB), NOSPLIT, $0-24
+	MOVQ p+0(FP), SI
+	MOVQ n+8(FP), DI
+	MOVQ line+16(FP), DX
+	ADDQ SI, DI
+	MOVQ DX, AX
+	NEGQ AX
+	ANDQ AX, SI
+loop:
+	CLFLUSHOPT (SI)
+	ADDQ DX, SI
+	CMPQ SI, DI
+	JB   loop
+	SFENCE
+	RET
+
+// func clflush(p unsafe.Pointer, n, line uintptr)
+TEXT ·clflush(SB), NOSPLIT, $0-24
+	MOVQ p+0(FP), SI
+	MOVQ n+8(FP), DI
+	MOVQ line+16(FP), DX
+	ADDQ SI, DI
+	MOVQ DX, AX
+	NEGQ AX
+	ANDQ AX, SI
+loop:
+	CLFLUSH (SI)
+	ADDQ DX, SI
+	CMPQ SI, DI
+	JB   loop
+	SFENCE
+	RET
@@ -0,0 +1,15 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+// +build !amd64
+
+package mmap
+
+// flushCache flushes the cache lines holding b, and waits for the flushes
+// to complete. It reports whether it did: Go offers no cache flushing
+// instructions on this architecture.
+func flushCache(b []byte) bool {
+	return false
+}
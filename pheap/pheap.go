@@ -0,0 +1,331 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pheap provides an experimental persistent heap: Go values
+// allocated within a memory-mapped file, that is mapped at the same
+// address each time it is opened, so that the pointers between them stay
+// valid across runs.
+//
+// Data structures built in the heap, such as linked lists or trees, are
+// then usable as soon as the heap is opened, without decoding them.
+// This only holds as long as the file is opened by the same program, built
+// for the same platform: opening a heap checks the version of its format,
+// that it can be mapped at its base address, and that its root has the
+// expected type, as described by a hash of its layout.
+//
+// The values of the heap are not part of the Go heap: their pointers must
+// only point to other values of the heap, and their types must not hold
+// strings, slices, maps, channels, funcs nor interfaces, as the garbage
+// collector would not see the references the heap holds.
+// Values are allocated from the start of the heap to its end, and are
+// never freed.
+package pheap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"reflect"
+	"unsafe"
+
+	"github.com/go-mmap/mmap"
+)
+
+// Version is the version of the format of the heap files.
+const Version = 1
+
+// hdrSize is the size of the header of a heap file: a magic string, the
+// version of the format, the size of pointers, the base address, the end
+// of the allocated values, and the offset and type hash of the root.
+const hdrSize = 64
+
+const (
+	offVersion  = 8
+	offPtrSize  = 12
+	offBase     = 16
+	offTop      = 24
+	offRoot     = 32
+	offRootType = 40
+)
+
+var magic = [8]byte{'m', 'm', 'a', 'p', 'h', 'e', 'a', 'p'}
+
+var (
+	// ErrClosed is returned when using a closed heap.
+	ErrClosed = errors.New("pheap: closed")
+
+	// ErrNoSpace is returned when allocating more values than the heap
+	// holds.
+	ErrNoSpace = errors.New("pheap: no space left in heap")
+
+	// ErrNoRoot is returned by Root when no root was set.
+	ErrNoRoot = errors.New("pheap: no root")
+)
+
+// Heap is a persistent heap held by a memory-mapped file.
+// A Heap must not be used from several goroutines at once.
+type Heap struct {
+	f    *mmap.File
+	base unsafe.Pointer
+	size int64
+}
+
+// Create creates the named file holding an empty heap of size bytes, and
+// opens it.
+// The heap is mapped at the address base, which must be a multiple of the
+// allocation granularity of the platform, or wherever the OS chooses if
+// base is zero. The heap is mapped at the same address when opened again.
+// An existing file is truncated.
+func Create(filename string, size int64, base uintptr) (*Heap, error) {
+	if size <= hdrSize {
+		return nil, fmt.Errorf("pheap: invalid heap size %d", size)
+	}
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("pheap: could not create heap: %w", err)
+	}
+	err = fd.Truncate(size)
+	if err == nil {
+		err = fd.Close()
+	} else {
+		_ = fd.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pheap: could not size heap %q: %w", filename, err)
+	}
+
+	var opts []mmap.Option
+	if base != 0 {
+		opts = append(opts, mmap.MapAt(base))
+	}
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write, opts...)
+	if err != nil {
+		return nil, err
+	}
+	h := &Heap{f: f, base: f.UnsafePointer(), size: size}
+	if h.base == nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("pheap: heap %q is too large to be mapped", filename)
+	}
+	b := f.Bytes()
+	copy(b, magic[:])
+	binary.LittleEndian.PutUint32(b[offVersion:], Version)
+	binary.LittleEndian.PutUint32(b[offPtrSize:], uint32(unsafe.Sizeof(uintptr(0))))
+	binary.LittleEndian.PutUint64(b[offBase:], uint64(uintptr(h.base)))
+	binary.LittleEndian.PutUint64(b[offTop:], hdrSize)
+	return h, nil
+}
+
+// Open opens the heap held by the named file, created with Create, mapping
+// it at its base address.
+// Opening fails with an error wrapping mmap.ErrAddrNotAvailable if the
+// base address is already in use.
+func Open(filename string) (*Heap, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("pheap: could not open heap: %w", err)
+	}
+	var hdr [hdrSize]byte
+	_, err = fd.ReadAt(hdr[:], 0)
+	_ = fd.Close()
+	if err != nil || string(hdr[:8]) != string(magic[:]) {
+		return nil, fmt.Errorf("pheap: %q is not a heap", filename)
+	}
+	if v := binary.LittleEndian.Uint32(hdr[offVersion:]); v != Version {
+		return nil, fmt.Errorf("pheap: unsupported version %d of heap %q", v, filename)
+	}
+	if n := binary.LittleEndian.Uint32(hdr[offPtrSize:]); n != uint32(unsafe.Sizeof(uintptr(0))) {
+		return nil, fmt.Errorf("pheap: heap %q was created for %d-bit pointers", filename, 8*n)
+	}
+	base := uintptr(binary.LittleEndian.Uint64(hdr[offBase:]))
+
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write, mmap.MapAt(base))
+	if err != nil {
+		return nil, fmt.Errorf("pheap: could not map heap %q at %#x: %w", filename, base, err)
+	}
+	h := &Heap{f: f, base: f.UnsafePointer(), size: f.Size()}
+	if top := h.header(offTop); top < hdrSize || top > uint64(h.size) {
+		_ = f.Close()
+		return nil, fmt.Errorf("pheap: invalid header of %q", filename)
+	}
+	return h, nil
+}
+
+func (h *Heap) header(off int) uint64 {
+	return binary.LittleEndian.Uint64(h.f.Bytes()[off:])
+}
+
+func (h *Heap) setHeader(off int, v uint64) {
+	binary.LittleEndian.PutUint64(h.f.Bytes()[off:], v)
+}
+
+// Base returns the address the heap is mapped at.
+func (h *Heap) Base() uintptr {
+	return uintptr(h.base)
+}
+
+// Size returns the size of the heap, in bytes.
+func (h *Heap) Size() int64 {
+	return h.size
+}
+
+// Used returns the number of bytes allocated in the heap, including its
+// header.
+func (h *Heap) Used() int64 {
+	if h.f == nil {
+		return 0
+	}
+	return int64(h.header(offTop))
+}
+
+// Contains reports whether p points within the heap.
+func (h *Heap) Contains(p unsafe.Pointer) bool {
+	return h.f != nil && uintptr(p) >= uintptr(h.base) && uintptr(p)-uintptr(h.base) < uintptr(h.size)
+}
+
+// alloc allocates n zeroed bytes aligned to align, and returns their offset.
+func (h *Heap) alloc(n, align uintptr) (uint64, error) {
+	if h.f == nil {
+		return 0, ErrClosed
+	}
+	if align == 0 {
+		align = 1
+	}
+	off := (h.header(offTop) + uint64(align) - 1) &^ (uint64(align) - 1)
+	if off+uint64(n) > uint64(h.size) || off+uint64(n) < off {
+		return 0, ErrNoSpace
+	}
+	h.setHeader(offTop, off+uint64(n))
+	b := h.f.Bytes()[off : off+uint64(n)]
+	for i := range b {
+		b[i] = 0
+	}
+	return off, nil
+}
+
+// New allocates a zeroed value of type T in the heap, and returns a
+// pointer to it.
+// T must not hold strings, slices, maps, channels, funcs nor interfaces.
+func New[T any](h *Heap) (*T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if err := check(t, make(map[reflect.Type]bool)); err != nil {
+		return nil, err
+	}
+	off, err := h.alloc(t.Size(), uintptr(t.Align()))
+	if err != nil {
+		return nil, err
+	}
+	return (*T)(unsafe.Add(h.base, off)), nil
+}
+
+// SetRoot makes p, allocated in the heap, the root of the heap, from which
+// its data structures are reached when it is opened again with Root.
+func SetRoot[T any](h *Heap, p *T) error {
+	if h.f == nil {
+		return ErrClosed
+	}
+	if !h.Contains(unsafe.Pointer(p)) {
+		return fmt.Errorf("pheap: root %p is not in the heap", p)
+	}
+	h.setHeader(offRoot, uint64(uintptr(unsafe.Pointer(p))-uintptr(h.base)))
+	h.setHeader(offRootType, typeHash(reflect.TypeOf(p).Elem()))
+	return nil
+}
+
+// Root returns the root of the heap, set with SetRoot.
+// It fails if the root is not of type T, as described by its layout.
+func Root[T any](h *Heap) (*T, error) {
+	if h.f == nil {
+		return nil, ErrClosed
+	}
+	off := h.header(offRoot)
+	if off == 0 {
+		return nil, ErrNoRoot
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if h.header(offRootType) != typeHash(t) {
+		return nil, fmt.Errorf("pheap: root is not of type %v", t)
+	}
+	if off+uint64(t.Size()) > h.header(offTop) {
+		return nil, fmt.Errorf("pheap: invalid root offset %d", off)
+	}
+	return (*T)(unsafe.Add(h.base, off)), nil
+}
+
+// Sync commits the heap to stable storage.
+func (h *Heap) Sync() error {
+	if h.f == nil {
+		return ErrClosed
+	}
+	return h.f.Sync()
+}
+
+// Close closes the heap, which unmaps it: the pointers to its values must
+// not be used afterwards.
+// It does not sync it.
+func (h *Heap) Close() error {
+	if h.f == nil {
+		return nil
+	}
+	f := h.f
+	h.f, h.base = nil, nil
+	return f.Close()
+}
+
+// check checks that values of type t can be held by the heap.
+func check(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func,
+		reflect.Interface, reflect.UnsafePointer:
+		return fmt.Errorf("pheap: type %v can not be held by a heap", t)
+	case reflect.Ptr, reflect.Array:
+		return check(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if err := check(t.Field(i).Type, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeHash returns a hash of the layout of type t: its kind, size and
+// alignment, its name, and the names, offsets and types of its fields.
+func typeHash(t reflect.Type) uint64 {
+	h := fnv.New64a()
+	hashType(h, t, make(map[reflect.Type]int))
+	return h.Sum64()
+}
+
+func hashType(h io.Writer, t reflect.Type, seen map[reflect.Type]int) {
+	if i, ok := seen[t]; ok {
+		// Refer to types already hashed by their index, so that recursive
+		// types are hashed once.
+		fmt.Fprintf(h, "ref %d;", i)
+		return
+	}
+	seen[t] = len(seen)
+	fmt.Fprintf(h, "%s.%s %s %d %d;", t.PkgPath(), t.Name(), t.Kind(), t.Size(), t.Align())
+	switch t.Kind() {
+	case reflect.Ptr:
+		hashType(h, t.Elem(), seen)
+	case reflect.Array:
+		fmt.Fprintf(h, "len %d;", t.Len())
+		hashType(h, t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			fmt.Fprintf(h, "field %s %d;", sf.Name, sf.Offset)
+			hashType(h, sf.Type, seen)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pheap
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+type node struct {
+	Value int64
+	Next  *node
+}
+
+type list struct {
+	Len  int
+	Head *node
+}
+
+func TestHeap(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "heap")
+	h, err := Create(fname, 1<<20, 0)
+	if err != nil {
+		t.Fatalf("could not create heap: %+v", err)
+	}
+	base := h.Base()
+
+	l, err := New[list](h)
+	if err != nil {
+		t.Fatalf("could not allocate list: %+v", err)
+	}
+	for i := int64(0); i < 100; i++ {
+		n, err := New[node](h)
+		if err != nil {
+			t.Fatalf("could not allocate node: %+v", err)
+		}
+		n.Value = i
+		n.Next = l.Head
+		l.Head = n
+		l.Len++
+	}
+	if _, err := Root[list](h); !errors.Is(err, ErrNoRoot) {
+		t.Fatalf("invalid error without root: %+v", err)
+	}
+	err = SetRoot(h, l)
+	if err != nil {
+		t.Fatalf("could not set root: %+v", err)
+	}
+	if err := SetRoot(h, &list{}); err == nil {
+		t.Fatalf("expected an error setting a root outside the heap")
+	}
+	if _, err := New[struct{ S string }](h); err == nil {
+		t.Fatalf("expected an error allocating a string")
+	}
+	err = h.Close()
+	if err != nil {
+		t.Fatalf("could not close heap: %+v", err)
+	}
+
+	h, err = Open(fname)
+	if err != nil {
+		if errors.Is(err, mmap.ErrAddrNotAvailable) {
+			t.Skipf("base address %#x reused: %+v", base, err)
+		}
+		t.Fatalf("could not open heap: %+v", err)
+	}
+	defer h.Close()
+	if h.Base() != base {
+		t.Fatalf("invalid base: got=%#x, want=%#x", h.Base(), base)
+	}
+
+	if _, err := Root[node](h); err == nil {
+		t.Fatalf("expected an error loading a root of another type")
+	}
+	l, err = Root[list](h)
+	if err != nil {
+		t.Fatalf("could not load root: %+v", err)
+	}
+	want := int64(99)
+	n := 0
+	for p := l.Head; p != nil; p = p.Next {
+		if p.Value != want {
+			t.Fatalf("invalid node value: got=%d, want=%d", p.Value, want)
+		}
+		want--
+		n++
+	}
+	if n != 100 || l.Len != 100 {
+		t.Fatalf("invalid list length: %d, %d", n, l.Len)
+	}
+
+	for {
+		_, err := New[[4096]byte](h)
+		if err != nil {
+			if !errors.Is(err, ErrNoSpace) {
+				t.Fatalf("invalid error when full: %+v", err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+// WithProgress reports the progress of the long operations on the file:
+// Sync, SyncContext, Prefetch and CloneTo, as well as Verify and Rehash of
+// the Checksummed files wrapping it.
+//
+// These operations then process the file in chunks of 16 MiB, and call fn
+// after each one with the number of bytes processed so far and the total
+// number of bytes to process. Sync and SyncContext first call fn with no
+// bytes processed, as each of them starts over.
+// If fn returns an error, the operation stops and returns it, leaving the
+// rest of the file unprocessed.
+func WithProgress(fn func(done, total int64) error) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// reports reports whether the file reports the progress of operations.
+func (f *File) reports() bool {
+	return f != nil && f.cfg.progress != nil
+}
+
+// progress reports that done bytes out of total were processed.
+func (f *File) progress(done, total int64) error {
+	if !f.reports() {
+		return nil
+	}
+	return f.cfg.progress(done, total)
+}
+
+// chunked calls fn for each chunk of syncChunk bytes of the [off, off+n)
+// range of the file, and reports the progress after each one.
+func (f *File) chunked(off, n int64, fn func(off, n int64) error) error {
+	if _, err := f.region(off, n); err != nil {
+		return err
+	}
+	for done := int64(0); done < n; {
+		c := n - done
+		if c > syncChunk {
+			c = syncChunk
+		}
+		err := fn(off+done, c)
+		if err != nil {
+			return err
+		}
+		done += c
+		err = f.progress(done, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
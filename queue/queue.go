@@ -0,0 +1,536 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package queue provides a persistent FIFO queue of records, stored in a
+// directory of memory-mapped segment files.
+//
+// Records are appended to fixed-size segments, each record prefixed with
+// its length and a CRC-32C checksum. A memory-mapped meta file holds the
+// end of the queue and the positions committed by named consumers, which
+// resume from them when the queue is reopened.
+//
+// Records appended since the last Sync may be lost by a crash of the
+// system, but never observed corrupted: reopening the queue recovers the
+// records that made it to the segments intact, and drops the others.
+package queue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-mmap/mmap"
+)
+
+const (
+	// DefaultSegmentSize is the size of the segment files of queues
+	// created with a segment size of zero.
+	DefaultSegmentSize = 64 << 20
+
+	// recHdrSize is the size of the header of a record: the length of
+	// its payload and the checksum of both.
+	recHdrSize = 8
+
+	// metaSize is the size of the meta file: a header holding a magic
+	// string, the segment size, the end and the start of the queue, and
+	// its end at the last sync, followed by the slots of the consumers.
+	metaSize    = 4096
+	metaHdrSize = 64
+
+	// slotSize is the size of the slot of a consumer: its name, padded
+	// with zeros, followed by its committed position.
+	slotSize = 64
+	nameSize = slotSize - 8
+
+	metaFile  = "meta"
+	segSuffix = ".seg"
+)
+
+var magic = [8]byte{'m', 'm', 'a', 'p', 'q', 'u', 'e', '1'}
+
+var (
+	// ErrClosed is returned when using a closed queue.
+	ErrClosed = errors.New("queue: closed")
+
+	// ErrCorrupt is returned when reading a record that fails its checksum.
+	ErrCorrupt = errors.New("queue: corrupt record")
+
+	errNoRecord = errors.New("queue: no record")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Queue is a persistent FIFO queue of records.
+// A Queue and its consumers must not be used from several goroutines at
+// once, nor opened by several processes at once.
+type Queue struct {
+	dir     string
+	segSize int64
+	meta    *mmap.File
+	segs    map[int64]*mmap.File // mapped segments, by offset of their first byte.
+}
+
+// Open opens the queue stored in the directory dir, creating it if needed
+// with segments of segmentSize bytes, or DefaultSegmentSize if zero.
+// Existing queues keep the segment size they were created with.
+func Open(dir string, segmentSize int64) (*Queue, error) {
+	if segmentSize == 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if segmentSize < recHdrSize {
+		return nil, fmt.Errorf("queue: invalid segment size %d", segmentSize)
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("queue: could not create %q: %w", dir, err)
+	}
+
+	name := filepath.Join(dir, metaFile)
+	meta, err := mmap.OpenFile(name, mmap.Read|mmap.Write)
+	if errors.Is(err, os.ErrNotExist) {
+		meta, err = createMeta(name, segmentSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := meta.Bytes()
+	if len(b) != metaSize || !bytes.Equal(b[:8], magic[:]) {
+		_ = meta.Close()
+		return nil, fmt.Errorf("queue: %q is not a queue", dir)
+	}
+
+	q := &Queue{
+		dir:     dir,
+		segSize: int64(binary.LittleEndian.Uint64(b[8:])),
+		meta:    meta,
+		segs:    make(map[int64]*mmap.File),
+	}
+	if q.segSize < recHdrSize {
+		_ = meta.Close()
+		return nil, fmt.Errorf("queue: invalid segment size %d of %q", q.segSize, dir)
+	}
+	err = q.recover()
+	if err != nil {
+		_ = q.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func createMeta(name string, segSize int64) (*mmap.File, error) {
+	a, err := mmap.CreateAtomic(name, metaSize)
+	if err != nil {
+		return nil, err
+	}
+	b := a.Bytes()
+	copy(b, magic[:])
+	binary.LittleEndian.PutUint64(b[8:], uint64(segSize))
+	err = a.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return mmap.OpenFile(name, mmap.Read|mmap.Write)
+}
+
+// recover sets the end of the queue to the end of the last record that
+// made it intact to the segments before a crash.
+// The OS may write the meta file back before the segments, or after them:
+// the records are scanned from the end of the queue at the last sync, and
+// the end held by the meta file moves forward past the records it does not
+// account for, or back before the records that were lost.
+func (q *Queue) recover() error {
+	off := q.synced()
+	if off < q.head() {
+		off = q.head()
+	}
+	for {
+		_, _, next, err := q.record(off)
+		if err != nil {
+			if !errors.Is(err, errNoRecord) && !errors.Is(err, ErrCorrupt) {
+				return err
+			}
+			break
+		}
+		off = next
+	}
+	if end := q.tail(); off < end {
+		// Wipe what made it of the lost records, so that records later
+		// appended over them are never followed by stale ones.
+		err := q.zero(off, end)
+		if err != nil {
+			return fmt.Errorf("queue: could not recover: %w", err)
+		}
+	}
+	q.setTail(off)
+	return nil
+}
+
+// zero zeroes the [beg, end) range of the segments that exist.
+func (q *Queue) zero(beg, end int64) error {
+	for beg < end {
+		pos := beg % q.segSize
+		n := q.segSize - pos
+		if n > end-beg {
+			n = end - beg
+		}
+		seg, err := q.segment(beg, false)
+		switch {
+		case err == nil:
+			err = seg.Zero(pos, n)
+			if err != nil {
+				return err
+			}
+		case !errors.Is(err, errNoRecord):
+			return err
+		}
+		beg += n
+	}
+	return nil
+}
+
+func (q *Queue) tail() int64 {
+	return int64(binary.LittleEndian.Uint64(q.meta.Bytes()[16:]))
+}
+
+func (q *Queue) setTail(off int64) {
+	binary.LittleEndian.PutUint64(q.meta.Bytes()[16:], uint64(off))
+}
+
+func (q *Queue) head() int64 {
+	return int64(binary.LittleEndian.Uint64(q.meta.Bytes()[24:]))
+}
+
+func (q *Queue) setHead(off int64) {
+	binary.LittleEndian.PutUint64(q.meta.Bytes()[24:], uint64(off))
+}
+
+func (q *Queue) synced() int64 {
+	return int64(binary.LittleEndian.Uint64(q.meta.Bytes()[32:]))
+}
+
+func (q *Queue) setSynced(off int64) {
+	binary.LittleEndian.PutUint64(q.meta.Bytes()[32:], uint64(off))
+}
+
+func (q *Queue) segName(base int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%016x%s", base, segSuffix))
+}
+
+// segment returns the segment holding the byte at offset off, creating it
+// if asked to.
+// It returns errNoRecord if the segment does not exist.
+func (q *Queue) segment(off int64, create bool) (*mmap.File, error) {
+	base := off - off%q.segSize
+	if f, ok := q.segs[base]; ok {
+		return f, nil
+	}
+	name := q.segName(base)
+	f, err := mmap.OpenFile(name, mmap.Read|mmap.Write)
+	if errors.Is(err, os.ErrNotExist) && create {
+		err = createSegment(name, q.segSize)
+		if err == nil {
+			f, err = mmap.OpenFile(name, mmap.Read|mmap.Write)
+		}
+	}
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, errNoRecord
+	case err != nil:
+		return nil, err
+	case int64(f.Len()) != q.segSize:
+		_ = f.Close()
+		return nil, fmt.Errorf("queue: invalid size of segment %q", name)
+	}
+	q.segs[base] = f
+	return f, nil
+}
+
+// createSegment creates the named segment file, of size zero bytes.
+func createSegment(name string, size int64) error {
+	fd, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	err = fd.Truncate(size)
+	if e := fd.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(name)
+	}
+	return err
+}
+
+// checksum returns the checksum of a record, covering its length so that
+// zeroed headers never pass as empty records.
+func checksum(hdr, p []byte) uint32 {
+	crc := crc32.Update(0, crcTable, hdr[:4])
+	return crc32.Update(crc, crcTable, p)
+}
+
+// record returns the record at offset off, or following it when the
+// record at off did not fit in its segment.
+// It returns the offset of the record, a copy of its payload, and the
+// offset of the next record.
+// Segments are read and written with ReadAt and WriteAt, rather than
+// through their mapped bytes, as segments larger than the bound set by
+// mmap.SetMaxMappedBytes are mapped through a sliding window.
+func (q *Queue) record(off int64) (int64, []byte, int64, error) {
+	for {
+		seg, err := q.segment(off, false)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		pos := off % q.segSize
+		var hdr [recHdrSize]byte
+		if q.segSize-pos >= recHdrSize {
+			_, err = seg.ReadAt(hdr[:], pos)
+			if err != nil {
+				return 0, nil, 0, fmt.Errorf("queue: could not read record at offset %d: %w", off, err)
+			}
+		}
+		if binary.LittleEndian.Uint64(hdr[:]) == 0 {
+			if pos == 0 {
+				return 0, nil, 0, errNoRecord
+			}
+			// Records not fitting at the end of a segment are appended
+			// to the next one.
+			off += q.segSize - pos
+			continue
+		}
+		n := int64(binary.LittleEndian.Uint32(hdr[:]))
+		if n > q.segSize-pos-recHdrSize {
+			return 0, nil, 0, fmt.Errorf("%w at offset %d", ErrCorrupt, off)
+		}
+		p := make([]byte, n)
+		_, err = seg.ReadAt(p, pos+recHdrSize)
+		if err != nil {
+			return 0, nil, 0, fmt.Errorf("queue: could not read record at offset %d: %w", off, err)
+		}
+		if checksum(hdr[:], p) != binary.LittleEndian.Uint32(hdr[4:]) {
+			return 0, nil, 0, fmt.Errorf("%w at offset %d", ErrCorrupt, off)
+		}
+		return off, p, off + recHdrSize + n, nil
+	}
+}
+
+// Append appends the record p to the queue, and returns its offset.
+// Records must fit in a segment, header included.
+func (q *Queue) Append(p []byte) (int64, error) {
+	if q.meta == nil {
+		return 0, ErrClosed
+	}
+	n := int64(recHdrSize + len(p))
+	if n > q.segSize {
+		return 0, fmt.Errorf("queue: record of %d bytes does not fit in a segment", len(p))
+	}
+
+	off := q.tail()
+	if pos := off % q.segSize; pos+n > q.segSize {
+		off += q.segSize - pos
+	}
+	seg, err := q.segment(off, true)
+	if err != nil {
+		return 0, fmt.Errorf("queue: could not append: %w", err)
+	}
+	pos := off % q.segSize
+	var hdr [recHdrSize]byte
+	binary.LittleEndian.PutUint32(hdr[:], uint32(len(p)))
+	binary.LittleEndian.PutUint32(hdr[4:], checksum(hdr[:], p))
+	_, err = seg.WriteAt(p, pos+recHdrSize)
+	if err == nil {
+		_, err = seg.WriteAt(hdr[:], pos)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("queue: could not append: %w", err)
+	}
+	q.setTail(off + n)
+	return off, nil
+}
+
+// Sync commits the records appended so far and the positions committed by
+// consumers to stable storage.
+func (q *Queue) Sync() error {
+	if q.meta == nil {
+		return ErrClosed
+	}
+	for _, seg := range q.segs {
+		err := seg.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	// The records before the end of the queue are now on stable storage:
+	// recovery starts from there.
+	q.setSynced(q.tail())
+	return q.meta.Sync()
+}
+
+// Trim removes the segments only holding records that all consumers
+// committed.
+// Queues without consumers are never trimmed.
+func (q *Queue) Trim() error {
+	if q.meta == nil {
+		return ErrClosed
+	}
+	b := q.meta.Bytes()
+	low, found := q.tail(), false
+	for i := metaHdrSize; i < metaSize; i += slotSize {
+		if b[i] == 0 {
+			continue
+		}
+		if pos := int64(binary.LittleEndian.Uint64(b[i+nameSize:])); pos < low {
+			low = pos
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+
+	head := low - low%q.segSize
+	if head <= q.head() {
+		return nil
+	}
+	beg := q.head()
+	q.setHead(head)
+	err := q.meta.Sync()
+	if err != nil {
+		return err
+	}
+	for base := beg - beg%q.segSize; base < head; base += q.segSize {
+		if f, ok := q.segs[base]; ok {
+			delete(q.segs, base)
+			_ = f.Close()
+		}
+		err = os.Remove(q.segName(base))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("queue: could not remove segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the queue and its segments.
+// It does not sync them.
+func (q *Queue) Close() error {
+	if q.meta == nil {
+		return nil
+	}
+	var err error
+	for base, seg := range q.segs {
+		if e := seg.Close(); err == nil {
+			err = e
+		}
+		delete(q.segs, base)
+	}
+	if e := q.meta.Close(); err == nil {
+		err = e
+	}
+	q.meta = nil
+	return err
+}
+
+// Consumer reads the records of a queue in order, on behalf of a named
+// consumer whose position persists across runs once committed.
+type Consumer struct {
+	q    *Queue
+	slot int   // offset of the slot of the consumer in the meta file
+	pos  int64 // offset of the next record to read
+}
+
+// Consumer returns the consumer of the given name, positioned after the
+// last record it committed, or at the first record of the queue if it
+// never committed any.
+// Names are at most 55 bytes long, and a queue has at most 63 consumers.
+func (q *Queue) Consumer(name string) (*Consumer, error) {
+	if q.meta == nil {
+		return nil, ErrClosed
+	}
+	if name == "" || len(name) >= nameSize {
+		return nil, fmt.Errorf("queue: invalid consumer name %q", name)
+	}
+	b := q.meta.Bytes()
+	free := -1
+	for i := metaHdrSize; i < metaSize; i += slotSize {
+		key := b[i : i+nameSize]
+		switch {
+		case key[0] == 0 && free < 0:
+			free = i
+		case string(bytes.TrimRight(key, "\x00")) == name:
+			pos := int64(binary.LittleEndian.Uint64(b[i+nameSize:]))
+			switch {
+			case pos < q.head():
+				pos = q.head()
+			case pos > q.tail():
+				// The records the consumer committed were lost.
+				pos = q.tail()
+			}
+			return &Consumer{q: q, slot: i, pos: pos}, nil
+		}
+	}
+	if free < 0 {
+		return nil, fmt.Errorf("queue: too many consumers")
+	}
+	copy(b[free:free+nameSize], name)
+	binary.LittleEndian.PutUint64(b[free+nameSize:], uint64(q.head()))
+	return &Consumer{q: q, slot: free, pos: q.head()}, nil
+}
+
+// Offset returns the offset of the next record to read.
+func (c *Consumer) Offset() int64 {
+	return c.pos
+}
+
+// Next returns a copy of the next record and its offset.
+// It returns io.EOF when all the records appended so far were read.
+func (c *Consumer) Next() ([]byte, int64, error) {
+	q := c.q
+	if q.meta == nil {
+		return nil, 0, ErrClosed
+	}
+	if c.pos >= q.tail() {
+		return nil, 0, io.EOF
+	}
+	off, p, next, err := q.record(c.pos)
+	if errors.Is(err, errNoRecord) {
+		return nil, 0, fmt.Errorf("%w: missing segment at offset %d", ErrCorrupt, c.pos)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	c.pos = next
+	return p, off, nil
+}
+
+// Commit records the position of the consumer, after the last record
+// returned by Next, so that it resumes from there when the queue is
+// reopened.
+// The position reaches stable storage on the next Sync.
+func (c *Consumer) Commit() error {
+	if c.q.meta == nil {
+		return ErrClosed
+	}
+	binary.LittleEndian.PutUint64(c.q.meta.Bytes()[c.slot+nameSize:], uint64(c.pos))
+	return nil
+}
+
+// Ack moves the consumer right after the record at off, returned by Next,
+// and commits its position.
+func (c *Consumer) Ack(off int64) error {
+	if c.q.meta == nil {
+		return ErrClosed
+	}
+	_, _, next, err := c.q.record(off)
+	if err != nil {
+		return fmt.Errorf("queue: could not ack record at offset %d: %w", off, err)
+	}
+	c.pos = next
+	return c.Commit()
+}
@@ -0,0 +1,280 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, 256)
+	if err != nil {
+		t.Fatalf("could not open queue: %+v", err)
+	}
+	defer q.Close()
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		_, err = q.Append([]byte(fmt.Sprintf("record-%03d", i)))
+		if err != nil {
+			t.Fatalf("could not append record %d: %+v", i, err)
+		}
+	}
+	_, err = q.Append(nil)
+	if err != nil {
+		t.Fatalf("could not append empty record: %+v", err)
+	}
+	_, err = q.Append(make([]byte, 256))
+	if err == nil {
+		t.Fatalf("expected an error appending a record larger than a segment")
+	}
+
+	c, err := q.Consumer("reader")
+	if err != nil {
+		t.Fatalf("could not create consumer: %+v", err)
+	}
+	for i := 0; i < n/2; i++ {
+		p, _, err := c.Next()
+		if err != nil {
+			t.Fatalf("could not read record %d: %+v", i, err)
+		}
+		if got, want := string(p), fmt.Sprintf("record-%03d", i); got != want {
+			t.Fatalf("invalid record: got=%q, want=%q", got, want)
+		}
+	}
+	err = c.Commit()
+	if err != nil {
+		t.Fatalf("could not commit: %+v", err)
+	}
+	err = q.Trim()
+	if err != nil {
+		t.Fatalf("could not trim: %+v", err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "0000000000000000.seg"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("first segment not trimmed: %+v", err)
+	}
+	err = q.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	err = q.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+
+	q, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("could not reopen queue: %+v", err)
+	}
+	c, err = q.Consumer("reader")
+	if err != nil {
+		t.Fatalf("could not reopen consumer: %+v", err)
+	}
+	for i := n / 2; i < n; i++ {
+		p, _, err := c.Next()
+		if err != nil {
+			t.Fatalf("could not read record %d: %+v", i, err)
+		}
+		if got, want := string(p), fmt.Sprintf("record-%03d", i); got != want {
+			t.Fatalf("invalid record: got=%q, want=%q", got, want)
+		}
+	}
+	p, _, err := c.Next()
+	if err != nil || len(p) != 0 {
+		t.Fatalf("could not read empty record: %q, %+v", p, err)
+	}
+	_, _, err = c.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("invalid error at end of queue: %+v", err)
+	}
+}
+
+func TestQueueRecover(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, 4096)
+	if err != nil {
+		t.Fatalf("could not open queue: %+v", err)
+	}
+	defer q.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err = q.Append([]byte(fmt.Sprintf("record-%d", i)))
+		if err != nil {
+			t.Fatalf("could not append record %d: %+v", i, err)
+		}
+	}
+	off, err := q.Append([]byte("record-3"))
+	if err != nil {
+		t.Fatalf("could not append record: %+v", err)
+	}
+	// Simulate a crash losing the end of the queue, then corrupt the last
+	// record.
+	q.setTail(0)
+	q.segs[0].Bytes()[off+recHdrSize] ^= 0xff
+	err = q.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+
+	q, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("could not reopen queue: %+v", err)
+	}
+	if got, want := q.tail(), off; got != want {
+		t.Fatalf("invalid recovered end of queue: got=%d, want=%d", got, want)
+	}
+	c, err := q.Consumer("reader")
+	if err != nil {
+		t.Fatalf("could not create consumer: %+v", err)
+	}
+	var last int64
+	for i := 0; i < 3; i++ {
+		_, last, err = c.Next()
+		if err != nil {
+			t.Fatalf("could not read record %d: %+v", i, err)
+		}
+	}
+	err = c.Ack(last)
+	if err != nil {
+		t.Fatalf("could not ack: %+v", err)
+	}
+	if got, want := c.Offset(), off; got != want {
+		t.Fatalf("invalid offset: got=%d, want=%d", got, want)
+	}
+}
+
+func TestQueueWindowed(t *testing.T) {
+	defer mmap.SetMaxMappedBytes(mmap.SetMaxMappedBytes(1 << 20))
+
+	dir := t.TempDir()
+	q, err := Open(dir, 2<<20)
+	if err != nil {
+		t.Fatalf("could not open queue: %+v", err)
+	}
+	defer q.Close()
+
+	const n = 10
+	for i := 0; i < n; i++ {
+		_, err = q.Append([]byte(fmt.Sprintf("record-%d", i)))
+		if err != nil {
+			t.Fatalf("could not append record %d: %+v", i, err)
+		}
+	}
+	if q.segs[0].Bytes() != nil {
+		t.Fatalf("segment not mapped through a window")
+	}
+	c, err := q.Consumer("reader")
+	if err != nil {
+		t.Fatalf("could not create consumer: %+v", err)
+	}
+	for i := 0; i < n; i++ {
+		p, _, err := c.Next()
+		if err != nil {
+			t.Fatalf("could not read record %d: %+v", i, err)
+		}
+		if got, want := string(p), fmt.Sprintf("record-%d", i); got != want {
+			t.Fatalf("invalid record: got=%q, want=%q", got, want)
+		}
+	}
+}
+
+func TestQueueLost(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, 4096)
+	if err != nil {
+		t.Fatalf("could not open queue: %+v", err)
+	}
+	defer q.Close()
+
+	_, err = q.Append([]byte("record-0"))
+	if err != nil {
+		t.Fatalf("could not append record: %+v", err)
+	}
+	err = q.Sync()
+	if err != nil {
+		t.Fatalf("could not sync: %+v", err)
+	}
+	lost, err := q.Append([]byte("record-1"))
+	if err != nil {
+		t.Fatalf("could not append record: %+v", err)
+	}
+	_, err = q.Append([]byte("record-2"))
+	if err != nil {
+		t.Fatalf("could not append record: %+v", err)
+	}
+	err = q.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+
+	// Simulate a crash after the end of the queue, but not the second
+	// record, reached the disk.
+	fd, err := os.OpenFile(filepath.Join(dir, "0000000000000000.seg"), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("could not open segment: %+v", err)
+	}
+	_, err = fd.WriteAt(make([]byte, recHdrSize+len("record-1")), lost)
+	if e := fd.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		t.Fatalf("could not zero record: %+v", err)
+	}
+
+	read := func(q *Queue, want ...string) {
+		t.Helper()
+		c, err := q.Consumer("reader")
+		if err != nil {
+			t.Fatalf("could not create consumer: %+v", err)
+		}
+		for _, want := range want {
+			p, _, err := c.Next()
+			if err != nil {
+				t.Fatalf("could not read record %q: %+v", want, err)
+			}
+			if got := string(p); got != want {
+				t.Fatalf("invalid record: got=%q, want=%q", got, want)
+			}
+		}
+		_, _, err = c.Next()
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("invalid error at end of queue: %+v", err)
+		}
+	}
+
+	q, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("could not reopen queue: %+v", err)
+	}
+	if got, want := q.tail(), lost; got != want {
+		t.Fatalf("invalid recovered end of queue: got=%d, want=%d", got, want)
+	}
+	read(q, "record-0")
+
+	// The third record must not follow the ones appended over the second.
+	_, err = q.Append([]byte("record-3"))
+	if err != nil {
+		t.Fatalf("could not append record: %+v", err)
+	}
+	err = q.Close()
+	if err != nil {
+		t.Fatalf("could not close: %+v", err)
+	}
+	q, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("could not reopen queue: %+v", err)
+	}
+	defer q.Close()
+	read(q, "record-0", "record-3")
+}
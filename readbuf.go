@@ -0,0 +1,105 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// Buffers handed out by ReadAtBuf are pooled by powers of two, from 512
+// bytes to 4 MiB. Larger buffers are allocated for each call.
+const (
+	minBufShift = 9
+	maxBufShift = 22
+)
+
+var bufPools [maxBufShift - minBufShift + 1]sync.Pool
+
+// pooledBuf is a buffer handed out by ReadAtBuf.
+// Its generation is bumped each time it is released, so that the release
+// funcs of the previous users of the buffer do nothing once it is handed
+// out again.
+type pooledBuf struct {
+	b     []byte
+	class int
+	gen   atomic.Uint64
+}
+
+// releaser returns the func releasing the buffer for its current user.
+func (p *pooledBuf) releaser() func() {
+	gen := p.gen.Load()
+	return func() {
+		if p.class < 0 || !p.gen.CompareAndSwap(gen, gen+1) {
+			return
+		}
+		bufPools[p.class].Put(p)
+	}
+}
+
+// bufClass returns the index of the pool of buffers of at least n bytes,
+// or -1 if such buffers are not pooled.
+func bufClass(n int64) int {
+	if n <= 1<<minBufShift {
+		return 0
+	}
+	shift := bits.Len64(uint64(n - 1))
+	if shift > maxBufShift {
+		return -1
+	}
+	return shift - minBufShift
+}
+
+// ReadAtBuf returns a copy of the n bytes of the file at off, and a func
+// releasing the copy once it is not needed anymore.
+//
+// The copies are made into pooled buffers, which makes ReadAtBuf suited to
+// servers answering concurrent requests for ranges of mapped files: they
+// do not allocate a buffer for each request, and handlers never hold on to the
+// mapping itself, which may be unmapped while they run.
+// release must be called once done with the copy, even if an error is
+// returned, and the copy must not be used afterwards. Calling it again
+// does nothing, even once the buffer was handed out to another caller.
+//
+// As with ReadAt, fewer than n bytes are returned, along with io.EOF, when
+// the file ends before off+n.
+func (f *File) ReadAtBuf(off, n int64) (p []byte, release func(), err error) {
+	if n < 0 {
+		n = 0
+	}
+	short := false
+	if f != nil && off >= 0 {
+		// Do not take a buffer larger than what is left of the file.
+		if err := f.refresh(); err != nil {
+			return nil, func() {}, err
+		}
+		if rem := f.size() - off; n > rem {
+			n, short = rem, true
+			if n < 0 {
+				n = 0
+			}
+		}
+	}
+	class := bufClass(n)
+	var buf *pooledBuf
+	if class >= 0 {
+		buf, _ = bufPools[class].Get().(*pooledBuf)
+	}
+	if buf == nil {
+		size := n
+		if class >= 0 {
+			size = 1 << (class + minBufShift)
+		}
+		buf = &pooledBuf{b: make([]byte, size), class: class}
+	}
+
+	c, err := f.ReadAt(buf.b[:n], off)
+	if err == nil && short {
+		err = io.EOF
+	}
+	return buf.b[:c], buf.releaser(), err
+}
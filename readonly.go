@@ -0,0 +1,167 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"io"
+	"os"
+)
+
+// ReadOnly is a memory-mapped file opened for reading only.
+//
+// ReadOnly only exposes the read-side methods of File, so that accidental
+// writes are caught at compile time rather than failing at run time.
+type ReadOnly struct {
+	f *File
+}
+
+// OpenReadOnly memory-maps the named file for reading only.
+// Options may be provided to further tune how the file is opened and mapped.
+func OpenReadOnly(filename string, opts ...Option) (*ReadOnly, error) {
+	f, err := OpenFile(filename, Read, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadOnly{f: f}, nil
+}
+
+// Len returns the length of the underlying memory-mapped file.
+func (r *ReadOnly) Len() int {
+	return r.f.Len()
+}
+
+// Size returns the length of the underlying memory-mapped file.
+func (r *ReadOnly) Size() int64 {
+	return r.f.Size()
+}
+
+// At returns the byte at index i.
+// At panics if i is out of range, like File.At.
+func (r *ReadOnly) At(i int) byte {
+	return r.f.At(i)
+}
+
+// Stat returns the FileInfo structure describing file.
+func (r *ReadOnly) Stat() (os.FileInfo, error) {
+	return r.f.Stat()
+}
+
+// StatLive returns the FileInfo structure describing file as it is now,
+// like File.StatLive.
+func (r *ReadOnly) StatLive() (os.FileInfo, error) {
+	return r.f.StatLive()
+}
+
+// Changed reports whether the file was modified since it was last mapped,
+// like File.Changed.
+func (r *ReadOnly) Changed() (bool, error) {
+	return r.f.Changed()
+}
+
+// Read implements the io.Reader interface.
+func (r *ReadOnly) Read(p []byte) (int, error) {
+	return r.f.Read(p)
+}
+
+// ReadByte implements the io.ByteReader interface.
+func (r *ReadOnly) ReadByte() (byte, error) {
+	return r.f.ReadByte()
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *ReadOnly) ReadAt(p []byte, off int64) (int, error) {
+	return r.f.ReadAt(p, off)
+}
+
+// ReadFullAt reads exactly len(p) bytes of the file at off into p, like
+// File.ReadFullAt.
+func (r *ReadOnly) ReadFullAt(p []byte, off int64) error {
+	return r.f.ReadFullAt(p, off)
+}
+
+// WriteTo implements the io.WriterTo interface, like File.WriteTo.
+func (r *ReadOnly) WriteTo(w io.Writer) (int64, error) {
+	return r.f.WriteTo(w)
+}
+
+// ReadAtBuf returns a pooled copy of the n bytes of the file at off, like
+// File.ReadAtBuf.
+func (r *ReadOnly) ReadAtBuf(off, n int64) ([]byte, func(), error) {
+	return r.f.ReadAtBuf(off, n)
+}
+
+// Peek returns the next n bytes without advancing the cursor, like
+// File.Peek.
+func (r *ReadOnly) Peek(n int) ([]byte, error) {
+	return r.f.Peek(n)
+}
+
+// Discard skips the next n bytes, like File.Discard.
+func (r *ReadOnly) Discard(n int) (int, error) {
+	return r.f.Discard(n)
+}
+
+// Seek implements the io.Seeker interface.
+func (r *ReadOnly) Seek(offset int64, whence int) (int64, error) {
+	return r.f.Seek(offset, whence)
+}
+
+// Pos returns the position of the cursor, like File.Pos.
+func (r *ReadOnly) Pos() int64 {
+	return r.f.Pos()
+}
+
+// PushPos saves the position of the cursor, like File.PushPos.
+func (r *ReadOnly) PushPos() {
+	r.f.PushPos()
+}
+
+// PopPos restores the last saved position of the cursor, like File.PopPos.
+func (r *ReadOnly) PopPos() error {
+	return r.f.PopPos()
+}
+
+// Prefetch asks the OS to read the [off, off+n) range of the file into
+// memory ahead of access.
+func (r *ReadOnly) Prefetch(off, n int64) error {
+	return r.f.Prefetch(off, n)
+}
+
+// Release tells the OS that the [off, off+n) range of the file is not
+// needed anymore.
+func (r *ReadOnly) Release(off, n int64) error {
+	return r.f.Release(off, n)
+}
+
+// Remap maps the file again, so that the mapping reflects the current size
+// of the file.
+func (r *ReadOnly) Remap() error {
+	return r.f.Remap()
+}
+
+// SectionReader returns a reader of the [off, off+n) range of the file.
+func (r *ReadOnly) SectionReader(off, n int64) *io.SectionReader {
+	return io.NewSectionReader(r, off, n)
+}
+
+// StreamReader returns a reader of the file from its start, tuned for
+// streaming through the whole file once.
+func (r *ReadOnly) StreamReader() *StreamReader {
+	return r.f.StreamReader()
+}
+
+// Close closes the file.
+func (r *ReadOnly) Close() error {
+	return r.f.Close()
+}
+
+var (
+	_ io.Reader     = (*ReadOnly)(nil)
+	_ io.ReaderAt   = (*ReadOnly)(nil)
+	_ io.ByteReader = (*ReadOnly)(nil)
+	_ io.WriterTo   = (*ReadOnly)(nil)
+	_ io.Seeker     = (*ReadOnly)(nil)
+	_ io.Closer     = (*ReadOnly)(nil)
+)
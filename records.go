@@ -0,0 +1,78 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "fmt"
+
+// RecordView gives index-based access to a file made of fixed-size
+// records.
+type RecordView struct {
+	f    *File
+	size int
+}
+
+// Records returns a view of the file as a sequence of records of
+// recordSize bytes.
+// Trailing bytes not making up a whole record are not part of the view.
+// Records panics if recordSize is not positive.
+func (f *File) Records(recordSize int) *RecordView {
+	if recordSize <= 0 {
+		panic("mmap: invalid record size")
+	}
+	return &RecordView{f: f, size: recordSize}
+}
+
+// Len returns the number of records of the view.
+func (v *RecordView) Len() int {
+	return int(v.f.size() / int64(v.size))
+}
+
+// At returns the i-th record.
+// The returned slice aliases the mapping, unless the file is mapped
+// through a sliding window: it is then a copy of the record.
+// At panics if i is out of range.
+func (v *RecordView) At(i int) []byte {
+	if i < 0 || v.Len() <= i {
+		panic("index out of range")
+	}
+	off := int64(i) * int64(v.size)
+	if v.f.w != nil {
+		rec := make([]byte, v.size)
+		if _, err := v.f.readAt(rec, off); err != nil {
+			panic(err)
+		}
+		return rec
+	}
+	return v.f.data[off : off+int64(v.size) : off+int64(v.size)]
+}
+
+// Set sets the i-th record to p, which must be exactly one record long.
+// Set panics if i is out of range.
+func (v *RecordView) Set(i int, p []byte) error {
+	if i < 0 || v.Len() <= i {
+		panic("index out of range")
+	}
+	if len(p) != v.size {
+		return fmt.Errorf("mmap: invalid record length %d, want %d", len(p), v.size)
+	}
+	if !v.f.wflag() {
+		return errBadFD
+	}
+	_, err := v.f.writeAt(p, int64(i)*int64(v.size))
+	if err != nil {
+		return err
+	}
+	return v.f.wrote(int64(v.size))
+}
+
+// Range calls fn for each record of the view in order, until fn returns
+// false.
+func (v *RecordView) Range(fn func(i int, rec []byte) bool) {
+	for i, n := 0, v.Len(); i < n; i++ {
+		if !fn(i, v.At(i)) {
+			return
+		}
+	}
+}
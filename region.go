@@ -0,0 +1,199 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Region is a memory mapping of a range of a file, for huge files of which
+// only a part is needed, or that do not fit in the address space of the
+// process.
+//
+// The OS maps files from offsets that are multiples of its allocation
+// granularity: the page size on Unix, and 64 KiB on Windows. A region maps
+// the range from the closest such offset below it, as reported by
+// BaseOffset, but exposes the requested range only: the offsets of its
+// methods are relative to the start of the region, and FileOffset and
+// RegionOffset translate them to and from offsets in the file.
+type Region struct {
+	f    *File  // the file, mapped through a window that is never used.
+	view []byte // the mapping, from base.
+	data []byte // the bytes of the region, within view.
+	off  int64  // offset of the region in the file.
+	base int64  // offset of the mapping in the file.
+}
+
+// OpenRegion memory-maps the [off, off+n) range of the named file for
+// reading/writing, depending on the flag value.
+// The range must lie within the file, whatever the alignment of off.
+// Options apply as with OpenFile, but for MapAt, WithDirtyTracking and
+// snapshots, which regions do not support.
+func OpenRegion(filename string, flag Flag, off, n int64, opts ...Option) (*Region, error) {
+	if off < 0 || n <= 0 {
+		return nil, fmt.Errorf("mmap: invalid region [%d, %d+%d) of %q", off, off, n, filename)
+	}
+	cfg := newOptions(opts)
+	if cfg.addr != 0 || cfg.private || cfg.dirty {
+		return nil, fmt.Errorf("mmap: region of %q can not be mapped at a fixed address, snapshotted nor track dirty pages", filename)
+	}
+	cfg.window = windowSpan
+	f, err := openLogged(filename, flag, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if f.size() < off+n || off+n < off {
+		_ = f.Close()
+		return nil, fmt.Errorf("mmap: region [%d, %d+%d) is past the end of %q", off, off, n, filename)
+	}
+	if !f.Mapped() {
+		_ = f.Close()
+		return nil, fmt.Errorf("mmap: region of %q can not be mapped: %w", filename, errUnsupported)
+	}
+
+	base := off - off%allocGranularity()
+	size := off + n - base
+	if int64(int(size)) != size || size > maxView {
+		_ = f.Close()
+		return nil, fmt.Errorf("mmap: region [%d, %d+%d) of %q is too large to be mapped", off, off, n, filename)
+	}
+	reserved, err := f.reserve(nil, size)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	view, err := f.mapView(base, int(size))
+	if err != nil {
+		unreserve(reserved)
+		_ = f.Close()
+		return nil, err
+	}
+	statMap(view)
+	unreserve(reserved)
+	return &Region{
+		f:    f,
+		view: view,
+		data: view[off-base : off-base+n : off-base+n],
+		off:  off,
+		base: base,
+	}, nil
+}
+
+// Bytes returns the mapped bytes of the region.
+// The slice must not be used once the region is closed.
+func (r *Region) Bytes() []byte {
+	return r.data
+}
+
+// Len returns the length of the region.
+func (r *Region) Len() int {
+	return len(r.data)
+}
+
+// Offset returns the offset of the region in the file, as passed to
+// OpenRegion.
+func (r *Region) Offset() int64 {
+	return r.off
+}
+
+// BaseOffset returns the offset in the file of the mapping holding the
+// region: Offset rounded down to the allocation granularity of the OS.
+func (r *Region) BaseOffset() int64 {
+	return r.base
+}
+
+// FileOffset returns the offset in the file of the byte at offset i of the
+// region.
+func (r *Region) FileOffset(i int64) int64 {
+	return r.off + i
+}
+
+// RegionOffset returns the offset in the region of the byte at offset off
+// of the file, and whether the region holds it.
+func (r *Region) RegionOffset(off int64) (int64, bool) {
+	i := off - r.off
+	return i, i >= 0 && i < int64(len(r.data))
+}
+
+// ReadAt implements the io.ReaderAt interface, off being relative to the
+// start of the region.
+func (r *Region) ReadAt(p []byte, off int64) (int, error) {
+	if r.data == nil {
+		return 0, errClosed
+	}
+	if !r.f.rflag() {
+		return 0, errBadFD
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("mmap: invalid ReadAt offset %d", off)
+	}
+	if int64(len(r.data)) <= off {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements the io.WriterAt interface, off being relative to the
+// start of the region.
+// Writes past the end of the region write the bytes that fit, and fail
+// with ErrNoSpace.
+func (r *Region) WriteAt(p []byte, off int64) (int, error) {
+	if r.data == nil {
+		return 0, errClosed
+	}
+	if !r.f.wflag() {
+		return 0, errBadFD
+	}
+	if off < 0 || int64(len(r.data)) < off {
+		return 0, fmt.Errorf("mmap: invalid WriteAt offset %d", off)
+	}
+	n := copy(r.data[off:], p)
+	if n < len(p) {
+		return n, ErrNoSpace
+	}
+	return n, nil
+}
+
+// Sync commits the contents of the region to stable storage.
+// As with File.Sync, it does nothing for regions opened for reading only,
+// and also flushes the file buffers when opened WithFullSync.
+func (r *Region) Sync() error {
+	if r.data == nil {
+		return errClosed
+	}
+	if !r.f.wflag() || r.f.cfg.noSync {
+		return nil
+	}
+	beg := time.Now()
+	err := r.f.syncView(r.view)
+	if err == nil && r.f.cfg.fullSync {
+		err = r.f.syncFD()
+	}
+	statSync(beg)
+	return err
+}
+
+// Close unmaps the region, and closes the file.
+func (r *Region) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	f, view := r.f, r.view
+	r.f, r.view, r.data = nil, nil, nil
+	statUnmap(view)
+	return joinErrors(pathError("mmap.close", f.fd.Name(), f.unmapView(view)), f.Close())
+}
+
+var (
+	_ io.ReaderAt = (*Region)(nil)
+	_ io.WriterAt = (*Region)(nil)
+	_ io.Closer   = (*Region)(nil)
+)
@@ -0,0 +1,135 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OpenFileInfo describes an open file, as returned by OpenFiles.
+type OpenFileInfo struct {
+	Path   string    // Name of the file, as opened.
+	Size   int64     // Size of the mapping of the file.
+	Flag   Flag      // Access to the file.
+	Opened time.Time // Time the file was opened.
+}
+
+// registry holds the open files, once enabled with TrackOpenFiles.
+var registry struct {
+	enabled atomic.Bool
+
+	// files are keyed by their order of opening, rather than by *File, so
+	// that the registry does not keep them from being finalized.
+	mu    sync.Mutex
+	files map[uint64]*openEntry
+	seq   uint64 // number of files registered.
+}
+
+// openEntry is the entry of an open file in the registry.
+type openEntry struct {
+	name   string
+	opened time.Time
+	seq    uint64 // order of opening.
+	size   atomic.Int64
+	flag   atomic.Uint32
+}
+
+// TrackOpenFiles enables, or disables, the registry of the open files of
+// the process, as reported by OpenFiles, so that operators can see what it
+// has mapped at runtime.
+// Only the files opened while the registry is enabled are reported.
+// Disabling the registry forgets them.
+func TrackOpenFiles(enable bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.enabled.Store(enable)
+	if !enable {
+		registry.files = nil
+	}
+}
+
+// OpenFiles returns the files opened, and not closed yet, since the
+// registry was enabled with TrackOpenFiles, in order of opening.
+// Files of the sliding windows of OpenRegion are included.
+func OpenFiles() []OpenFileInfo {
+	registry.mu.Lock()
+	entries := make([]*openEntry, 0, len(registry.files))
+	for _, e := range registry.files {
+		entries = append(entries, e)
+	}
+	registry.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	infos := make([]OpenFileInfo, len(entries))
+	for i, e := range entries {
+		infos[i] = OpenFileInfo{
+			Path:   e.name,
+			Size:   e.size.Load(),
+			Flag:   Flag(e.flag.Load()),
+			Opened: e.opened,
+		}
+	}
+	return infos
+}
+
+// opened records that f was opened: it sets up the reference count of its
+// mapping, sets its finalizer, as set with WithFinalizer, and registers it
+// if the registry is enabled.
+func (f *File) opened() {
+	f.initRefs()
+	f.setFinalizer()
+	if !registry.enabled.Load() {
+		return
+	}
+	e := &openEntry{name: f.name, opened: time.Now()}
+	if e.name == "" {
+		e.name = f.fd.Name()
+	}
+	e.size.Store(f.size())
+	e.flag.Store(uint32(f.flag))
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if !registry.enabled.Load() {
+		return
+	}
+	if registry.files == nil {
+		registry.files = make(map[uint64]*openEntry)
+	}
+	registry.seq++
+	e.seq = registry.seq
+	registry.files[e.seq] = e
+	f.reg = e
+}
+
+// updated records the current size and access of f in the registry, and
+// its size in its mapper, once it was mapped again.
+func (f *File) updated() {
+	f.mapper.resized(f, f.size())
+	if e := f.reg; e != nil {
+		e.size.Store(f.size())
+		e.flag.Store(uint32(f.flag))
+	}
+}
+
+// closing records that f is being closed: it clears its finalizer, and
+// removes it from the registry.
+func (f *File) closing() {
+	runtime.SetFinalizer(f, nil)
+	if f.reg == nil {
+		return
+	}
+	seq := f.reg.seq
+	f.reg = nil
+	registry.mu.Lock()
+	delete(registry.files, seq)
+	registry.mu.Unlock()
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "time"
+
+// RemotePolicy tells how to map files living on network or FUSE
+// filesystems, such as NFS, SMB or sshfs.
+//
+// Mapping such files is risky: accessing the mapping raises SIGBUS, rather
+// than returning an error, when the server goes away or the file is
+// truncated by another client, and the pages may hold stale data because
+// the client caches are not kept coherent with the changes of other
+// clients.
+type RemotePolicy int
+
+const (
+	// RemoteAllow maps remote files as local ones. This is the default.
+	RemoteAllow RemotePolicy = iota
+	// RemoteWarn maps remote files as local ones, but calls the warning
+	// hook given to WithRemotePolicy first.
+	RemoteWarn
+	// RemoteFallback reads and writes remote files through their
+	// descriptor, with pread and pwrite, as WithFallback does when mapping
+	// fails.
+	RemoteFallback
+)
+
+// WithRemotePolicy sets how files on network or FUSE filesystems are
+// mapped, as detected from the magic number of their filesystem on Linux,
+// its type name on BSDs and the remote protocol or volume information of
+// the file on Windows.
+//
+// Under RemoteWarn, warn is called with the name of the file and the type
+// of its filesystem, such as "nfs", "smb" or "fuse", before mapping it.
+// Under RemoteFallback, files mapped with MapAt or WithDirtyTracking, and
+// snapshots are still mapped, as their mapping is essential.
+func WithRemotePolicy(policy RemotePolicy, warn func(filename, fstype string)) Option {
+	return func(o *options) {
+		o.remote = policy
+		o.remoteWarn = warn
+	}
+}
+
+// checkRemote applies the remote policy of f, once it is opened and before
+// it is mapped.
+func (f *File) checkRemote() {
+	if f.cfg.remote == RemoteAllow {
+		return
+	}
+	fstype := remoteFS(f.fd)
+	if fstype == "" {
+		return
+	}
+	switch f.cfg.remote {
+	case RemoteWarn:
+		if f.cfg.remoteWarn != nil {
+			f.cfg.remoteWarn(f.name, fstype)
+		}
+	case RemoteFallback:
+		if f.cfg.addr != 0 || f.cfg.private || f.cfg.dirty {
+			return
+		}
+		f.cfg.direct = true
+		f.logEvent("remote", 0, time.Now(), nil)
+	}
+}
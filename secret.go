@@ -0,0 +1,98 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"os"
+)
+
+// Secret is a memory-mapped file holding sensitive data, such as TLS keys
+// or credentials.
+//
+// The pages of a Secret are locked in memory, so they are never written to
+// swap, and excluded from core dumps and from child processes, where
+// supported. Close wipes them before unmapping the file.
+//
+// The mapping of a Secret is private and read-only: wiping it never
+// reaches the file.
+//
+// Note that wiping only zeroes the pages of the mapping, which are private
+// copies of the file: the plaintext stays in the file, and in the page
+// cache of the OS until it evicts it. Secrets that must not outlive the
+// process are better kept in files backed by memory, such as on tmpfs, and
+// removed once opened.
+type Secret struct {
+	f *File
+}
+
+// OpenSecret memory-maps the named file as a Secret.
+// Options may be provided to further tune how the file is opened and mapped.
+func OpenSecret(filename string, opts ...Option) (*Secret, error) {
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.private = true
+		o.fork = forkWipe
+	})
+	f, err := OpenFile(filename, Read, opts...)
+	if err != nil {
+		return nil, err
+	}
+	size := f.Size()
+	if size > 0 {
+		err = f.LockRange(0, size)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("mmap: could not lock %q: %w", filename, err)
+		}
+	}
+	err = f.dontDump()
+	if err != nil {
+		_ = f.UnlockRange(0, size)
+		_ = f.Close()
+		return nil, fmt.Errorf("mmap: could not exclude %q from core dumps: %w", filename, err)
+	}
+	return &Secret{f: f}, nil
+}
+
+// Len returns the length of the secret.
+func (s *Secret) Len() int {
+	return s.f.Len()
+}
+
+// Bytes returns the contents of the secret.
+// The returned slice aliases the mapping: it must not be written to, and
+// not be used after Close.
+func (s *Secret) Bytes() []byte {
+	if s.f.closed() {
+		return nil
+	}
+	return s.f.data
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (s *Secret) ReadAt(p []byte, off int64) (int, error) {
+	return s.f.ReadAt(p, off)
+}
+
+// Close wipes the contents of the mapping of the secret, and closes it.
+// The contents of the file, and of the page cache, are left as is.
+func (s *Secret) Close() error {
+	if s == nil {
+		return os.ErrInvalid
+	}
+	f := s.f
+	if f.closed() {
+		return nil
+	}
+	data := f.data
+	for i := range data {
+		data[i] = 0
+	}
+	var err error
+	if len(data) > 0 {
+		err = f.UnlockRange(0, int64(len(data)))
+	}
+	return joinErrors(err, f.Close())
+}
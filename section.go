@@ -0,0 +1,19 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "io"
+
+// ReaderAt returns the file as an io.ReaderAt, for APIs taking a ReaderAt
+// along with the size of its contents, as returned by Size.
+func (f *File) ReaderAt() io.ReaderAt {
+	return f
+}
+
+// SectionReader returns a reader of the [off, off+n) range of the file.
+// The reader has its own offset, and does not move the cursor of the file.
+func (f *File) SectionReader(off, n int64) *io.SectionReader {
+	return io.NewSectionReader(f, off, n)
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/go-mmap/mmap"
+)
+
+var countersMagic = [8]byte{'m', 'm', 'a', 'p', 'c', 'n', 't', 'r'}
+
+// countersHeader is the header of a counters file, followed by the shards.
+type countersHeader struct {
+	magic    [8]byte
+	counters uint64
+	shards   uint64
+	_        [cacheLine - 24]byte
+}
+
+// countersHeaderSize is the size of the header of a counters file.
+const countersHeaderSize = int(unsafe.Sizeof(countersHeader{}))
+
+// Counters is a set of int64 counters laid out in a shared memory-mapped
+// file, that any number of processes increment concurrently while others
+// read their totals, as for metrics collected from worker processes.
+//
+// Each counter is striped across shards, each on its own cache lines:
+// increments go to the shard of the processor running the goroutine, as
+// far as the runtime lets it be told, so that processes and goroutines
+// running on different processors do not contend with each other.
+// Sum adds up the shards of a counter.
+type Counters struct {
+	f      *mmap.File
+	shards [][]int64
+	n      int
+	pid    int // offset of the shards of the process.
+
+	// slots hands out the indices of the shards per processor: sync.Pool
+	// keeps a cache per processor.
+	slots sync.Pool
+	next  atomic.Uint32
+}
+
+// CreateCounters creates the named file holding n zeroed counters striped
+// across shards shards, and opens it.
+// A good number of shards is the number of processors of the machine, as
+// returned by runtime.NumCPU.
+// An existing file is truncated.
+func CreateCounters(filename string, n, shards int) (*Counters, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("shm: invalid number of counters %d", n)
+	}
+	if shards <= 0 {
+		return nil, fmt.Errorf("shm: invalid number of shards %d", shards)
+	}
+
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not create counters: %w", err)
+	}
+	err = fd.Truncate(int64(countersHeaderSize + shards*shardSize(n)))
+	if err == nil {
+		err = fd.Close()
+	} else {
+		_ = fd.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not size counters %q: %w", filename, err)
+	}
+
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	b, err := mapping(f, countersHeaderSize, "counters file")
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	hdr := (*countersHeader)(unsafe.Pointer(&b[0]))
+	hdr.counters = uint64(n)
+	hdr.shards = uint64(shards)
+	copy(hdr.magic[:], countersMagic[:])
+	return newCounters(f, b, n, shards), nil
+}
+
+// OpenCounters opens the counters held by the named file, created with
+// CreateCounters.
+func OpenCounters(filename string) (*Counters, error) {
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	b, err := mapping(f, countersHeaderSize, "counters file")
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	hdr := (*countersHeader)(unsafe.Pointer(&b[0]))
+	n, shards := hdr.counters, hdr.shards
+	if hdr.magic != countersMagic || n == 0 || shards == 0 ||
+		uint64(f.Len()-countersHeaderSize) != shards*uint64(shardSize(int(n))) {
+		_ = f.Close()
+		return nil, fmt.Errorf("shm: %q is not a counters file", filename)
+	}
+	return newCounters(f, b, int(n), int(shards)), nil
+}
+
+// shardSize returns the size of a shard of n counters, rounded up to a
+// whole number of cache lines.
+func shardSize(n int) int {
+	return (n*8 + cacheLine - 1) &^ (cacheLine - 1)
+}
+
+// newCounters returns the counters held by f, mapped at b.
+func newCounters(f *mmap.File, b []byte, n, shards int) *Counters {
+	b = b[countersHeaderSize:]
+	c := &Counters{
+		f:      f,
+		shards: make([][]int64, shards),
+		n:      n,
+		pid:    os.Getpid(),
+	}
+	size := shardSize(n)
+	for i := range c.shards {
+		ptr := (*int64)(unsafe.Pointer(&b[i*size]))
+		c.shards[i] = unsafe.Slice(ptr, n)
+	}
+	return c
+}
+
+// Len returns the number of counters.
+func (c *Counters) Len() int {
+	return c.n
+}
+
+// Shards returns the number of shards each counter is striped across.
+func (c *Counters) Shards() int {
+	return len(c.shards)
+}
+
+// shard returns the shard the running goroutine increments, and the slot
+// to put back into c.slots once done.
+func (c *Counters) shard() ([]int64, *uint32) {
+	slot, _ := c.slots.Get().(*uint32)
+	if slot == nil {
+		slot = new(uint32)
+		*slot = c.next.Add(1) - 1
+	}
+	i := (uint(c.pid) + uint(*slot)) % uint(len(c.shards))
+	return c.shards[i], slot
+}
+
+// Add adds delta to the counter i.
+// Add panics if i is out of range.
+func (c *Counters) Add(i int, delta int64) {
+	if len(c.shards) == 0 {
+		// Unmapped.
+		return
+	}
+	shard, slot := c.shard()
+	atomic.AddInt64(&shard[i], delta)
+	c.slots.Put(slot)
+}
+
+// Inc increments the counter i.
+// Inc panics if i is out of range.
+func (c *Counters) Inc(i int) {
+	c.Add(i, 1)
+}
+
+// Sum returns the total of the counter i, across all the shards.
+// Sum panics if i is out of range.
+//
+// The shards are loaded one after the other, while other processes keep
+// incrementing them: the total is not a snapshot, but it is exact once the
+// counter stops changing.
+func (c *Counters) Sum(i int) int64 {
+	var sum int64
+	for _, shard := range c.shards {
+		sum += atomic.LoadInt64(&shard[i])
+	}
+	return sum
+}
+
+// Close unmaps the counters: Add then does nothing, and Sum returns zero.
+func (c *Counters) Close() error {
+	c.shards = nil
+	return c.f.Close()
+}
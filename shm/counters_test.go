@@ -0,0 +1,103 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestCounters(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "counters")
+	w, err := CreateCounters(fname, 3, 4)
+	if err != nil {
+		t.Fatalf("could not create counters: %+v", err)
+	}
+	defer w.Close()
+
+	r, err := OpenCounters(fname)
+	if err != nil {
+		t.Fatalf("could not open counters: %+v", err)
+	}
+	defer r.Close()
+	if r.Len() != 3 || r.Shards() != 4 {
+		t.Fatalf("invalid layout: %d counters, %d shards", r.Len(), r.Shards())
+	}
+
+	const (
+		workers = 8
+		n       = 10000
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				w.Inc(0)
+				w.Add(2, -2)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := r.Sum(0), int64(workers*n); got != want {
+		t.Fatalf("invalid sum: got=%d, want=%d", got, want)
+	}
+	if got := r.Sum(1); got != 0 {
+		t.Fatalf("invalid sum of untouched counter: %d", got)
+	}
+	if got, want := r.Sum(2), int64(-2*workers*n); got != want {
+		t.Fatalf("invalid sum: got=%d, want=%d", got, want)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad")
+	err = os.WriteFile(bad, make([]byte, 256), 0600)
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+	if _, err := OpenCounters(bad); err == nil {
+		t.Fatalf("expected an error opening an invalid file")
+	}
+	if _, err := CreateCounters(fname, 0, 1); err == nil {
+		t.Fatalf("expected an error creating no counters")
+	}
+}
+
+func TestCountersClosed(t *testing.T) {
+	c, err := CreateCounters(filepath.Join(t.TempDir(), "counters"), 2, 4)
+	if err != nil {
+		t.Fatalf("could not create counters: %+v", err)
+	}
+	c.Inc(0)
+	err = c.Close()
+	if err != nil {
+		t.Fatalf("could not close counters: %+v", err)
+	}
+
+	c.Inc(0)
+	c.Add(1, 2)
+	if got := c.Sum(0); got != 0 {
+		t.Fatalf("invalid sum of unmapped counter: %d", got)
+	}
+}
+
+func TestCountersWindowed(t *testing.T) {
+	defer mmap.SetMaxMappedBytes(mmap.SetMaxMappedBytes(4096))
+
+	fname := filepath.Join(t.TempDir(), "counters")
+	_, err := CreateCounters(fname, 16, 1<<10)
+	if err == nil {
+		t.Fatalf("expected an error creating counters mapped through a window")
+	}
+	_, err = OpenCounters(fname)
+	if err == nil {
+		t.Fatalf("expected an error opening counters mapped through a window")
+	}
+}
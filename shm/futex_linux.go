@@ -0,0 +1,31 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// Futex operations, without FUTEX_PRIVATE_FLAG so that they work across
+// processes sharing the mapping.
+const (
+	futexWait = 0
+	futexWake = 1
+)
+
+// wait blocks while addr holds val, until woken up by wake.
+// It may return spuriously.
+func wait(addr *atomic.Uint32, val uint32, b *backoff) {
+	_, _, _ = syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), futexWait, uintptr(val), 0, 0, 0)
+}
+
+// wake wakes up all the waiters on addr, in all processes.
+func wake(addr *atomic.Uint32) {
+	_, _, _ = syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), futexWake, math.MaxInt32, 0, 0, 0)
+}
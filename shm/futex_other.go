@@ -0,0 +1,25 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !freebsd
+// +build !linux,!freebsd
+
+package shm
+
+import "sync/atomic"
+
+// wait waits for addr to stop holding val, polling it with b.
+// It may return spuriously.
+//
+// There is no system call to wait on addresses shared between processes
+// here: WaitOnAddress only wakes up waiters of the same process on
+// Windows, and __ulock_wait is private on macOS.
+func wait(addr *atomic.Uint32, val uint32, b *backoff) {
+	if addr.Load() == val {
+		b.wait()
+	}
+}
+
+// wake is a no-op: waiters poll the address.
+func wake(addr *atomic.Uint32) {}
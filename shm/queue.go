@@ -0,0 +1,309 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package shm provides data structures laid out in shared memory-mapped
+// files, to exchange data between processes without sockets or pipes.
+//
+// The structures rely on atomic operations on the shared mapping. Queues,
+// seqlocks and counters never make system calls once opened, and their
+// waiting sides poll the mapping; RWLock waiters block in the kernel where
+// it can wake up waiters of other processes, and poll elsewhere.
+package shm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/go-mmap/mmap"
+)
+
+// cacheLine is the size of the cache lines the fields written by different
+// processes are kept apart by, to avoid false sharing.
+const cacheLine = 64
+
+// mapping returns the mapped bytes of f, the file of a structure of the
+// given kind, which must hold at least n bytes.
+// Files mapped through a sliding window, as the ones larger than the bound
+// set by mmap.SetMaxMappedBytes, have no mapping to share.
+func mapping(f *mmap.File, n int, kind string) ([]byte, error) {
+	b := f.Bytes()
+	switch {
+	case b == nil && f.Len() > 0:
+		return nil, fmt.Errorf("shm: %s %q is mapped through a window, and can not be shared", kind, f.Name())
+	case len(b) < n:
+		return nil, fmt.Errorf("shm: %q is not a %s", f.Name(), kind)
+	}
+	return b, nil
+}
+
+var queueMagic = [8]byte{'m', 'm', 'a', 'p', 's', 'p', 's', 'c'}
+
+// ErrClosed is returned when writing to a queue closed for writing.
+var ErrClosed = errors.New("shm: queue closed")
+
+// queueHeader is the header of a queue file, followed by the data area.
+// The head is only written by the consumer and the tail by the producer,
+// each on its own cache line.
+type queueHeader struct {
+	magic    [8]byte
+	capacity uint64
+	closed   atomic.Uint32 // set once the producer closed the queue.
+	_        [cacheLine - 20]byte
+
+	head atomic.Uint64 // number of bytes read so far.
+	_    [cacheLine - 8]byte
+
+	tail atomic.Uint64 // number of bytes written so far.
+	_    [cacheLine - 8]byte
+}
+
+// queueHeaderSize is the size of the header of a queue file.
+const queueHeaderSize = int(unsafe.Sizeof(queueHeader{}))
+
+// Queue is a single-producer, single-consumer byte queue laid out in a
+// shared memory-mapped file: one process writes to the queue while another
+// one reads from it, as with a pipe.
+//
+// Only one goroutine of one process may write to the queue, and only one
+// goroutine of one process may read from it.
+type Queue struct {
+	f    *mmap.File
+	hdr  *queueHeader
+	data []byte
+	mask uint64
+}
+
+// CreateQueue creates the named file holding an empty queue of capacity
+// bytes, and opens it.
+// capacity must be a power of two.
+// An existing file is truncated.
+func CreateQueue(filename string, capacity int) (*Queue, error) {
+	if capacity <= 0 || capacity&(capacity-1) != 0 {
+		return nil, fmt.Errorf("shm: queue capacity %d is not a power of two", capacity)
+	}
+
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not create queue: %w", err)
+	}
+	err = fd.Truncate(int64(queueHeaderSize + capacity))
+	if err == nil {
+		err = fd.Close()
+	} else {
+		_ = fd.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not size queue %q: %w", filename, err)
+	}
+
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	q, err := newQueue(f)
+	if err != nil {
+		return nil, err
+	}
+	q.hdr.capacity = uint64(capacity)
+	copy(q.hdr.magic[:], queueMagic[:])
+	q.mask = uint64(capacity) - 1
+	return q, nil
+}
+
+// OpenQueue opens the queue held by the named file, created with
+// CreateQueue.
+func OpenQueue(filename string) (*Queue, error) {
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	q, err := newQueue(f)
+	if err != nil {
+		return nil, err
+	}
+	capacity := q.hdr.capacity
+	if q.hdr.magic != queueMagic || capacity != uint64(len(q.data)) || capacity&(capacity-1) != 0 {
+		_ = f.Close()
+		return nil, fmt.Errorf("shm: %q is not a queue", filename)
+	}
+	q.mask = capacity - 1
+	return q, nil
+}
+
+// newQueue returns the queue held by f, closing f if it can not.
+func newQueue(f *mmap.File) (*Queue, error) {
+	b, err := mapping(f, queueHeaderSize, "queue")
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Queue{
+		f:    f,
+		hdr:  (*queueHeader)(unsafe.Pointer(&b[0])),
+		data: b[queueHeaderSize:],
+	}, nil
+}
+
+// Cap returns the capacity of the queue, in bytes.
+func (q *Queue) Cap() int {
+	return len(q.data)
+}
+
+// Len returns the number of bytes written to the queue and not read yet.
+func (q *Queue) Len() int {
+	if q.hdr == nil {
+		return 0
+	}
+	return int(q.hdr.tail.Load() - q.hdr.head.Load())
+}
+
+// TryWrite writes as many bytes of p as fit in the queue, without waiting.
+// It returns the number of bytes written, none once the queue is unmapped.
+func (q *Queue) TryWrite(p []byte) int {
+	if q.hdr == nil {
+		return 0
+	}
+	tail := q.hdr.tail.Load()
+	free := uint64(len(q.data)) - (tail - q.hdr.head.Load())
+	if uint64(len(p)) > free {
+		p = p[:free]
+	}
+	if len(p) == 0 {
+		return 0
+	}
+	off := tail & q.mask
+	n := copy(q.data[off:], p)
+	copy(q.data, p[n:])
+	q.hdr.tail.Store(tail + uint64(len(p)))
+	return len(p)
+}
+
+// Write writes p to the queue, waiting for the consumer to make room as
+// needed.
+// Write returns ErrClosed once the queue was closed with CloseWrite, and
+// os.ErrClosed once it was unmapped with Close.
+func (q *Queue) Write(p []byte) (int, error) {
+	if q.hdr == nil {
+		return 0, os.ErrClosed
+	}
+	var (
+		n int
+		b backoff
+	)
+	for {
+		if q.hdr.closed.Load() != 0 {
+			return n, ErrClosed
+		}
+		c := q.TryWrite(p[n:])
+		n += c
+		if n == len(p) {
+			return n, nil
+		}
+		if c > 0 {
+			b.reset()
+		}
+		b.wait()
+	}
+}
+
+// TryRead reads up to len(p) bytes from the queue, without waiting.
+// It returns the number of bytes read, none once the queue is unmapped.
+func (q *Queue) TryRead(p []byte) int {
+	if q.hdr == nil {
+		return 0
+	}
+	head := q.hdr.head.Load()
+	used := q.hdr.tail.Load() - head
+	if uint64(len(p)) > used {
+		p = p[:used]
+	}
+	if len(p) == 0 {
+		return 0
+	}
+	off := head & q.mask
+	n := copy(p, q.data[off:])
+	copy(p[n:], q.data)
+	q.hdr.head.Store(head + uint64(len(p)))
+	return len(p)
+}
+
+// Read reads up to len(p) bytes from the queue, waiting for the producer
+// to write some if the queue is empty.
+// Read returns io.EOF once the queue was closed with CloseWrite and all the
+// bytes written before were read, and os.ErrClosed once it was unmapped
+// with Close.
+func (q *Queue) Read(p []byte) (int, error) {
+	if q.hdr == nil {
+		return 0, os.ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	var b backoff
+	for {
+		// Check for closing first, so that bytes written before closing
+		// are all read.
+		closed := q.hdr.closed.Load() != 0
+		if n := q.TryRead(p); n > 0 {
+			return n, nil
+		}
+		if closed {
+			return 0, io.EOF
+		}
+		b.wait()
+	}
+}
+
+// CloseWrite closes the queue for writing: the consumer reads io.EOF once
+// it read all the bytes written so far.
+// It must be called by the producer.
+func (q *Queue) CloseWrite() error {
+	if q.hdr == nil {
+		return os.ErrClosed
+	}
+	q.hdr.closed.Store(1)
+	return nil
+}
+
+// Close unmaps the queue: its other methods then fail with os.ErrClosed.
+// It does not close the queue for writing.
+func (q *Queue) Close() error {
+	q.hdr = nil
+	q.data = nil
+	return q.f.Close()
+}
+
+// backoff waits for the other side of a shared structure, spinning first,
+// then sleeping for increasing durations.
+type backoff struct {
+	n int
+}
+
+const (
+	backoffSpins = 64
+	backoffMax   = time.Millisecond
+)
+
+func (b *backoff) wait() {
+	b.n++
+	if b.n <= backoffSpins {
+		runtime.Gosched()
+		return
+	}
+	d := time.Microsecond << uint(b.n-backoffSpins)
+	if d > backoffMax || d <= 0 {
+		d = backoffMax
+	}
+	time.Sleep(d)
+}
+
+func (b *backoff) reset() {
+	b.n = 0
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestQueue(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "queue")
+	w, err := CreateQueue(fname, 4096)
+	if err != nil {
+		t.Fatalf("could not create queue: %+v", err)
+	}
+	defer w.Close()
+
+	r, err := OpenQueue(fname)
+	if err != nil {
+		t.Fatalf("could not open queue: %+v", err)
+	}
+	defer r.Close()
+
+	want := make([]byte, 1<<20)
+	for i := range want {
+		want[i] = byte(i * 7)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		for i := 0; i < len(want); i += 1000 {
+			j := i + 1000
+			if j > len(want) {
+				j = len(want)
+			}
+			if _, err := w.Write(want[i:j]); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- w.CloseWrite()
+	}()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not read queue: %+v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("could not write queue: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("invalid contents read from queue")
+	}
+
+	_, err = w.Write([]byte("x"))
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("invalid error writing to closed queue: %+v", err)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q, err := CreateQueue(filepath.Join(t.TempDir(), "queue"), 8)
+	if err != nil {
+		t.Fatalf("could not create queue: %+v", err)
+	}
+	defer q.Close()
+
+	if got, want := q.TryWrite([]byte("hello world")), 8; got != want {
+		t.Fatalf("invalid written bytes: got=%d, want=%d", got, want)
+	}
+	if got, want := q.TryWrite([]byte("x")), 0; got != want {
+		t.Fatalf("invalid written bytes to full queue: got=%d, want=%d", got, want)
+	}
+
+	p := make([]byte, 5)
+	if n := q.TryRead(p); string(p[:n]) != "hello" {
+		t.Fatalf("invalid read bytes: %q", p[:n])
+	}
+	// Wrap around the end of the data area.
+	if got, want := q.TryWrite([]byte("12345")), 5; got != want {
+		t.Fatalf("invalid written bytes: got=%d, want=%d", got, want)
+	}
+	p = make([]byte, 16)
+	if n := q.TryRead(p); string(p[:n]) != " wo12345" {
+		t.Fatalf("invalid read bytes: %q", p[:n])
+	}
+	if got := q.Len(); got != 0 {
+		t.Fatalf("invalid length of drained queue: %d", got)
+	}
+
+	_, err = CreateQueue(filepath.Join(t.TempDir(), "queue"), 12)
+	if err == nil {
+		t.Fatalf("expected an error creating a queue of 12 bytes")
+	}
+	_, err = OpenQueue(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatalf("expected an error opening a missing queue")
+	}
+}
+
+func TestQueueClosed(t *testing.T) {
+	q, err := CreateQueue(filepath.Join(t.TempDir(), "queue"), 8)
+	if err != nil {
+		t.Fatalf("could not create queue: %+v", err)
+	}
+	err = q.Close()
+	if err != nil {
+		t.Fatalf("could not close queue: %+v", err)
+	}
+
+	p := make([]byte, 1)
+	if _, err := q.Write(p); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("invalid error writing to unmapped queue: %+v", err)
+	}
+	if _, err := q.Read(p); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("invalid error reading from unmapped queue: %+v", err)
+	}
+	if err := q.CloseWrite(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("invalid error closing unmapped queue for writing: %+v", err)
+	}
+	if q.TryWrite(p) != 0 || q.TryRead(p) != 0 || q.Len() != 0 || q.Cap() != 0 {
+		t.Fatalf("unmapped queue is not empty")
+	}
+}
+
+func TestQueueWindowed(t *testing.T) {
+	defer mmap.SetMaxMappedBytes(mmap.SetMaxMappedBytes(4096))
+
+	fname := filepath.Join(t.TempDir(), "queue")
+	_, err := CreateQueue(fname, 1<<16)
+	if err == nil {
+		t.Fatalf("expected an error creating a queue mapped through a window")
+	}
+	_, err = OpenQueue(fname)
+	if err == nil {
+		t.Fatalf("expected an error opening a queue mapped through a window")
+	}
+}
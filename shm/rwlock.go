@@ -0,0 +1,220 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/go-mmap/mmap"
+)
+
+var rwLockMagic = [8]byte{'m', 'm', 'a', 'p', 'r', 'w', 'l', 'k'}
+
+// The state of a lock counts its readers in its low bits, and flags
+// whether a writer holds it and whether writers wait for it.
+const (
+	rwWriter  = 1 << 31
+	rwWaiting = 1 << 30
+	rwReaders = rwWaiting - 1
+)
+
+// rwLockHeader is the header of a lock file.
+type rwLockHeader struct {
+	magic [8]byte
+	_     [cacheLine - 8]byte
+	state atomic.Uint32
+	_     [cacheLine - 4]byte
+}
+
+// rwLockSize is the size of a lock file.
+const rwLockSize = int(unsafe.Sizeof(rwLockHeader{}))
+
+// RWLock is a reader-writer lock shared between processes through a
+// memory-mapped file: any number of readers or a single writer may hold
+// it, as with sync.RWMutex, so that many processes read a shared database
+// file while one process updates it.
+//
+// Writers have priority: once a writer waits for the lock, new readers
+// wait for it to be done.
+// Waiters block in the kernel on Linux (futex) and FreeBSD (_umtx_op).
+// Elsewhere, including Windows, they poll the lock: WaitOnAddress only
+// wakes up waiters of the same process, and can not wait on a lock shared
+// with other processes.
+// A process dying while holding the lock leaves it held.
+type RWLock struct {
+	f     *mmap.File
+	state *atomic.Uint32
+}
+
+// CreateRWLock creates the named file holding an unlocked lock, and opens
+// it. An existing file is truncated.
+func CreateRWLock(filename string) (*RWLock, error) {
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not create lock: %w", err)
+	}
+	err = fd.Truncate(int64(rwLockSize))
+	if err == nil {
+		err = fd.Close()
+	} else {
+		_ = fd.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not size lock %q: %w", filename, err)
+	}
+
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	b, err := mapping(f, rwLockSize, "lock")
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	hdr := (*rwLockHeader)(unsafe.Pointer(&b[0]))
+	copy(hdr.magic[:], rwLockMagic[:])
+	return &RWLock{f: f, state: &hdr.state}, nil
+}
+
+// OpenRWLock opens the lock held by the named file, created with
+// CreateRWLock.
+func OpenRWLock(filename string) (*RWLock, error) {
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	b, err := mapping(f, rwLockSize, "lock")
+	if err == nil && len(b) != rwLockSize {
+		err = fmt.Errorf("shm: %q is not a lock", filename)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	hdr := (*rwLockHeader)(unsafe.Pointer(&b[0]))
+	if hdr.magic != rwLockMagic {
+		_ = f.Close()
+		return nil, fmt.Errorf("shm: %q is not a lock", filename)
+	}
+	return &RWLock{f: f, state: &hdr.state}, nil
+}
+
+// RWLockAt returns the lock held by the 4 bytes at off of f, such as a
+// field of the header of a database file, zeroed when the file was
+// created. f must be opened for reading and writing, even by readers, and
+// off must be a multiple of 4.
+// Closing the lock does not close f.
+func RWLockAt(f *mmap.File, off int64) (*RWLock, error) {
+	b, err := mapping(f, 0, "file")
+	if err != nil {
+		return nil, err
+	}
+	if off < 0 || off%4 != 0 || int64(len(b)) < off+4 {
+		return nil, fmt.Errorf("shm: invalid lock offset %d", off)
+	}
+	return &RWLock{state: (*atomic.Uint32)(unsafe.Pointer(&b[off]))}, nil
+}
+
+// Lock locks l for writing, waiting for the readers and the writer holding
+// it to unlock it.
+func (l *RWLock) Lock() {
+	var b backoff
+	for {
+		s := l.state.Load()
+		if s&(rwWriter|rwReaders) == 0 {
+			// Other waiting writers flag themselves again when they
+			// wake up.
+			if l.state.CompareAndSwap(s, rwWriter) {
+				return
+			}
+			continue
+		}
+		if s&rwWaiting == 0 {
+			if !l.state.CompareAndSwap(s, s|rwWaiting) {
+				continue
+			}
+			s |= rwWaiting
+		}
+		wait(l.state, s, &b)
+	}
+}
+
+// TryLock tries to lock l for writing, and reports whether it succeeded.
+func (l *RWLock) TryLock() bool {
+	s := l.state.Load()
+	return s&(rwWriter|rwReaders) == 0 && l.state.CompareAndSwap(s, rwWriter)
+}
+
+// Unlock unlocks l for writing.
+// It is a run-time error if l is not locked for writing.
+func (l *RWLock) Unlock() {
+	for {
+		s := l.state.Load()
+		if s&rwWriter == 0 {
+			panic("shm: unlock of unlocked RWLock")
+		}
+		if l.state.CompareAndSwap(s, s&^rwWriter) {
+			break
+		}
+	}
+	wake(l.state)
+}
+
+// RLock locks l for reading, waiting for the writer holding it, or waiting
+// for it, to unlock it.
+func (l *RWLock) RLock() {
+	var b backoff
+	for {
+		s := l.state.Load()
+		if s&(rwWriter|rwWaiting) == 0 {
+			if s&rwReaders == rwReaders {
+				panic("shm: too many readers of RWLock")
+			}
+			if l.state.CompareAndSwap(s, s+1) {
+				return
+			}
+			continue
+		}
+		wait(l.state, s, &b)
+	}
+}
+
+// TryRLock tries to lock l for reading, and reports whether it succeeded.
+func (l *RWLock) TryRLock() bool {
+	s := l.state.Load()
+	return s&(rwWriter|rwWaiting) == 0 && s&rwReaders != rwReaders && l.state.CompareAndSwap(s, s+1)
+}
+
+// RUnlock undoes a single RLock call.
+// It is a run-time error if l is not locked for reading.
+func (l *RWLock) RUnlock() {
+	for {
+		s := l.state.Load()
+		if s&rwReaders == 0 {
+			panic("shm: runlock of unlocked RWLock")
+		}
+		if !l.state.CompareAndSwap(s, s-1) {
+			continue
+		}
+		if s&rwReaders == 1 && s&rwWaiting != 0 {
+			// Let the waiting writers in.
+			wake(l.state)
+		}
+		return
+	}
+}
+
+// Close unmaps the lock, unless it was returned by RWLockAt.
+func (l *RWLock) Close() error {
+	l.state = nil
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestRWLock(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "lock")
+	w, err := CreateRWLock(fname)
+	if err != nil {
+		t.Fatalf("could not create lock: %+v", err)
+	}
+	defer w.Close()
+	r, err := OpenRWLock(fname)
+	if err != nil {
+		t.Fatalf("could not open lock: %+v", err)
+	}
+	defer r.Close()
+
+	if !w.TryLock() {
+		t.Fatalf("could not lock an unlocked lock")
+	}
+	if r.TryRLock() || r.TryLock() {
+		t.Fatalf("locked a lock held for writing")
+	}
+	w.Unlock()
+	if !r.TryRLock() || !r.TryRLock() {
+		t.Fatalf("could not lock for reading")
+	}
+	if w.TryLock() {
+		t.Fatalf("locked for writing a lock held for reading")
+	}
+	r.RUnlock()
+	r.RUnlock()
+
+	// The protected data lives in its own mapping: readers check that the
+	// two halves written by the writer always match.
+	dname := filepath.Join(dir, "data")
+	err = os.WriteFile(dname, make([]byte, 16), 0600)
+	if err != nil {
+		t.Fatalf("could not create data: %+v", err)
+	}
+	data, err := mmap.OpenFile(dname, mmap.Read|mmap.Write)
+	if err != nil {
+		t.Fatalf("could not open data: %+v", err)
+	}
+	defer data.Close()
+	b := data.Bytes()
+	if _, err := RWLockAt(data, 2); err == nil {
+		t.Fatalf("expected an error with a misaligned lock")
+	}
+
+	const n = 2000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := uint64(1); i <= n; i++ {
+			w.Lock()
+			binary.LittleEndian.PutUint64(b[0:], i)
+			binary.LittleEndian.PutUint64(b[8:], i)
+			w.Unlock()
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				r.RLock()
+				lo := binary.LittleEndian.Uint64(b[0:])
+				hi := binary.LittleEndian.Uint64(b[8:])
+				r.RUnlock()
+				if lo != hi {
+					t.Errorf("torn read: %d != %d", lo, hi)
+					return
+				}
+				if lo == n {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRWLockWindowed(t *testing.T) {
+	defer mmap.SetMaxMappedBytes(mmap.SetMaxMappedBytes(4096))
+
+	dname := filepath.Join(t.TempDir(), "data")
+	err := os.WriteFile(dname, make([]byte, 1<<16), 0600)
+	if err != nil {
+		t.Fatalf("could not create data: %+v", err)
+	}
+	data, err := mmap.OpenFile(dname, mmap.Read|mmap.Write)
+	if err != nil {
+		t.Fatalf("could not open data: %+v", err)
+	}
+	defer data.Close()
+	if _, err := RWLockAt(data, 0); err == nil {
+		t.Fatalf("expected an error with a file mapped through a window")
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/go-mmap/mmap"
+)
+
+var seqLockMagic = [8]byte{'m', 'm', 'a', 'p', 's', 'e', 'q', 'l'}
+
+var errReadOnly = errors.New("shm: seqlock opened for reading")
+
+// seqLockHeader is the header of a seqlock file, followed by the snapshot.
+type seqLockHeader struct {
+	magic [8]byte
+	size  uint64
+	_     [cacheLine - 16]byte
+
+	// seq is odd while a snapshot is being published, and counts twice
+	// the published snapshots otherwise.
+	seq atomic.Uint64
+	_   [cacheLine - 8]byte
+}
+
+// seqLockHeaderSize is the size of the header of a seqlock file.
+const seqLockHeaderSize = int(unsafe.Sizeof(seqLockHeader{}))
+
+// SeqLock publishes fixed-size snapshots through a shared memory-mapped
+// file: one writer process publishes snapshots that any number of reader
+// processes load without locking, retrying the loads that overlap with a
+// publication, so that they never observe a torn snapshot.
+//
+// Readers never slow the writer down; they only see the latest snapshot,
+// and miss the ones published while they were not loading.
+// A writer dying while publishing leaves readers waiting until a new writer
+// publishes a snapshot.
+//
+// Only one goroutine of one process may publish snapshots.
+//
+// Snapshots are stored and loaded with atomic operations on 8-byte words,
+// so that loads are ordered with the sequence number around them on every
+// architecture, including the ones with weaker memory models than amd64.
+// The race detector does not see the accesses of other processes to the
+// mapping: it can not check their ordering.
+type SeqLock struct {
+	f      *mmap.File
+	hdr    *seqLockHeader
+	words  []atomic.Uint64 // snapshot, padded to a whole number of words.
+	size   int
+	writer bool // set for seqlocks opened for publishing.
+}
+
+// CreateSeqLock creates the named file holding a seqlock for snapshots of
+// size bytes, opens it for publishing, and publishes a zeroed snapshot.
+// An existing file is truncated.
+func CreateSeqLock(filename string, size int) (*SeqLock, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("shm: invalid snapshot size %d", size)
+	}
+
+	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not create seqlock: %w", err)
+	}
+	err = fd.Truncate(int64(seqLockHeaderSize + words(size)*8))
+	if err == nil {
+		err = fd.Close()
+	} else {
+		_ = fd.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shm: could not size seqlock %q: %w", filename, err)
+	}
+
+	f, err := mmap.OpenFile(filename, mmap.Read|mmap.Write)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSeqLock(f)
+	if err != nil {
+		return nil, err
+	}
+	s.writer = true
+	s.hdr.size = uint64(size)
+	s.size = size
+	copy(s.hdr.magic[:], seqLockMagic[:])
+	return s, nil
+}
+
+// OpenSeqLock opens the seqlock held by the named file, created with
+// CreateSeqLock, for loading snapshots.
+func OpenSeqLock(filename string) (*SeqLock, error) {
+	f, err := mmap.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSeqLock(f)
+	if err != nil {
+		return nil, err
+	}
+	n := f.Len() - seqLockHeaderSize
+	if s.hdr.magic != seqLockMagic || s.hdr.size > uint64(n) || words(int(s.hdr.size))*8 != n {
+		_ = f.Close()
+		return nil, fmt.Errorf("shm: %q is not a seqlock", filename)
+	}
+	s.size = int(s.hdr.size)
+	return s, nil
+}
+
+// newSeqLock returns the seqlock held by f, closing f if it can not.
+func newSeqLock(f *mmap.File) (*SeqLock, error) {
+	b, err := mapping(f, seqLockHeaderSize, "seqlock")
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s := &SeqLock{
+		f:   f,
+		hdr: (*seqLockHeader)(unsafe.Pointer(&b[0])),
+	}
+	if n := (len(b) - seqLockHeaderSize) / 8; n > 0 {
+		s.words = unsafe.Slice((*atomic.Uint64)(unsafe.Pointer(&b[seqLockHeaderSize])), n)
+	}
+	return s, nil
+}
+
+// words returns the number of 8-byte words holding snapshots of size bytes.
+func words(size int) int {
+	return (size + 7) / 8
+}
+
+// Size returns the size of the snapshots, in bytes.
+func (s *SeqLock) Size() int {
+	return s.size
+}
+
+// Seq returns the number of snapshots published so far, counting the one
+// being published.
+func (s *SeqLock) Seq() uint64 {
+	if s.hdr == nil {
+		return 0
+	}
+	return (s.hdr.seq.Load() + 1) / 2
+}
+
+// Publish publishes p as the new snapshot.
+// p must be Size bytes long.
+func (s *SeqLock) Publish(p []byte) error {
+	if s.hdr == nil {
+		return os.ErrClosed
+	}
+	if !s.writer {
+		return errReadOnly
+	}
+	if len(p) != s.size {
+		return fmt.Errorf("shm: invalid snapshot size %d, want %d", len(p), s.size)
+	}
+	s.hdr.seq.Add(1)
+	for i := range s.words {
+		var w [8]byte
+		copy(w[:], p[i*8:])
+		s.words[i].Store(*(*uint64)(unsafe.Pointer(&w)))
+	}
+	s.hdr.seq.Add(1)
+	return nil
+}
+
+// TryLoad copies the latest snapshot into p, which must be Size bytes
+// long, without waiting.
+// It returns the sequence number of the snapshot, as returned by Seq, and
+// reports whether the snapshot was loaded: it was not when a publication
+// was in progress, or once the seqlock is unmapped.
+func (s *SeqLock) TryLoad(p []byte) (uint64, bool) {
+	if s.hdr == nil || len(p) != s.size {
+		return 0, false
+	}
+	beg := s.hdr.seq.Load()
+	if beg&1 != 0 {
+		return 0, false
+	}
+	for i := range s.words {
+		w := s.words[i].Load()
+		copy(p[i*8:], (*[8]byte)(unsafe.Pointer(&w))[:])
+	}
+	if s.hdr.seq.Load() != beg {
+		return 0, false
+	}
+	return beg / 2, true
+}
+
+// Load copies the latest snapshot into p, which must be Size bytes long,
+// waiting for publications in progress to complete.
+// It returns the sequence number of the snapshot, as returned by Seq.
+func (s *SeqLock) Load(p []byte) (uint64, error) {
+	if s.hdr == nil {
+		return 0, os.ErrClosed
+	}
+	if len(p) != s.size {
+		return 0, fmt.Errorf("shm: invalid snapshot size %d, want %d", len(p), s.size)
+	}
+	var b backoff
+	for {
+		seq, ok := s.TryLoad(p)
+		if ok {
+			return seq, nil
+		}
+		b.wait()
+	}
+}
+
+// Close unmaps the seqlock: its other methods then fail with os.ErrClosed.
+func (s *SeqLock) Close() error {
+	s.hdr = nil
+	s.words = nil
+	s.size = 0
+	return s.f.Close()
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mmap/mmap"
+)
+
+func TestSeqLock(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "seqlock")
+	w, err := CreateSeqLock(fname, 256)
+	if err != nil {
+		t.Fatalf("could not create seqlock: %+v", err)
+	}
+	defer w.Close()
+
+	r, err := OpenSeqLock(fname)
+	if err != nil {
+		t.Fatalf("could not open seqlock: %+v", err)
+	}
+	defer r.Close()
+
+	p := make([]byte, r.Size())
+	seq, err := r.Load(p)
+	if err != nil {
+		t.Fatalf("could not load snapshot: %+v", err)
+	}
+	if seq != 0 || !bytes.Equal(p, make([]byte, len(p))) {
+		t.Fatalf("invalid initial snapshot %d: %x", seq, p)
+	}
+
+	const n = 10000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		snap := make([]byte, w.Size())
+		for i := uint64(1); i <= n; i++ {
+			// Fill the whole snapshot with i, so that torn loads are
+			// made of different values.
+			for j := 0; j < len(snap); j += 8 {
+				binary.LittleEndian.PutUint64(snap[j:], i)
+			}
+			_ = w.Publish(snap)
+		}
+	}()
+
+	var last uint64
+	for last < n {
+		seq, err := r.Load(p)
+		if err != nil {
+			t.Fatalf("could not load snapshot: %+v", err)
+		}
+		v := binary.LittleEndian.Uint64(p)
+		for j := 8; j < len(p); j += 8 {
+			if got := binary.LittleEndian.Uint64(p[j:]); got != v {
+				t.Fatalf("torn snapshot %d: %d != %d", seq, got, v)
+			}
+		}
+		if v != seq || seq < last {
+			t.Fatalf("invalid snapshot %d after %d: %d", seq, last, v)
+		}
+		last = seq
+	}
+	<-done
+
+	if got, want := r.Seq(), uint64(n); got != want {
+		t.Fatalf("invalid sequence: got=%d, want=%d", got, want)
+	}
+	err = r.Publish(p)
+	if !errors.Is(err, errReadOnly) {
+		t.Fatalf("invalid error publishing from a reader: %+v", err)
+	}
+	err = w.Publish(p[:1])
+	if err == nil {
+		t.Fatalf("expected an error publishing a short snapshot")
+	}
+}
+
+func TestSeqLockUnaligned(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "seqlock")
+	w, err := CreateSeqLock(fname, 13)
+	if err != nil {
+		t.Fatalf("could not create seqlock: %+v", err)
+	}
+	defer w.Close()
+
+	want := []byte("hello, world!")
+	err = w.Publish(want)
+	if err != nil {
+		t.Fatalf("could not publish snapshot: %+v", err)
+	}
+
+	r, err := OpenSeqLock(fname)
+	if err != nil {
+		t.Fatalf("could not open seqlock: %+v", err)
+	}
+	defer r.Close()
+
+	if got, want := r.Size(), 13; got != want {
+		t.Fatalf("invalid size: got=%d, want=%d", got, want)
+	}
+	got := make([]byte, r.Size())
+	_, err = r.Load(got)
+	if err != nil {
+		t.Fatalf("could not load snapshot: %+v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("invalid snapshot: got=%q, want=%q", got, want)
+	}
+}
+
+func TestSeqLockClosed(t *testing.T) {
+	s, err := CreateSeqLock(filepath.Join(t.TempDir(), "seqlock"), 8)
+	if err != nil {
+		t.Fatalf("could not create seqlock: %+v", err)
+	}
+	err = s.Close()
+	if err != nil {
+		t.Fatalf("could not close seqlock: %+v", err)
+	}
+
+	p := make([]byte, 8)
+	if err := s.Publish(p); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("invalid error publishing to unmapped seqlock: %+v", err)
+	}
+	if _, err := s.Load(p); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("invalid error loading from unmapped seqlock: %+v", err)
+	}
+	if _, ok := s.TryLoad(p); ok {
+		t.Fatalf("loaded a snapshot from unmapped seqlock")
+	}
+	if s.Seq() != 0 || s.Size() != 0 {
+		t.Fatalf("unmapped seqlock is not empty")
+	}
+}
+
+func TestSeqLockWindowed(t *testing.T) {
+	defer mmap.SetMaxMappedBytes(mmap.SetMaxMappedBytes(4096))
+
+	fname := filepath.Join(t.TempDir(), "seqlock")
+	_, err := CreateSeqLock(fname, 1<<16)
+	if err == nil {
+		t.Fatalf("expected an error creating a seqlock mapped through a window")
+	}
+	_, err = OpenSeqLock(fname)
+	if err == nil {
+		t.Fatalf("expected an error opening a seqlock mapped through a window")
+	}
+}
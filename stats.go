@@ -0,0 +1,74 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds statistics about the memory mappings made by the package,
+// across all files.
+type Stats struct {
+	Mappings    int64 // Number of live mappings, counting the views of windowed files.
+	MappedBytes int64 // Total size of the live mappings.
+	Syncs       int64 // Number of calls to Sync committing contents to stable storage.
+	Remaps      int64 // Number of calls to Remap.
+}
+
+var stats struct {
+	mappings atomic.Int64
+	bytes    atomic.Int64
+	syncs    atomic.Int64
+	remaps   atomic.Int64
+
+	syncHook atomic.Pointer[func(time.Duration)]
+}
+
+// ReadStats returns the current statistics of the package.
+func ReadStats() Stats {
+	return Stats{
+		Mappings:    stats.mappings.Load(),
+		MappedBytes: stats.bytes.Load(),
+		Syncs:       stats.syncs.Load(),
+		Remaps:      stats.remaps.Load(),
+	}
+}
+
+// SetSyncHook registers fn to be called with the duration of every call to
+// Sync committing contents to stable storage, on any file.
+// fn may be called from several goroutines at once.
+// SetSyncHook replaces any previously registered hook; a nil fn removes it.
+func SetSyncHook(fn func(d time.Duration)) {
+	if fn == nil {
+		stats.syncHook.Store(nil)
+		return
+	}
+	stats.syncHook.Store(&fn)
+}
+
+// statMap records the new mapping data.
+func statMap(data []byte) {
+	stats.mappings.Add(1)
+	stats.bytes.Add(int64(len(data)))
+	trackMapping(data)
+}
+
+// statUnmap records the release of the mapping data.
+func statUnmap(data []byte) {
+	stats.mappings.Add(-1)
+	stats.bytes.Add(-int64(len(data)))
+	untrackMapping(data)
+	released()
+}
+
+// statSync records a call to Sync started at beg.
+// It is meant to be deferred.
+func statSync(beg time.Time) {
+	stats.syncs.Add(1)
+	if fn := stats.syncHook.Load(); fn != nil {
+		(*fn)(time.Since(beg))
+	}
+}
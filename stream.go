@@ -0,0 +1,121 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// streamSpan is the size of the windows a StreamReader prefetches and
+// releases.
+const streamSpan = 4 << 20
+
+// StreamReader reads a memory-mapped file sequentially, managing the
+// residency of its pages: the window following the one being read is
+// prefetched in the background, without reads waiting for it, and windows
+// already read are released, unless the file is a snapshot.
+//
+// A StreamReader has its own offset, and does not move the cursor of the
+// file. It must be closed before the file.
+type StreamReader struct {
+	f   *File
+	off int64
+
+	fetched  int64       // end of the windows prefetched so far.
+	released int64       // end of the windows released so far.
+	busy     atomic.Bool // set while a prefetch is in flight.
+	wg       sync.WaitGroup
+	restored bool // set once the access pattern of the file was restored.
+}
+
+// StreamReader returns a reader of the file from its start, tuned for
+// streaming through the whole file once.
+// It advises the OS that the whole mapping is accessed sequentially, until
+// the end of the file is read or the reader is closed: the access pattern
+// the file was opened with is then restored.
+func (f *File) StreamReader() *StreamReader {
+	_ = f.advise(adviceSequential)
+	return &StreamReader{f: f}
+}
+
+// Read implements the io.Reader interface.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	if !r.f.rflag() {
+		return 0, errBadFD
+	}
+	size := r.f.size()
+	if r.off >= size {
+		r.restore()
+		return 0, io.EOF
+	}
+	r.prefetch(size)
+
+	n, err := r.f.readAt(p, r.off)
+	r.off += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	// Keep the window being read, release the ones before it.
+	if beg := r.off - r.off%streamSpan; beg > r.released && !r.f.cfg.private {
+		_ = r.f.Release(r.released, beg-r.released)
+		r.released = beg
+	}
+	return n, nil
+}
+
+// prefetch starts prefetching the window following the one holding the
+// offset of the reader, unless it was already.
+func (r *StreamReader) prefetch(size int64) {
+	end := r.off - r.off%streamSpan + 2*streamSpan
+	if end > size {
+		end = size
+	}
+	if end <= r.fetched {
+		return
+	}
+	if !r.busy.CompareAndSwap(false, true) {
+		// At most one prefetch is in flight: the next read starts this
+		// one, rather than waiting for the previous one.
+		return
+	}
+	beg := r.fetched
+	if beg < r.off {
+		beg = r.off
+	}
+	r.fetched = end
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.busy.Store(false)
+		_ = r.f.Prefetch(beg, end-beg)
+	}()
+}
+
+// restore restores the access pattern the file was opened with, once.
+func (r *StreamReader) restore() {
+	if r.restored {
+		return
+	}
+	r.restored = true
+	_ = r.f.advise(r.f.cfg.advice)
+}
+
+// Close waits for the background prefetching to complete, and restores
+// the access pattern the file was opened with.
+// The file itself is left open.
+func (r *StreamReader) Close() error {
+	r.wg.Wait()
+	r.restore()
+	return nil
+}
+
+var (
+	_ io.Reader = (*StreamReader)(nil)
+	_ io.Closer = (*StreamReader)(nil)
+)
@@ -0,0 +1,236 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncOnClose makes Close commit the contents of the file to stable storage
+// before unmapping it.
+func SyncOnClose() Option {
+	return func(o *options) {
+		o.syncOnClose = true
+	}
+}
+
+// CloseWithSync commits the contents of the file to stable storage, and
+// closes it, as if it was opened with SyncOnClose.
+// The errors of syncing, unmapping and closing the file are all reported.
+func (f *File) CloseWithSync() error {
+	if f == nil {
+		return os.ErrInvalid
+	}
+	f.cfg.syncOnClose = true
+	return f.Close()
+}
+
+// SyncEveryNBytes makes the file commit its contents to stable storage
+// every time n bytes have been written to it with Write, WriteByte,
+// WriteAt, Zero or Fill since the last sync.
+// The write completing the n bytes returns the error of the sync, if any.
+func SyncEveryNBytes(n int64) Option {
+	return func(o *options) {
+		o.syncEvery = n
+	}
+}
+
+// NoSync turns Sync, SyncRange and SyncContext into no-ops, for files whose
+// contents need not be durable, such as scratch space.
+// The OS still writes the contents of the mapping back to the file
+// eventually.
+func NoSync() Option {
+	return func(o *options) {
+		o.noSync = true
+	}
+}
+
+// WithFullSync makes Sync and SyncContext behave as SyncFull, also
+// committing the buffers of the descriptor to stable storage, for files
+// on filesystems where flushing the mapping alone does not make its
+// contents durable.
+func WithFullSync() Option {
+	return func(o *options) {
+		o.fullSync = true
+	}
+}
+
+// Sync commits the current contents of the file to stable storage.
+//
+// Files opened for reading only have nothing to commit: Sync, SyncFull,
+// SyncRange and SyncContext return nil for them, so that code handling
+// any file, such as a helper closing it, may sync it regardless of its
+// access.
+func (f *File) Sync() error {
+	return f.syncAll(f.cfg.fullSync)
+}
+
+// SyncFull commits the current contents of the file to stable storage,
+// like Sync, and then the buffers of its descriptor, as fsync does.
+//
+// On overlayfs, as used by containers and WSL, and on some other
+// filesystems, flushing the mapping does not guarantee that its contents
+// reached the underlying storage; SyncFull does.
+// On macOS, fsync does not either: the drive may keep the data in its
+// cache. SyncFull issues F_FULLFSYNC instead, which flushes that cache, as
+// databases need for their commits to survive a power loss.
+// On Windows, Sync already flushes the buffers of the file, and SyncFull
+// is the same as Sync.
+func (f *File) SyncFull() error {
+	return f.syncAll(true)
+}
+
+// syncAll implements Sync, and SyncFull if full is set.
+func (f *File) syncAll(full bool) error {
+	if !f.wflag() || f.cfg.private || f.cfg.noSync {
+		return nil
+	}
+	beg := time.Now()
+	err := f.progress(0, f.size())
+	switch {
+	case err != nil:
+		// The progress callback stopped the sync.
+	case f.reports() && f.w == nil && !f.cfg.dirty && !f.parallelSync():
+		err = f.syncChunked(nil)
+	default:
+		err = f.sync()
+		if err == nil {
+			err = f.progress(f.size(), f.size())
+		}
+	}
+	if err == nil && full {
+		err = f.syncFD()
+	}
+	statSync(beg)
+	f.logEvent("sync", f.size(), beg, err)
+	return err
+}
+
+// parallelSyncMin is the size from which mappings are synced in parallel,
+// when requested with WithParallelSync.
+const parallelSyncMin = 256 << 20
+
+// WithParallelSync makes Sync flush mappings of 256 MiB or more from up to
+// workers goroutines at once, each flushing its own part of the mapping.
+// This speeds up syncing huge mappings to storage devices serving several
+// requests concurrently, such as NVMe drives.
+func WithParallelSync(workers int) Option {
+	return func(o *options) {
+		o.syncWorkers = workers
+	}
+}
+
+// parallelSync reports whether the mapping should be synced in parallel.
+func (f *File) parallelSync() bool {
+	return f.cfg.syncWorkers > 1 && len(f.data) >= parallelSyncMin
+}
+
+// syncParallel splits the mapping in page-aligned parts, and flushes them
+// with flush from f.cfg.syncWorkers goroutines.
+func (f *File) syncParallel(flush func(b []byte) error) error {
+	workers := f.cfg.syncWorkers
+	page := os.Getpagesize()
+	part := (len(f.data) + workers - 1) / workers
+	part = (part + page - 1) &^ (page - 1)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	for beg := 0; beg < len(f.data); beg += part {
+		end := beg + part
+		if end > len(f.data) {
+			end = len(f.data)
+		}
+		wg.Add(1)
+		go func(b []byte) {
+			defer wg.Done()
+			err := flush(b)
+			if err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(f.data[beg:end])
+	}
+	wg.Wait()
+	return first
+}
+
+// wrote records that n bytes were written to the file, and commits it to
+// stable storage if its sync policy asks for it.
+func (f *File) wrote(n int64) error {
+	every := f.cfg.syncEvery
+	if every <= 0 || f.dirty.Add(n) < every {
+		return nil
+	}
+	f.dirty.Store(0)
+	return f.Sync()
+}
+
+// syncOnClose commits the file to stable storage before it is closed,
+// if its sync policy asks for it.
+func (f *File) syncOnClose() error {
+	if !f.cfg.syncOnClose || !f.wflag() {
+		return nil
+	}
+	return f.Sync()
+}
+
+// syncChunk is the size of the ranges flushed at once by SyncContext.
+const syncChunk = 16 << 20
+
+// SyncContext commits the current contents of the file to stable storage,
+// like Sync, but flushes the mapping in bounded chunks.
+// SyncContext checks ctx between chunks and returns its error as soon as
+// it is done, leaving the rest of the mapping unflushed.
+func (f *File) SyncContext(ctx context.Context) error {
+	if !f.wflag() {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.w != nil || f.cfg.dirty || f.cfg.private || f.cfg.noSync {
+		return f.Sync()
+	}
+
+	beg := time.Now()
+	err := f.progress(0, f.size())
+	if err == nil {
+		err = f.syncChunked(ctx.Err)
+	}
+	if err == nil && f.cfg.fullSync {
+		err = f.syncFD()
+	}
+	statSync(beg)
+	f.logEvent("sync", f.size(), beg, err)
+	return err
+}
+
+// syncChunked flushes the mapping in chunks of syncChunk bytes, reporting
+// the progress after each one, and then the file buffers, once.
+// check, if not nil, is called before each chunk, and stops the sync when
+// it fails.
+func (f *File) syncChunked(check func() error) error {
+	err := f.chunked(0, f.size(), func(off, n int64) error {
+		if check != nil {
+			if err := check(); err != nil {
+				return err
+			}
+		}
+		return f.flushRange(off, n)
+	})
+	if err != nil {
+		return err
+	}
+	return f.flushFile()
+}
@@ -0,0 +1,330 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Txn is a transaction over a memory-mapped file.
+//
+// Writes made through a transaction go to private shadow copies of the
+// pages they touch, and are only carried to the file by Commit.
+// Reads made through a transaction observe its own writes.
+//
+// Commit is atomic, even across crashes: the written pages are first logged
+// to a redo log next to the file, named after it with a "-journal" suffix,
+// and only then carried to the file. Begin replays the log of a commit
+// interrupted by a crash, before starting the transaction, so that the
+// file holds either all the pages of a transaction, or none of them.
+//
+// A Txn must not be used from several goroutines at once.
+type Txn struct {
+	f     *File
+	page  int64
+	pages map[int64][]byte // shadow copies of the written pages, by index.
+	done  bool
+}
+
+// Begin starts a transaction over the file.
+func (f *File) Begin() (*Txn, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	if !f.wflag() {
+		return nil, errBadFD
+	}
+	if f.closed() {
+		return nil, errClosed
+	}
+	if err := f.replayJournal(); err != nil {
+		return nil, err
+	}
+	return &Txn{
+		f:     f,
+		page:  int64(os.Getpagesize()),
+		pages: make(map[int64][]byte),
+	}, nil
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (t *Txn) ReadAt(p []byte, off int64) (int, error) {
+	if t.done {
+		return 0, errClosed
+	}
+	size := t.f.size()
+	if off < 0 {
+		return 0, fmt.Errorf("mmap: invalid ReadAt offset %d", off)
+	}
+	if size <= off {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < size {
+		i, beg := off/t.page, off%t.page
+		var m int
+		if shadow, ok := t.pages[i]; ok {
+			m = copy(p[n:], shadow[beg:])
+		} else {
+			end := (i + 1) * t.page
+			if end > size {
+				end = size
+			}
+			q := p[n:]
+			if int64(len(q)) > end-off {
+				q = q[:end-off]
+			}
+			var err error
+			m, err = t.f.readAt(q, off)
+			if err != nil {
+				return n + m, err
+			}
+		}
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements the io.WriterAt interface.
+func (t *Txn) WriteAt(p []byte, off int64) (int, error) {
+	if t.done {
+		return 0, errClosed
+	}
+	size := t.f.size()
+	if off < 0 || size < off {
+		return 0, fmt.Errorf("mmap: invalid WriteAt offset %d", off)
+	}
+
+	n := 0
+	for n < len(p) && off < size {
+		i, beg := off/t.page, off%t.page
+		shadow, err := t.shadow(i)
+		if err != nil {
+			return n, err
+		}
+		m := copy(shadow[beg:], p[n:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// shadow returns the shadow copy of the i-th page of the file, making it
+// on first use.
+func (t *Txn) shadow(i int64) ([]byte, error) {
+	if shadow, ok := t.pages[i]; ok {
+		return shadow, nil
+	}
+	off := i * t.page
+	n := t.f.size() - off
+	if n > t.page {
+		n = t.page
+	}
+	shadow := make([]byte, n)
+	_, err := t.f.readAt(shadow, off)
+	if err != nil {
+		return nil, err
+	}
+	t.pages[i] = shadow
+	return shadow, nil
+}
+
+// Commit publishes the pages written by the transaction to the file, and
+// commits them to stable storage.
+// Commit fails without writing anything if the file was truncated below the
+// written pages, or if the redo log could not be written: the transaction
+// is then still open, and may be committed again or rolled back.
+func (t *Txn) Commit() error {
+	if t.done {
+		return errClosed
+	}
+	size := t.f.size()
+	for i, shadow := range t.pages {
+		if i*t.page+int64(len(shadow)) > size {
+			return fmt.Errorf("mmap: could not commit transaction: file was truncated")
+		}
+	}
+	if len(t.pages) == 0 {
+		t.done = true
+		return t.f.Sync()
+	}
+
+	idx := make([]int64, 0, len(t.pages))
+	for i := range t.pages {
+		idx = append(idx, i)
+	}
+	sort.Slice(idx, func(i, j int) bool { return idx[i] < idx[j] })
+	err := t.f.writeJournal(t.journal(idx))
+	if err != nil {
+		return err
+	}
+	t.done = true
+
+	for _, i := range idx {
+		_, err := t.f.writeAt(t.pages[i], i*t.page)
+		if err != nil {
+			return fmt.Errorf("mmap: could not commit transaction: %w", err)
+		}
+	}
+	t.pages = nil
+	err = t.f.Sync()
+	if err != nil {
+		// The redo log is kept, for the next transaction to replay.
+		return err
+	}
+	return t.f.removeJournal()
+}
+
+// journal returns the redo log of the pages of the transaction, in the
+// order of idx.
+func (t *Txn) journal(idx []int64) []byte {
+	journal := make([]byte, 0, len(journalMagic)+len(idx)*(int(t.page)+12)+4)
+	journal = append(journal, journalMagic...)
+	for _, i := range idx {
+		shadow := t.pages[i]
+		journal = binary.LittleEndian.AppendUint64(journal, uint64(i*t.page))
+		journal = binary.LittleEndian.AppendUint32(journal, uint32(len(shadow)))
+		journal = append(journal, shadow...)
+	}
+	return binary.LittleEndian.AppendUint32(journal, crc32.Checksum(journal, castagnoli))
+}
+
+// Rollback discards the writes made by the transaction.
+func (t *Txn) Rollback() error {
+	if t.done {
+		return errClosed
+	}
+	t.done = true
+	t.pages = nil
+	return nil
+}
+
+// journalMagic starts the redo logs of transactions.
+const journalMagic = "mmap-txn-journal"
+
+// journalPath returns the path of the redo log of the transactions of the
+// file.
+func (f *File) journalPath() string {
+	return f.fd.Name() + "-journal"
+}
+
+// writeJournal writes the redo log of a transaction, and commits it to
+// stable storage along with its directory entry.
+// The log holds the magic number, then the offset, length and contents of
+// each page, then the CRC-32C of all the preceding bytes, so that logs cut
+// by a crash are told apart from complete ones.
+func (f *File) writeJournal(journal []byte) error {
+	path := f.journalPath()
+	j, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("mmap: could not create transaction journal: %w", err)
+	}
+	_, err = j.Write(journal)
+	if err == nil {
+		err = j.Sync()
+	}
+	err = joinErrors(err, j.Close())
+	if err == nil {
+		err = syncDir(filepath.Dir(path))
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("mmap: could not write transaction journal: %w", err)
+	}
+	return nil
+}
+
+// removeJournal removes the redo log of a transaction, once its pages were
+// committed to stable storage.
+func (f *File) removeJournal() error {
+	err := os.Remove(f.journalPath())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("mmap: could not remove transaction journal: %w", err)
+	}
+	return nil
+}
+
+// replayJournal carries the pages of the redo log of a commit interrupted
+// by a crash, if any, to the file.
+// Logs cut by a crash belong to commits that did not write to the file yet,
+// and are discarded.
+func (f *File) replayJournal() error {
+	journal, err := os.ReadFile(f.journalPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("mmap: could not read transaction journal: %w", err)
+	}
+	pages, ok := parseJournal(journal)
+	if !ok {
+		return f.removeJournal()
+	}
+	size := f.size()
+	for _, p := range pages {
+		if p.off+int64(len(p.data)) > size {
+			return fmt.Errorf("mmap: could not replay transaction journal: file was truncated")
+		}
+	}
+	for _, p := range pages {
+		_, err := f.writeAt(p.data, p.off)
+		if err != nil {
+			return fmt.Errorf("mmap: could not replay transaction journal: %w", err)
+		}
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	return f.removeJournal()
+}
+
+// journalPage is a page logged in the redo log of a transaction.
+type journalPage struct {
+	off  int64
+	data []byte
+}
+
+// parseJournal returns the pages of a redo log, and whether it is complete.
+func parseJournal(journal []byte) ([]journalPage, bool) {
+	n := len(journal) - 4
+	if n < len(journalMagic) || string(journal[:len(journalMagic)]) != journalMagic {
+		return nil, false
+	}
+	if crc32.Checksum(journal[:n], castagnoli) != binary.LittleEndian.Uint32(journal[n:]) {
+		return nil, false
+	}
+	var pages []journalPage
+	for b := journal[len(journalMagic):n]; len(b) > 0; {
+		if len(b) < 12 {
+			return nil, false
+		}
+		off := int64(binary.LittleEndian.Uint64(b))
+		size := binary.LittleEndian.Uint32(b[8:])
+		b = b[12:]
+		if off < 0 || uint64(size) > uint64(len(b)) {
+			return nil, false
+		}
+		pages = append(pages, journalPage{off: off, data: b[:size]})
+		b = b[size:]
+	}
+	return pages, true
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// UnsafePointer returns the address of the first byte of the mapping, to
+// hand the mapped bytes to C code (such as decompression libraries, or GPU
+// uploads) without copying them. The mapping is Len bytes long.
+// UnsafePointer returns nil for empty or closed files, and for files mapped
+// through a sliding window.
+//
+// The mapping is not part of the Go heap: it needs no pinning, and may be
+// retained by C code beyond the duration of a call.
+// It stays valid until the file is closed or remapped. As files are closed
+// when garbage collected, callers must keep f reachable, with
+// runtime.KeepAlive, for as long as the pointer is used.
+// Writing to a mapping opened read-only crashes the program.
+func (f *File) UnsafePointer() unsafe.Pointer {
+	if f == nil || f.w != nil || len(f.data) == 0 {
+		return nil
+	}
+	p := unsafe.Pointer(&f.data[0])
+	runtime.KeepAlive(f)
+	return p
+}
+
+// Addr returns the address of the mapping of the file, for diagnostics,
+// such as finding the mapping in /proc/self/maps or in VMMap.
+// For files mapped through a sliding window, it is the address of the
+// current view, if any.
+// Addr returns 0 for empty or closed files, and for files read through
+// their descriptor.
+func (f *File) Addr() uintptr {
+	data := f.mapped()
+	if len(data) == 0 {
+		return 0
+	}
+	return uintptr(unsafe.Pointer(&data[0]))
+}
+
+// MappedLen returns the number of bytes mapped for the file: its length,
+// or the length of the current view of files mapped through a sliding
+// window, if any.
+func (f *File) MappedLen() int {
+	return len(f.mapped())
+}
+
+// mapped returns the bytes currently mapped for the file.
+func (f *File) mapped() []byte {
+	if f == nil {
+		return nil
+	}
+	if w := f.w; w != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.data
+	}
+	return f.data
+}
+
+// Addr returns the address of the mapping holding the region, which starts
+// at BaseOffset in the file, or 0 once the region is closed.
+func (r *Region) Addr() uintptr {
+	if len(r.view) == 0 {
+		return 0
+	}
+	return uintptr(unsafe.Pointer(&r.view[0]))
+}
+
+// MappedLen returns the number of bytes mapped for the region, from
+// BaseOffset.
+func (r *Region) MappedLen() int {
+	return len(r.view)
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+)
+
+// utf8ChunkSize is the number of bytes validated at once by ValidUTF8Range.
+const utf8ChunkSize = 1 << 20
+
+// ValidUTF8 reports whether the contents of the file are valid UTF-8.
+func (f *File) ValidUTF8() bool {
+	ok, err := f.ValidUTF8Range(0, f.Size())
+	return ok && err == nil
+}
+
+// ValidUTF8Range reports whether the [off, off+n) range of the file is
+// valid UTF-8 on its own: runes cut by the bounds of the range are invalid.
+// The range is validated in chunks, so that files mapped through a sliding
+// window do not need to be copied at once.
+func (f *File) ValidUTF8Range(off, n int64) (bool, error) {
+	if f == nil {
+		return false, os.ErrInvalid
+	}
+	if f.closed() {
+		return false, errClosed
+	}
+	if size := f.size(); off < 0 || n < 0 || size < off || size-off < n {
+		return false, fmt.Errorf("mmap: invalid range [%d, %d)", off, off+n)
+	}
+
+	var (
+		// carry holds the beginning of a rune cut by the end of the
+		// previous chunk.
+		carry [utf8.UTFMax]byte
+		nc    int
+		buf   []byte
+	)
+	for end := off + n; off < end; {
+		c := end - off
+		if c > utf8ChunkSize {
+			c = utf8ChunkSize
+		}
+		var chunk []byte
+		if f.w == nil {
+			chunk = f.data[off : off+c]
+		} else {
+			if buf == nil {
+				buf = make([]byte, utf8ChunkSize)
+			}
+			_, err := f.readAt(buf[:c], off)
+			if err != nil {
+				return false, err
+			}
+			chunk = buf[:c]
+		}
+		off += c
+
+		for nc > 0 && len(chunk) > 0 && !utf8.FullRune(carry[:nc]) {
+			carry[nc] = chunk[0]
+			nc++
+			chunk = chunk[1:]
+		}
+		if nc > 0 && utf8.FullRune(carry[:nc]) {
+			if !utf8.Valid(carry[:nc]) {
+				return false, nil
+			}
+			nc = 0
+		}
+
+		i := partialRune(chunk)
+		if !utf8.Valid(chunk[:i]) {
+			return false, nil
+		}
+		nc += copy(carry[nc:], chunk[i:])
+	}
+	return nc == 0, nil
+}
+
+// partialRune returns the index of the beginning of the rune cut by the
+// end of b, or len(b) if b does not end with a partial rune.
+func partialRune(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-(utf8.UTFMax-1); i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if utf8.FullRune(b[i:]) {
+			return len(b)
+		}
+		return i
+	}
+	return len(b)
+}
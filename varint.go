@@ -0,0 +1,80 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadUvarintAt decodes the unsigned varint at off, as binary.Uvarint does,
+// and returns it with the number of bytes it spans.
+// If the file ends before the varint, or off is out of range, n is 0; if
+// the varint overflows 64 bits, n is negative.
+func (f *File) ReadUvarintAt(off int64) (v uint64, n int) {
+	var b [binary.MaxVarintLen64]byte
+	c, _ := f.ReadAt(b[:], off)
+	return binary.Uvarint(b[:c])
+}
+
+// ReadVarintAt decodes the signed varint at off, as binary.Varint does,
+// and returns it with the number of bytes it spans.
+// If the file ends before the varint, or off is out of range, n is 0; if
+// the varint overflows 64 bits, n is negative.
+func (f *File) ReadVarintAt(off int64) (v int64, n int) {
+	var b [binary.MaxVarintLen64]byte
+	c, _ := f.ReadAt(b[:], off)
+	return binary.Varint(b[:c])
+}
+
+// AppendLengthPrefixed appends p to dst, prefixed with its length as an
+// unsigned varint, and returns the extended buffer.
+// Records encoded this way, as delimited protobuf messages are, can be
+// iterated over with NextLengthPrefixed once written to a file.
+func AppendLengthPrefixed(dst, p []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(p)))
+	return append(dst, p...)
+}
+
+// NextLengthPrefixed returns the record at off, prefixed with its length
+// as an unsigned varint, and the offset of the following record.
+// It returns io.EOF when off is the end of the file, and
+// io.ErrUnexpectedEOF when the file ends in the middle of the record.
+// The returned slice aliases the mapping, unless the file is mapped through
+// a sliding window: it is then a copy of the record.
+func (f *File) NextLengthPrefixed(off int64) ([]byte, int64, error) {
+	if f == nil {
+		return nil, 0, os.ErrInvalid
+	}
+	if f.closed() {
+		return nil, 0, errClosed
+	}
+	size := f.size()
+	if off < 0 || size < off {
+		return nil, 0, fmt.Errorf("mmap: invalid offset %d", off)
+	}
+	if off == size {
+		return nil, 0, io.EOF
+	}
+	v, n := f.ReadUvarintAt(off)
+	switch {
+	case n == 0:
+		return nil, 0, io.ErrUnexpectedEOF
+	case n < 0:
+		return nil, 0, fmt.Errorf("mmap: invalid record length at offset %d", off)
+	}
+	beg := off + int64(n)
+	if v > uint64(size-beg) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	end := beg + int64(v)
+	rec, err := f.Range(int(beg), int(end))
+	if err != nil {
+		return nil, 0, err
+	}
+	return rec, end, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"sync/atomic"
+)
+
+// WithWatch watches the file for changes of its size or content made by
+// other processes.
+//
+// If fn is nil, the file is remapped on the first read or write following
+// a change.
+// The file must then not be accessed from several goroutines at once.
+//
+// Otherwise, fn is called from a separate goroutine whenever the file
+// changes, and is responsible for calling Remap as needed.
+//
+// The file is watched until it is closed.
+func WithWatch(fn func(f *File)) Option {
+	return func(o *options) {
+		o.watch = true
+		o.onChange = fn
+	}
+}
+
+// watcher tracks changes to a file.
+type watcher struct {
+	changed atomic.Bool
+	stop    func() error
+}
+
+func (f *File) startWatch() error {
+	w := new(watcher)
+	fn := f.cfg.onChange
+	notify := func() {
+		w.changed.Store(true)
+	}
+	if fn != nil {
+		// Only capture f when needed, so unreachable files can still be
+		// finalized when no callback was provided.
+		notify = func() {
+			fn(f)
+		}
+	}
+
+	stop, err := watchFile(f.fd, notify)
+	if err != nil {
+		return err
+	}
+	w.stop = stop
+	f.watcher = w
+	return nil
+}
+
+func (f *File) stopWatch() {
+	if f.watcher == nil {
+		return
+	}
+	_ = f.watcher.stop()
+	f.watcher = nil
+}
+
+// refresh remaps the file if it changed since it was last mapped, or maps
+// it if it was empty and grew since.
+func (f *File) refresh() error {
+	if f.empty.Load() {
+		return f.refreshEmpty()
+	}
+	if f.watcher == nil || !f.watcher.changed.Swap(false) {
+		return nil
+	}
+	return f.Remap()
+}
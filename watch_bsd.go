@@ -0,0 +1,81 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package mmap
+
+import (
+	"os"
+	"sync"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// watchFile calls notify whenever the file changes, until the returned
+// stop function is called.
+func watchFile(fd *os.File, notify func()) (func() error, error) {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes [2]syscall.Kevent_t
+	syscall.SetKevent(&changes[0], int(fd.Fd()), syscall.EVFILT_VNODE, syscall.EV_ADD|syscall.EV_CLEAR)
+	changes[0].Fflags = syscall.NOTE_WRITE | syscall.NOTE_EXTEND | syscall.NOTE_ATTRIB
+	// The user event is triggered to stop watching.
+	syscall.SetKevent(&changes[1], 0, syscall.EVFILT_USER, syscall.EV_ADD|syscall.EV_CLEAR)
+	_, err = syscall.Kevent(kq, changes[:], nil, nil)
+	if err != nil {
+		_ = syscall.Close(kq)
+		return nil, err
+	}
+
+	var (
+		mu   sync.Mutex
+		done bool
+	)
+	go func() {
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			done = true
+			_ = syscall.Close(kq)
+		}()
+
+		events := make([]syscall.Kevent_t, 8)
+		for {
+			n, err := syscall.Kevent(kq, nil, events, nil)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			for _, ev := range events[:n] {
+				if ev.Filter == syscall.EVFILT_USER {
+					return
+				}
+			}
+			if n > 0 {
+				notify()
+			}
+		}
+	}()
+
+	stop := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return nil
+		}
+		var ev [1]syscall.Kevent_t
+		syscall.SetKevent(&ev[0], 0, syscall.EVFILT_USER, 0)
+		ev[0].Fflags = syscall.NOTE_TRIGGER
+		_, err := syscall.Kevent(kq, ev[:], nil, nil)
+		return err
+	}
+	return stop, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"os"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// watchFile calls notify whenever the file changes, until the returned
+// stop function is called.
+func watchFile(fd *os.File, notify func()) (func() error, error) {
+	ifd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = syscall.InotifyAddWatch(ifd, fd.Name(), syscall.IN_MODIFY|syscall.IN_ATTRIB)
+	if err != nil {
+		_ = syscall.Close(ifd)
+		return nil, err
+	}
+
+	// The inotify descriptor is non-blocking: it is registered with the
+	// runtime poller, so closing it unblocks the pending read.
+	in := os.NewFile(uintptr(ifd), "inotify")
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				return
+			}
+			if n > 0 {
+				notify()
+			}
+		}
+	}()
+
+	return in.Close, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	syscall "golang.org/x/sys/windows"
+)
+
+// watchFile calls notify whenever the file changes, until the returned
+// stop function is called.
+func watchFile(fd *os.File, notify func()) (func() error, error) {
+	path, err := filepath.Abs(fd.Name())
+	if err != nil {
+		return nil, err
+	}
+	dir, base := filepath.Split(path)
+
+	// Windows only watches directories.
+	name, err := syscall.UTF16PtrFromString(longPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(
+		name, syscall.FILE_LIST_DIRECTORY,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING,
+		syscall.FILE_FLAG_BACKUP_SEMANTICS|syscall.FILE_FLAG_OVERLAPPED, 0,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ev, err := syscall.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		_ = syscall.CloseHandle(h)
+		return nil, err
+	}
+	quit, err := syscall.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		_ = syscall.CloseHandle(ev)
+		_ = syscall.CloseHandle(h)
+		return nil, err
+	}
+
+	var (
+		mu   sync.Mutex
+		done bool
+	)
+	go func() {
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			done = true
+			_ = syscall.CloseHandle(quit)
+			_ = syscall.CloseHandle(ev)
+			_ = syscall.CloseHandle(h)
+		}()
+
+		// FILE_NOTIFY_INFORMATION records are DWORD-aligned.
+		buf := make([]uint32, 16<<10)
+		const mask = syscall.FILE_NOTIFY_CHANGE_SIZE | syscall.FILE_NOTIFY_CHANGE_LAST_WRITE
+		for {
+			ol := syscall.Overlapped{HEvent: ev}
+			err := syscall.ReadDirectoryChanges(
+				h, (*byte)(unsafe.Pointer(&buf[0])), uint32(4*len(buf)),
+				false, mask, nil, &ol, 0,
+			)
+			if err != nil {
+				return
+			}
+
+			i, err := syscall.WaitForMultipleObjects([]syscall.Handle{ev, quit}, false, syscall.INFINITE)
+			var n uint32
+			if err != nil || i != syscall.WAIT_OBJECT_0 {
+				_ = syscall.CancelIoEx(h, &ol)
+				_ = syscall.GetOverlappedResult(h, &ol, &n, true)
+				return
+			}
+			err = syscall.GetOverlappedResult(h, &ol, &n, false)
+			if err != nil {
+				return
+			}
+			if n == 0 {
+				// Too many changes to fit in the buffer: assume the file
+				// is one of them.
+				notify()
+				continue
+			}
+			if changed(buf, base) {
+				notify()
+			}
+		}
+	}()
+
+	stop := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return nil
+		}
+		return syscall.SetEvent(quit)
+	}
+	return stop, nil
+}
+
+// changed reports whether base is one of the files listed in the
+// FILE_NOTIFY_INFORMATION records of buf.
+func changed(buf []uint32, base string) bool {
+	off := uintptr(0)
+	for {
+		info := (*syscall.FileNotifyInformation)(unsafe.Add(unsafe.Pointer(&buf[0]), off))
+		name := syscall.UTF16ToString(unsafe.Slice(&info.FileName, info.FileNameLength/2))
+		if strings.EqualFold(name, base) {
+			return true
+		}
+		if info.NextEntryOffset == 0 {
+			return false
+		}
+		off += uintptr(info.NextEntryOffset)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxView is the size above which a file is mapped through a sliding
+// window of views, instead of as a whole.
+// On 32-bit platforms, this leaves address space for the rest of the process.
+const maxView = math.MaxInt >> 1
+
+// windowSpan is the default size of the views of a sliding window.
+// It is a multiple of the allocation granularity of all supported platforms.
+const windowSpan = 64 << 20
+
+// withWindow forces the file to be mapped through views of span bytes.
+// span must be a multiple of the allocation granularity of the platform.
+func withWindow(span int64) Option {
+	return func(o *options) {
+		o.window = span
+	}
+}
+
+// window maps a file through a sliding view, for files too large to be
+// mapped as a whole in the address space of the process.
+type window struct {
+	mu   sync.Mutex
+	size int64   // size of the file
+	span int64   // size of a view
+	fmap uintptr // handle to the OS file mapping, if any
+
+	off  int64  // offset of the current view in the file
+	data []byte // current view
+
+	// direct is set for files that could not be mapped, and are read and
+	// written through their descriptor instead.
+	direct bool
+}
+
+// WithFallback makes files that can not be memory-mapped, because the OS
+// is out of memory or address space, or their filesystem does not support
+// mappings, be read and written through their descriptor instead, with
+// pread and pwrite, rather than failing to open.
+//
+// Such files behave as the ones mapped through a sliding window, but for
+// the cost of a system call per access: Bytes and UnsafePointer return
+// nil, and their pages can not be locked nor advised.
+// Files mapped with MapAt or WithDirtyTracking, snapshots and secrets never
+// fall back, as their mapping is essential.
+func WithFallback() Option {
+	return func(o *options) {
+		o.fallback = true
+	}
+}
+
+// fallback sets f up to be read and written through its descriptor, after
+// mapping its size bytes failed with err, if WithFallback was given.
+// Otherwise, it returns err.
+func (f *File) fallback(size int64, err error) error {
+	if !f.cfg.fallback || f.cfg.addr != 0 || f.cfg.private || f.cfg.dirty {
+		return err
+	}
+	f.w = &window{size: size, direct: true}
+	f.logEvent("fallback", size, time.Now(), err)
+	return nil
+}
+
+// Mapped reports whether the file is memory-mapped, rather than read and
+// written through its descriptor after falling back with WithFallback.
+func (f *File) Mapped() bool {
+	return f != nil && (f.w == nil || !f.w.direct)
+}
+
+func newWindow(size, span int64) *window {
+	if span <= 0 {
+		span = windowSpan
+	}
+	return &window{size: size, span: span}
+}
+
+// view returns the mapped bytes from off to the end of the view holding off,
+// moving the window if needed.
+// view must be called with w.mu held.
+func (w *window) view(f *File, off int64) ([]byte, error) {
+	beg := off - off%w.span
+	if w.data == nil || w.off != beg {
+		err := w.unmap(f)
+		if err != nil {
+			return nil, err
+		}
+		n := w.span
+		if w.size-beg < n {
+			n = w.size - beg
+		}
+		reserved, err := f.reserve(w, n)
+		if err != nil {
+			return nil, err
+		}
+		data, err := f.mapView(beg, int(n))
+		if err != nil {
+			unreserve(reserved)
+			return nil, err
+		}
+		statMap(data)
+		unreserve(reserved)
+		trackView(w, f)
+		w.off = beg
+		w.data = data
+	}
+	return w.data[off-beg:], nil
+}
+
+// unmap releases the current view, if any.
+// unmap must be called with w.mu held.
+func (w *window) unmap(f *File) error {
+	if w.data == nil {
+		return nil
+	}
+	data := w.data
+	w.data = nil
+	forgetView(w)
+	statUnmap(data)
+	return f.unmapView(data)
+}
+
+func (w *window) readAt(f *File, p []byte, off int64) (int, error) {
+	if w.direct {
+		if rem := w.size - off; int64(len(p)) > rem {
+			p = p[:rem]
+		}
+		n, err := f.fd.ReadAt(p, off)
+		if err == io.EOF && n == len(p) {
+			err = nil
+		}
+		return n, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < w.size {
+		b, err := w.view(f, off)
+		if err != nil {
+			return n, err
+		}
+		m := copy(p[n:], b)
+		n += m
+		off += int64(m)
+	}
+	return n, nil
+}
+
+func (w *window) writeAt(f *File, p []byte, off int64) (int, error) {
+	if w.direct {
+		if rem := w.size - off; int64(len(p)) > rem {
+			p = p[:rem]
+		}
+		return f.fd.WriteAt(p, off)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < w.size {
+		b, err := w.view(f, off)
+		if err != nil {
+			return n, err
+		}
+		m := copy(b, p[n:])
+		n += m
+		off += int64(m)
+	}
+	return n, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// WriteTo implements the io.WriterTo interface, writing the contents of
+// the file from the current position to w, until the end of the file.
+//
+// When w is a *net.TCPConn, or a *os.File on Linux, the contents are sent
+// from the descriptor of the file by the kernel, with sendfile,
+// copy_file_range or TransmitFile, without being copied through the
+// mapping. They are otherwise written from the mapping, as with Read.
+// Snapshots, files shared with clones, and files holding their writes in
+// memory until synced, as with WithDirtyTracking on Windows, are always
+// written from the mapping.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if f == nil {
+		return 0, os.ErrInvalid
+	}
+	if err := f.refresh(); err != nil {
+		return 0, err
+	}
+	if !f.rflag() {
+		return 0, errBadFD
+	}
+	size := f.size()
+	if f.c >= size {
+		return 0, nil
+	}
+
+	n, err := f.writeRange(w, f.c, size)
+	f.c += n
+	return n, err
+}
+
+// writeRange writes the [off, end) range of the file to w.
+func (f *File) writeRange(w io.Writer, off, end int64) (int64, error) {
+	if rf, ok := f.sendable(w); ok {
+		return f.sendTo(rf, off, end)
+	}
+	if f.w != nil {
+		return io.Copy(w, io.NewSectionReader(f, off, end-off))
+	}
+	want := end - off
+	n, err := w.Write(f.data[off:end])
+	if n < 0 || int64(n) > want {
+		return 0, fmt.Errorf("mmap: invalid Write count %d", n)
+	}
+	if err == nil && int64(n) < want {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
+
+// sendable returns the io.ReaderFrom of w that copies from the descriptor
+// of the file in the kernel, if any.
+func (f *File) sendable(w io.Writer) (io.ReaderFrom, bool) {
+	if !f.mirrored() || f.shared() {
+		// The mapping holds contents that are not in the file.
+		return nil, false
+	}
+	switch w := w.(type) {
+	case *net.TCPConn:
+		return w, true
+	case *os.File:
+		return w, fileCopyOffload
+	}
+	return nil, false
+}
+
+// sendTo copies the [off, end) range of the file with the ReadFrom method
+// of w, reading it from the descriptor.
+func (f *File) sendTo(w io.ReaderFrom, off, end int64) (int64, error) {
+	_, err := f.fd.Seek(off, io.SeekStart)
+	if err != nil {
+		return 0, pathError("mmap.seek", f.fd.Name(), err)
+	}
+	n, err := w.ReadFrom(&io.LimitedReader{R: f.fd, N: end - off})
+	if err == nil && n < end-off {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+var _ io.WriterTo = (*File)(nil)
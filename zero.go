@@ -0,0 +1,130 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import "os"
+
+// Zero sets the [off, off+n) range of the file to zero.
+//
+// When the file system supports it, the whole pages of the range are
+// deallocated from the file instead of being written to, so zeroing large
+// ranges frees disk space and does not dirty memory.
+func (f *File) Zero(off, n int64) error {
+	_, err := f.region(off, n)
+	if err != nil {
+		return err
+	}
+	if !f.wflag() {
+		return errBadFD
+	}
+
+	beg, end := f.pages(off, n)
+	if beg < end && !f.cfg.private && punchHole(f.fd, beg, end-beg) == nil {
+		err = f.fill(0, off, beg-off)
+		if err == nil {
+			err = f.fill(0, end, off+n-end)
+		}
+	} else {
+		err = f.fill(0, off, n)
+	}
+	if err != nil {
+		return err
+	}
+	return f.wrote(n)
+}
+
+// Fill sets the [off, off+n) range of the file to b.
+func (f *File) Fill(b byte, off, n int64) error {
+	_, err := f.region(off, n)
+	if err != nil {
+		return err
+	}
+	if !f.wflag() {
+		return errBadFD
+	}
+	err = f.fill(b, off, n)
+	if err != nil {
+		return err
+	}
+	return f.wrote(n)
+}
+
+// pages returns the range of the whole pages contained in [off, off+n).
+func (f *File) pages(off, n int64) (beg, end int64) {
+	page := int64(os.Getpagesize())
+	beg = (off + page - 1) &^ (page - 1)
+	end = (off + n) &^ (page - 1)
+	return beg, end
+}
+
+// fill sets the n mapped bytes starting at off to b.
+func (f *File) fill(b byte, off, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if f.w != nil {
+		return f.w.fill(f, b, off, n)
+	}
+	memset(f.data[off:off+n], b)
+	return nil
+}
+
+func (w *window) fill(f *File, b byte, off, n int64) error {
+	if w.direct {
+		c := n
+		if c > windowSpan {
+			c = windowSpan
+		}
+		p := make([]byte, c)
+		memset(p, b)
+		for n > 0 {
+			if int64(len(p)) > n {
+				p = p[:n]
+			}
+			_, err := f.fd.WriteAt(p, off)
+			if err != nil {
+				return err
+			}
+			n -= int64(len(p))
+			off += int64(len(p))
+		}
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for n > 0 {
+		p, err := w.view(f, off)
+		if err != nil {
+			return err
+		}
+		if int64(len(p)) > n {
+			p = p[:n]
+		}
+		memset(p, b)
+		n -= int64(len(p))
+		off += int64(len(p))
+	}
+	return nil
+}
+
+// memset sets all the bytes of p to b.
+func memset(p []byte, b byte) {
+	if b == 0 {
+		// The compiler turns this loop into a memclr call.
+		for i := range p {
+			p[i] = 0
+		}
+		return
+	}
+	if len(p) == 0 {
+		return
+	}
+	p[0] = b
+	for i := 1; i < len(p); i *= 2 {
+		copy(p[i:], p[:i])
+	}
+}
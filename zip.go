@@ -0,0 +1,56 @@
+// Copyright 2026 The go-mmap Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mmap
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+)
+
+// ZipReader is a zip archive read from a memory-mapped file.
+type ZipReader struct {
+	*zip.Reader
+
+	f *File
+}
+
+// OpenZip memory-maps the named zip archive for reading.
+// The archive is read directly from the mapping, without buffering.
+func OpenZip(filename string) (*ZipReader, error) {
+	f, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("mmap: could not read zip archive %q: %w", filename, err)
+	}
+	return &ZipReader{Reader: r, f: f}, nil
+}
+
+// StoredEntry returns a view of the contents of the entry e of the
+// archive, which must be stored without compression.
+// Reading from the view reads the mapping in place.
+func (z *ZipReader) StoredEntry(e *zip.File) (*io.SectionReader, error) {
+	if e.Method != zip.Store {
+		return nil, fmt.Errorf("mmap: zip entry %q is compressed", e.Name)
+	}
+	off, err := e.DataOffset()
+	if err != nil {
+		return nil, fmt.Errorf("mmap: could not locate zip entry %q: %w", e.Name, err)
+	}
+	n := int64(e.UncompressedSize64)
+	if off < 0 || n < 0 || z.f.Size()-off < n {
+		return nil, fmt.Errorf("mmap: zip entry %q is out of bounds", e.Name)
+	}
+	return z.f.SectionReader(off, n), nil
+}
+
+// Close closes the archive.
+func (z *ZipReader) Close() error {
+	return z.f.Close()
+}